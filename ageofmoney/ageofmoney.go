@@ -0,0 +1,551 @@
+// Package ageofmoney computes the age of money of each transaction in a YNAB
+// plan, rather than the single averaged number YNAB shows on the dashboard.
+//
+// Money coming into the budget fills a bucket. Spending takes money from the
+// oldest bucket that isn't empty, first in, first out, and the age of the
+// money spent is the number of days between the date the bucket was filled
+// and the date of the spending. A spend that takes money from several buckets
+// also has a WeightedAge, the average age of the money weighted by how much
+// came from each bucket, which is how YNAB ages it. The number YNAB shows on
+// the dashboard is the average WeightedAge of the last ten spends; Compute
+// returns it after each spend in Result.Rolling:
+//
+//	res, err := ageofmoney.Compute(accounts, txns, scheduled, nil)
+//	if err != nil {
+//		return err
+//	}
+//	for _, s := range res.Spending {
+//		fmt.Println(s.Date, s.Age, s.PayeeName)
+//	}
+//
+// Compute doesn't change its arguments or make any requests, so it can be run
+// on data from the API, a store or a test.
+package ageofmoney
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+// ErrNoIncome is returned by Compute if no money has come into the budget.
+var ErrNoIncome = errors.New("ageofmoney: can't compute age of money without any income")
+
+// Defaults for Options.
+const (
+	DefaultMaxThresholds      = 25
+	DefaultMaxThresholdAmount = 20000 * 1000
+	DefaultRollingWindow      = 10
+	DefaultProjectionDays     = 90
+)
+
+// Options change how Compute works. A nil *Options uses the defaults.
+type Options struct {
+	// Now is the time the ages of thresholds are computed at. If zero,
+	// time.Now() is used.
+	Now time.Time
+	// IncludeScheduledIncome adds scheduled income to the buckets before the
+	// scheduled spending that follows it is projected.
+	IncludeScheduledIncome bool
+	// Until is the last day scheduled transactions are projected to; the
+	// next occurrence of each is projected even if it's later. If zero, it's
+	// DefaultProjectionDays after Now.
+	Until time.Time
+	// MaxThresholds is the largest number of thresholds to return. If zero,
+	// DefaultMaxThresholds is used.
+	MaxThresholds int
+	// MaxThresholdAmount stops the thresholds once this much money, in
+	// milliunits, could be spent. If zero, DefaultMaxThresholdAmount is used.
+	MaxThresholdAmount int64
+	// RollingWindow is the number of spends averaged in Result.Rolling. If
+	// zero, DefaultRollingWindow is used, which matches YNAB.
+	RollingWindow int
+}
+
+// A Bucket is money that came into the budget: cash coming into an on budget
+// account from outside the budget.
+type Bucket struct {
+	// TransactionID is empty for scheduled income.
+	TransactionID string    `json:"transaction_id,omitempty"`
+	Date          ynab.Date `json:"date"`
+	Amount        int64     `json:"amount"`
+	// Remaining is the part of Amount that hasn't been spent.
+	Remaining   int64  `json:"remaining"`
+	AccountID   string `json:"account_id"`
+	AccountName string `json:"account_name"`
+	PayeeName   string `json:"payee_name"`
+}
+
+// A Spend is money that left the budget, and the age of the money it spent.
+// Credit card purchases are counted when the card is paid, not when the money
+// is spent.
+type Spend struct {
+	// TransactionID is the ID of the transaction or, for projected spending,
+	// the scheduled transaction.
+	TransactionID string    `json:"transaction_id"`
+	Date          ynab.Date `json:"date"`
+	// Amount is the amount spent, as a positive number.
+	Amount      int64  `json:"amount"`
+	AccountID   string `json:"account_id"`
+	AccountName string `json:"account_name"`
+	PayeeName   string `json:"payee_name"`
+	// Earned is the date of the bucket the last of the money came from, and
+	// Age is the number of days between Earned and Date. Both are zero if
+	// Unfunded is true.
+	Earned ynab.Date `json:"earned"`
+	Age    int       `json:"age"`
+	// Draws are the parts of Amount taken from each bucket, oldest first, and
+	// WeightedAge is their average age weighted by amount. WeightedAge is
+	// zero if Unfunded is true.
+	Draws       []Draw  `json:"draws"`
+	WeightedAge float64 `json:"weighted_age"`
+	// Unfunded is true if more money was spent than was ever earned.
+	Unfunded bool `json:"unfunded,omitempty"`
+}
+
+// A Draw is the part of a Spend taken from one bucket.
+type Draw struct {
+	Earned ynab.Date `json:"earned"`
+	Amount int64     `json:"amount"`
+	// Age is the number of days between Earned and the date of the Spend.
+	Age int `json:"age"`
+}
+
+// A Point is the age of money after a spend, like the number YNAB shows: the
+// average WeightedAge of the spend and the ones before it, up to
+// Options.RollingWindow of them. Unfunded spends are left out.
+type Point struct {
+	TransactionID string    `json:"transaction_id"`
+	Date          ynab.Date `json:"date"`
+	Age           float64   `json:"age"`
+}
+
+// A Threshold is a bucket that will be spent from next.
+type Threshold struct {
+	Bucket *Bucket `json:"bucket"`
+	// Cumulative is the amount that can be spent before this bucket is
+	// empty.
+	Cumulative int64 `json:"cumulative"`
+	// Age is the age of money spent from this bucket at Options.Now.
+	Age int `json:"age"`
+}
+
+// Result is the output of Compute.
+type Result struct {
+	// Buckets are the inflows, oldest first, and how much of each is left.
+	Buckets []*Bucket `json:"buckets"`
+	// Spending is every outflow, oldest first.
+	Spending []*Spend `json:"spending"`
+	// Rolling is the age of money after each funded spend in Spending.
+	Rolling []*Point `json:"rolling"`
+	// Thresholds are the next buckets to be spent.
+	Thresholds []*Threshold `json:"thresholds"`
+	// Scheduled is the projected age of the money each occurrence of the
+	// scheduled transactions will spend, in date order. It stops at the first
+	// one there isn't enough money for, which is Unfunded.
+	Scheduled []*Spend `json:"scheduled"`
+}
+
+// Compute matches spending in txns to inflows, first in, first out, and
+// projects the age of the money each occurrence of scheduled will spend, up to
+// Options.Until. Deleted transactions are ignored. It returns an error if a
+// transaction's account, or the account it transfers to, isn't in accounts,
+// or ErrNoIncome if there are no inflows.
+func Compute(accounts []*ynab.Account, txns []*ynab.Transaction, scheduled []*ynab.ScheduledTransaction, opts *Options) (*Result, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Now.IsZero() {
+		o.Now = time.Now()
+	}
+	if o.MaxThresholds == 0 {
+		o.MaxThresholds = DefaultMaxThresholds
+	}
+	if o.MaxThresholdAmount == 0 {
+		o.MaxThresholdAmount = DefaultMaxThresholdAmount
+	}
+	if o.RollingWindow == 0 {
+		o.RollingWindow = DefaultRollingWindow
+	}
+	if o.Until.IsZero() {
+		o.Until = o.Now.AddDate(0, 0, DefaultProjectionDays)
+	}
+	c := newComputer(accounts)
+
+	res := new(Result)
+	var spending []*Spend
+	for _, tx := range txns {
+		if tx.Deleted {
+			continue
+		}
+		income, err := c.income(tx.AccountID, tx.TransferAccountID, tx.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if income {
+			res.Buckets = append(res.Buckets, &Bucket{
+				TransactionID: tx.ID,
+				Date:          tx.Date,
+				Amount:        tx.Amount,
+				Remaining:     tx.Amount,
+				AccountID:     tx.AccountID,
+				AccountName:   tx.AccountName,
+				PayeeName:     tx.PayeeName,
+			})
+			continue
+		}
+		amount, ok, err := c.outflow(tx.AccountID, tx.TransferAccountID, tx.Amount, false)
+		if err != nil {
+			return nil, err
+		}
+		if ok && amount != 0 {
+			spending = append(spending, &Spend{
+				TransactionID: tx.ID,
+				Date:          tx.Date,
+				Amount:        amount,
+				AccountID:     tx.AccountID,
+				AccountName:   tx.AccountName,
+				PayeeName:     tx.PayeeName,
+			})
+		}
+	}
+	if len(res.Buckets) == 0 {
+		return nil, ErrNoIncome
+	}
+	sort.SliceStable(res.Buckets, func(i, j int) bool {
+		return time.Time(res.Buckets[i].Date).Before(time.Time(res.Buckets[j].Date))
+	})
+	sort.SliceStable(spending, func(i, j int) bool {
+		it, jt := time.Time(spending[i].Date), time.Time(spending[j].Date)
+		if it.Equal(jt) {
+			return spending[i].Amount < spending[j].Amount
+		}
+		return it.Before(jt)
+	})
+	c.buckets = res.Buckets
+	var funded []*Spend
+	for _, s := range spending {
+		c.spend(s)
+		if s.Unfunded {
+			continue
+		}
+		funded = append(funded, s)
+		window := funded[max(0, len(funded)-o.RollingWindow):]
+		total := 0.0
+		for _, w := range window {
+			total += w.WeightedAge
+		}
+		res.Rolling = append(res.Rolling, &Point{
+			TransactionID: s.TransactionID,
+			Date:          s.Date,
+			Age:           total / float64(len(window)),
+		})
+	}
+	res.Spending = spending
+
+	cumulative := int64(0)
+	for i := c.current; i < len(c.buckets) && len(res.Thresholds) < o.MaxThresholds && cumulative <= o.MaxThresholdAmount; i++ {
+		b := c.buckets[i]
+		cumulative += b.Remaining
+		res.Thresholds = append(res.Thresholds, &Threshold{
+			Bucket:     b,
+			Cumulative: cumulative,
+			// ynab-age-of-money has always shown the age a day younger.
+			Age: days(o.Now.Sub(time.Time(b.Date))) - 1,
+		})
+	}
+
+	// Project scheduled spending on copies of the buckets, so Buckets shows
+	// what is left today.
+	c.resume(res.Buckets)
+	var upcoming []ynab.Occurrence
+	for _, st := range scheduled {
+		if st.Deleted {
+			continue
+		}
+		until := o.Until
+		if next := time.Time(st.DateNext); next.After(until) {
+			until = next
+		}
+		for occ := range st.Occurrences(time.Time{}, until) {
+			upcoming = append(upcoming, occ)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		it, jt := time.Time(upcoming[i].Date), time.Time(upcoming[j].Date)
+		if it.Equal(jt) {
+			return upcoming[i].ScheduledTransaction.Amount > upcoming[j].ScheduledTransaction.Amount
+		}
+		return it.Before(jt)
+	})
+	for _, occ := range upcoming {
+		st := occ.ScheduledTransaction
+		amount, ok, err := c.outflow(st.AccountID, st.TransferAccountID, st.Amount, true)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if !o.IncludeScheduledIncome {
+				continue
+			}
+			income, ok, err := c.scheduledIncome(st)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				c.buckets = append(c.buckets, &Bucket{
+					Date:        occ.Date,
+					Amount:      income,
+					Remaining:   income,
+					AccountID:   st.AccountID,
+					AccountName: st.AccountName,
+					PayeeName:   st.PayeeName,
+				})
+			}
+			continue
+		}
+		if amount == 0 {
+			continue
+		}
+		s := &Spend{
+			TransactionID: st.ID,
+			Date:          occ.Date,
+			Amount:        amount,
+			AccountID:     st.AccountID,
+			AccountName:   st.AccountName,
+			PayeeName:     st.PayeeName,
+		}
+		c.spend(s)
+		res.Scheduled = append(res.Scheduled, s)
+		if s.Unfunded {
+			break
+		}
+	}
+	return res, nil
+}
+
+// computer holds the state of a Compute call.
+type computer struct {
+	accounts map[string]*ynab.Account
+	buckets  []*Bucket
+	// current is the index of the bucket being spent from.
+	current int
+}
+
+func newComputer(accounts []*ynab.Account) *computer {
+	c := &computer{accounts: make(map[string]*ynab.Account, len(accounts))}
+	for _, acct := range accounts {
+		c.accounts[acct.ID] = acct
+	}
+	return c
+}
+
+// resume continues spending from copies of buckets.
+func (c *computer) resume(buckets []*Bucket) {
+	c.buckets = make([]*Bucket, len(buckets))
+	c.current = len(buckets)
+	for i, b := range buckets {
+		copied := *b
+		c.buckets[i] = &copied
+		if b.Remaining > 0 && i < c.current {
+			c.current = i
+		}
+	}
+}
+
+func (c *computer) account(id string) (*ynab.Account, error) {
+	acct, ok := c.accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("ageofmoney: unknown account %q", id)
+	}
+	return acct, nil
+}
+
+// spend takes s.Amount from the buckets and sets the age of s.
+func (c *computer) spend(s *Spend) {
+	amount := s.Amount
+	weighted := 0.0
+	for amount > 0 && c.current < len(c.buckets) {
+		b := c.buckets[c.current]
+		taken := min(amount, b.Remaining)
+		if taken > 0 {
+			d := Draw{Earned: b.Date, Amount: taken, Age: days(time.Time(s.Date).Sub(time.Time(b.Date)))}
+			s.Draws = append(s.Draws, d)
+			weighted += float64(d.Amount) * float64(d.Age)
+		}
+		if amount < b.Remaining {
+			b.Remaining -= amount
+			amount = 0
+			break
+		}
+		amount -= b.Remaining
+		b.Remaining = 0
+		c.current++
+	}
+	if amount > 0 {
+		s.Unfunded = true
+		return
+	}
+	s.WeightedAge = weighted / float64(s.Amount)
+	// Like ynab-age-of-money always has, a spend that exactly empties a
+	// bucket is aged from the next one, if there is one.
+	s.Earned = c.buckets[min(c.current, len(c.buckets)-1)].Date
+	s.Age = days(time.Time(s.Date).Sub(time.Time(s.Earned)))
+}
+
+// income reports whether a transaction of amount in accountID, transferring
+// to transferID, brings money into the budget.
+func (c *computer) income(accountID string, transferID types.NullString, amount int64) (bool, error) {
+	acct, err := c.account(accountID)
+	if err != nil {
+		return false, err
+	}
+	if !acct.OnBudget || !acct.CashBacked() || amount <= 0 {
+		return false, nil
+	}
+	if transferID.Valid {
+		transfer, err := c.account(transferID.String)
+		if err != nil {
+			return false, err
+		}
+		// transfers from off budget accounts are income, on budget, they
+		// are just moving money around
+		return !transfer.OnBudget, nil
+	}
+	return true, nil
+}
+
+// outflow reports whether a transaction of amount in accountID, transferring
+// to transferID, spends money from the budget, and the amount it spends as a
+// positive number. A payment from a cash account to a credit card counts as
+// spending, instead of the credit card purchases it pays for.
+func (c *computer) outflow(accountID string, transferID types.NullString, amount int64, scheduled bool) (int64, bool, error) {
+	acct, err := c.account(accountID)
+	if err != nil {
+		return 0, false, err
+	}
+	if !acct.OnBudget {
+		return 0, false, nil
+	}
+	var transfer *ynab.Account
+	if transferID.Valid {
+		if transfer, err = c.account(transferID.String); err != nil {
+			return 0, false, err
+		}
+	}
+	if acct.CashBacked() {
+		if transfer == nil || !transfer.OnBudget ||
+			// For scheduled transfers we only see one side of the
+			// transaction, so count cash to credit card transfers here.
+			(scheduled && !transfer.CashBacked()) {
+			// cash spending, or a transfer to an off budget account
+			return -amount, amount < 0, nil
+		}
+		// cash <> cash transfers just move money around, and the cash side
+		// of a credit card payment is counted on the credit card side.
+		return 0, false, nil
+	}
+	if transfer == nil || !transfer.CashBacked() {
+		// credit card spending, or e.g. a mortgage to escrow transfer
+		return 0, false, nil
+	}
+	if amount >= 0 {
+		// a payment from a cash account to this credit account
+		return amount, true, nil
+	}
+	return 0, false, nil
+}
+
+// scheduledIncome reports whether a scheduled transaction brings money into
+// the budget, and how much.
+func (c *computer) scheduledIncome(st *ynab.ScheduledTransaction) (int64, bool, error) {
+	acct, err := c.account(st.AccountID)
+	if err != nil {
+		return 0, false, err
+	}
+	if st.TransferAccountID.Valid {
+		transfer, err := c.account(st.TransferAccountID.String)
+		if err != nil {
+			return 0, false, err
+		}
+		if !acct.CashBacked() && st.Amount < 0 && transfer.OnBudget {
+			// a transfer from an off budget account to an on budget one
+			return -st.Amount, true, nil
+		}
+		if acct.CashBacked() && transfer.OnBudget {
+			return 0, false, nil
+		}
+	}
+	if !acct.CashBacked() {
+		return 0, false, nil
+	}
+	return st.Amount, true, nil
+}
+
+// days returns d in days, rounded to the nearest day.
+func days(d time.Duration) int {
+	return int(math.Round(d.Hours() / 24))
+}
+
+// AgeAt returns the age of money in Rolling at the end of the day t, or false
+// if nothing was spent by then.
+func (r *Result) AgeAt(t time.Time) (float64, bool) {
+	y, m, d := t.Date()
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+	i := sort.Search(len(r.Rolling), func(i int) bool {
+		return !time.Time(r.Rolling[i].Date).Before(end)
+	})
+	if i == 0 {
+		return 0, false
+	}
+	return r.Rolling[i-1].Age, true
+}
+
+// A MonthAge compares the age of money at the end of a month to the age YNAB
+// reports for it.
+type MonthAge struct {
+	// Month is the first day of the month.
+	Month    ynab.Date `json:"month"`
+	Computed float64   `json:"computed"`
+	Reported int       `json:"reported"`
+	// Drift is Computed, rounded to the nearest day, minus Reported.
+	Drift int `json:"drift"`
+}
+
+// Verify compares the age of money in Rolling at the end of each month to the
+// AgeOfMoney YNAB reports in months, e.g. from PlanService.ListMonths, oldest
+// month first. Deleted months, months without an age and months that end
+// before the first spend are skipped. It returns an error if a month isn't a
+// date.
+func (r *Result) Verify(months []*ynab.MonthSummary) ([]*MonthAge, error) {
+	ages := make([]*MonthAge, 0, len(months))
+	for _, m := range months {
+		if m.Deleted || m.AgeOfMoney == nil {
+			continue
+		}
+		start, err := time.ParseInLocation("2006-01-02", m.Month, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("ageofmoney: bad month %q: %w", m.Month, err)
+		}
+		computed, ok := r.AgeAt(start.AddDate(0, 1, -1))
+		if !ok {
+			continue
+		}
+		ages = append(ages, &MonthAge{
+			Month:    ynab.Date(start),
+			Computed: computed,
+			Reported: *m.AgeOfMoney,
+			Drift:    int(math.Round(computed)) - *m.AgeOfMoney,
+		})
+	}
+	sort.SliceStable(ages, func(i, j int) bool {
+		return time.Time(ages[i].Month).Before(time.Time(ages[j].Month))
+	})
+	return ages, nil
+}
@@ -0,0 +1,266 @@
+package ageofmoney
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+func date(s string) ynab.Date {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return ynab.Date(t)
+}
+
+var testAccounts = []*ynab.Account{
+	{ID: "checking", Name: "Checking", Type: "checking", OnBudget: true},
+	{ID: "savings", Name: "Savings", Type: "savings", OnBudget: true},
+	{ID: "visa", Name: "Visa", Type: "creditCard", OnBudget: true},
+	{ID: "brokerage", Name: "Brokerage", Type: "otherAsset"},
+}
+
+func transfer(id string) types.NullString {
+	return types.NullString{Valid: true, String: id}
+}
+
+func testTransactions() []*ynab.Transaction {
+	return []*ynab.Transaction{
+		{ID: "pay-1", AccountID: "checking", Date: date("2024-01-01"), Amount: 100000, PayeeName: "Employer"},
+		{ID: "pay-2", AccountID: "checking", Date: date("2024-01-15"), Amount: 100000, PayeeName: "Employer"},
+		{ID: "sell", AccountID: "savings", Date: date("2024-01-20"), Amount: 50000, PayeeName: "Transfer : Brokerage", TransferAccountID: transfer("brokerage")},
+		// moving money between cash accounts is neither income nor spending
+		{ID: "move-out", AccountID: "checking", Date: date("2024-01-21"), Amount: -10000, TransferAccountID: transfer("savings")},
+		{ID: "move-in", AccountID: "savings", Date: date("2024-01-21"), Amount: 10000, TransferAccountID: transfer("checking")},
+		{ID: "rent", AccountID: "checking", Date: date("2024-01-31"), Amount: -80000, PayeeName: "Landlord"},
+		// card spending counts when the card is paid
+		{ID: "dinner", AccountID: "visa", Date: date("2024-02-01"), Amount: -40000, PayeeName: "Restaurant"},
+		{ID: "pay-card", AccountID: "visa", Date: date("2024-02-10"), Amount: 40000, PayeeName: "Transfer : Checking", TransferAccountID: transfer("checking")},
+		{ID: "pay-card-cash", AccountID: "checking", Date: date("2024-02-10"), Amount: -40000, PayeeName: "Transfer : Visa", TransferAccountID: transfer("visa")},
+		{ID: "deleted", AccountID: "checking", Date: date("2024-02-11"), Amount: -99999999, Deleted: true},
+		{ID: "coffee", AccountID: "checking", Date: date("2024-02-14"), Amount: -5000, PayeeName: "Cafe"},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	txns := testTransactions()
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "s-rent", AccountID: "checking", DateNext: date("2024-03-01"), Amount: -80000, PayeeName: "Landlord"},
+		{ID: "s-pay", AccountID: "checking", DateNext: date("2024-02-28"), Amount: 100000, PayeeName: "Employer"},
+		{ID: "s-card", AccountID: "checking", DateNext: date("2024-03-10"), Amount: -60000, PayeeName: "Transfer : Visa", TransferAccountID: transfer("visa")},
+		{ID: "s-more", AccountID: "checking", DateNext: date("2024-03-15"), Amount: -1000, PayeeName: "Cafe"},
+	}
+	res, err := Compute(testAccounts, txns, scheduled, &Options{Now: time.Time(date("2024-02-20"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buckets []string
+	for _, b := range res.Buckets {
+		buckets = append(buckets, b.TransactionID)
+	}
+	if got := strings.Join(buckets, ","); got != "pay-1,pay-2,sell" {
+		t.Errorf("got buckets %s", got)
+	}
+	// rent takes 80 from pay-1, the card payment the other 20 and 20 of
+	// pay-2, and coffee 5 more.
+	if r := [3]int64{res.Buckets[0].Remaining, res.Buckets[1].Remaining, res.Buckets[2].Remaining}; r != [3]int64{0, 75000, 50000} {
+		t.Errorf("got remaining %v", r)
+	}
+	want := []struct {
+		id     string
+		amount int64
+		earned string
+		age    int
+	}{
+		{"rent", 80000, "2024-01-01", 30},
+		{"pay-card", 40000, "2024-01-15", 26},
+		{"coffee", 5000, "2024-01-15", 30},
+	}
+	if len(res.Spending) != len(want) {
+		t.Fatalf("got %d spends, want %d", len(res.Spending), len(want))
+	}
+	for i, w := range want {
+		s := res.Spending[i]
+		if s.TransactionID != w.id || s.Amount != w.amount || s.Earned.String() != w.earned || s.Age != w.age || s.Unfunded {
+			t.Errorf("spend %d: got %+v, want %+v", i, s, w)
+		}
+	}
+
+	if len(res.Thresholds) != 2 {
+		t.Fatalf("got %d thresholds", len(res.Thresholds))
+	}
+	if th := res.Thresholds[0]; th.Bucket.TransactionID != "pay-2" || th.Cumulative != 75000 || th.Age != 35 {
+		t.Errorf("got threshold %+v", th)
+	}
+	if th := res.Thresholds[1]; th.Bucket.TransactionID != "sell" || th.Cumulative != 125000 || th.Age != 30 {
+		t.Errorf("got threshold %+v", th)
+	}
+
+	// Without scheduled income, the card payment can't be covered.
+	var got []string
+	for _, s := range res.Scheduled {
+		got = append(got, s.TransactionID)
+		if s.TransactionID == "s-card" && !s.Unfunded {
+			t.Errorf("s-card should be unfunded: %+v", s)
+		}
+	}
+	if strings.Join(got, ",") != "s-rent,s-card" {
+		t.Errorf("got scheduled %v", got)
+	}
+	if res.Scheduled[0].Earned.String() != "2024-01-20" || res.Scheduled[0].Age != 41 {
+		t.Errorf("got %+v", res.Scheduled[0])
+	}
+	// Projections don't change the buckets.
+	if res.Buckets[1].Remaining != 75000 {
+		t.Errorf("projection changed the buckets: %+v", res.Buckets[1])
+	}
+
+	res, err = Compute(testAccounts, txns, scheduled, &Options{IncludeScheduledIncome: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for _, s := range res.Scheduled {
+		if s.Unfunded {
+			t.Errorf("%s is unfunded", s.TransactionID)
+		}
+		got = append(got, s.TransactionID+"@"+s.Earned.String())
+	}
+	if strings.Join(got, ",") != "s-rent@2024-01-20,s-card@2024-02-28,s-more@2024-02-28" {
+		t.Errorf("got scheduled %v", got)
+	}
+
+	// Compute doesn't change its arguments.
+	if txns[7].Amount != 40000 || scheduled[0].ID != "s-rent" {
+		t.Error("Compute changed its arguments")
+	}
+}
+
+func TestComputeRecurring(t *testing.T) {
+	txns := []*ynab.Transaction{{ID: "pay", AccountID: "checking", Date: date("2024-01-01"), Amount: 100000}}
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "coffee", AccountID: "checking", DateNext: date("2024-02-01"), Frequency: ynab.FrequencyWeekly, Amount: -1000},
+		{ID: "insurance", AccountID: "checking", DateNext: date("2024-06-01"), Frequency: ynab.FrequencyYearly, Amount: -5000},
+	}
+	res, err := Compute(testAccounts, txns, scheduled, &Options{Now: time.Time(date("2024-01-31")), Until: time.Time(date("2024-02-29"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range res.Scheduled {
+		got = append(got, s.TransactionID+"@"+s.Date.String())
+	}
+	// The next insurance payment is after Until, but is projected anyway.
+	want := "coffee@2024-02-01 coffee@2024-02-08 coffee@2024-02-15 coffee@2024-02-22 coffee@2024-02-29 insurance@2024-06-01"
+	if g := strings.Join(got, " "); g != want {
+		t.Errorf("got scheduled %s, want %s", g, want)
+	}
+}
+
+func TestComputeWeighted(t *testing.T) {
+	res, err := Compute(testAccounts, testTransactions(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The card payment takes 20 from pay-1, 40 days old, and 20 from pay-2,
+	// 26 days old.
+	card := res.Spending[1]
+	want := []Draw{{Earned: date("2024-01-01"), Amount: 20000, Age: 40}, {Earned: date("2024-01-15"), Amount: 20000, Age: 26}}
+	if !slices.Equal(card.Draws, want) || card.WeightedAge != 33 {
+		t.Errorf("got draws %+v, weighted age %v", card.Draws, card.WeightedAge)
+	}
+	var rolling []float64
+	for _, p := range res.Rolling {
+		rolling = append(rolling, p.Age)
+	}
+	if !slices.Equal(rolling, []float64{30, 31.5, 31}) {
+		t.Errorf("got rolling %v", rolling)
+	}
+	res, err = Compute(testAccounts, testTransactions(), nil, &Options{RollingWindow: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Rolling[2]; p.TransactionID != "coffee" || p.Age != 30 {
+		t.Errorf("got %+v", p)
+	}
+
+	if _, ok := res.AgeAt(time.Time(date("2024-01-30"))); ok {
+		t.Error("got an age before the first spend")
+	}
+	if age, ok := res.AgeAt(time.Time(date("2024-02-12")).Add(13 * time.Hour)); !ok || age != 33 {
+		t.Errorf("got age %v, %t", age, ok)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	res, err := Compute(testAccounts, testTransactions(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	age := func(n int) *int { return &n }
+	months := []*ynab.MonthSummary{
+		{Month: "2024-02-01", AgeOfMoney: age(29)},
+		{Month: "2023-12-01", AgeOfMoney: age(5)},
+		{Month: "2024-01-01", AgeOfMoney: age(30)},
+		{Month: "2024-03-01"},
+		{Month: "2024-04-01", AgeOfMoney: age(1), Deleted: true},
+	}
+	ages, err := res.Verify(months)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ages) != 2 {
+		t.Fatalf("got %d months, want 2", len(ages))
+	}
+	if a := ages[0]; a.Month.String() != "2024-01-01" || a.Computed != 30 || a.Drift != 0 {
+		t.Errorf("got %+v", a)
+	}
+	if a := ages[1]; a.Month.String() != "2024-02-01" || a.Computed != 31 || a.Reported != 29 || a.Drift != 2 {
+		t.Errorf("got %+v", a)
+	}
+	if _, err := res.Verify([]*ynab.MonthSummary{{Month: "February", AgeOfMoney: age(1)}}); err == nil {
+		t.Error("expected an error for a bad month")
+	}
+}
+
+func TestComputeOverspent(t *testing.T) {
+	txns := []*ynab.Transaction{
+		{ID: "pay", AccountID: "checking", Date: date("2024-01-01"), Amount: 10000},
+		{ID: "all", AccountID: "checking", Date: date("2024-01-02"), Amount: -10000},
+		{ID: "more", AccountID: "checking", Date: date("2024-01-03"), Amount: -1000},
+	}
+	res, err := Compute(testAccounts, txns, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := res.Spending[0]; s.Unfunded || s.Earned.String() != "2024-01-01" || s.Age != 1 {
+		t.Errorf("got %+v", s)
+	}
+	if s := res.Spending[1]; !s.Unfunded || s.Age != 0 {
+		t.Errorf("got %+v", s)
+	}
+	if len(res.Thresholds) != 0 {
+		t.Errorf("got thresholds %+v", res.Thresholds)
+	}
+}
+
+func TestComputeErrors(t *testing.T) {
+	if _, err := Compute(testAccounts, nil, nil, nil); !errors.Is(err, ErrNoIncome) {
+		t.Errorf("got %v, want ErrNoIncome", err)
+	}
+	txns := []*ynab.Transaction{{AccountID: "missing", Amount: 1000}}
+	if _, err := Compute(testAccounts, txns, nil, nil); err == nil || !strings.Contains(err.Error(), `unknown account "missing"`) {
+		t.Errorf("got %v", err)
+	}
+	txns = []*ynab.Transaction{{AccountID: "checking", Amount: 1000, TransferAccountID: transfer("gone")}}
+	if _, err := Compute(testAccounts, txns, nil, nil); err == nil || !strings.Contains(err.Error(), `unknown account "gone"`) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package ageofmoney
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"gopkg.in/yaml.v3"
+)
+
+// A Scenario is a what-if question about the age of money, like "if we get a
+// $500 raise, when does the age of money reach 90 days?". Simulate answers it.
+type Scenario struct {
+	// Start is the first day simulated. If zero, it's today.
+	Start ynab.Date `yaml:"start"`
+	// Until is the last day simulated. If zero, it's a year after Start.
+	Until ynab.Date `yaml:"until"`
+	// Target stops the simulation at the end of the first week the age of
+	// money is at least Target days. If zero, the simulation runs until
+	// Until.
+	Target float64 `yaml:"target"`
+	// Skip leaves out the scheduled transactions to these payees, e.g. a
+	// subscription you are going to cancel. Payee names are compared without
+	// regard to case.
+	Skip []string `yaml:"skip"`
+	// Flows are hypothetical income and spending, in addition to the
+	// scheduled transactions.
+	Flows []*Flow `yaml:"flows"`
+}
+
+// A Flow is a hypothetical recurring income or expense.
+type Flow struct {
+	Name string `yaml:"name"`
+	// Amount is positive for income and negative for spending, in
+	// milliunits. In a scenario file it's a decimal number of the plan's
+	// currency; see UnmarshalYAML.
+	Amount int64 `yaml:"-"`
+	// Frequency is how often the flow recurs, e.g. monthly, everyOtherWeek
+	// or never.
+	Frequency ynab.Frequency `yaml:"frequency"`
+	// Start is the date of the first occurrence. If zero, it's the start of
+	// the scenario.
+	Start ynab.Date `yaml:"start"`
+	// End is the last date the flow can occur on, if it isn't zero.
+	End ynab.Date `yaml:"end"`
+}
+
+// UnmarshalYAML decodes a flow in a scenario file, whose amount is a decimal
+// number like 500.00, and checks that it has an amount and a valid
+// frequency.
+func (f *Flow) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: a flow must be a mapping with a name, amount and frequency", n.Line)
+	}
+	// Decoding a node doesn't reject unknown fields, like the decoder in
+	// ParseScenario does.
+	for i := 0; i < len(n.Content); i += 2 {
+		switch key := n.Content[i]; key.Value {
+		case "name", "amount", "frequency", "start", "end":
+		default:
+			return fmt.Errorf("line %d: unknown flow setting %q", key.Line, key.Value)
+		}
+	}
+	type flow Flow // without this method
+	var v struct {
+		flow   `yaml:",inline"`
+		Amount string `yaml:"amount"`
+	}
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	*f = Flow(v.flow)
+	if v.Amount != "" {
+		amount, err := ynab.ParseMilliunits(v.Amount, ynab.CurrencyFormat{})
+		if err != nil {
+			return fmt.Errorf("line %d: flow %q: invalid amount %q", n.Line, f.Name, v.Amount)
+		}
+		f.Amount = int64(amount)
+	}
+	switch {
+	case f.Amount == 0:
+		return fmt.Errorf("line %d: flow %q needs an amount", n.Line, f.Name)
+	case f.Frequency == "":
+		return fmt.Errorf("line %d: flow %q needs a frequency", n.Line, f.Name)
+	case !f.Frequency.Valid():
+		return fmt.Errorf("line %d: flow %q: unknown frequency %q", n.Line, f.Name, f.Frequency)
+	}
+	return nil
+}
+
+// ParseScenario parses a scenario file, which is YAML like this:
+//
+//	start: 2026-11-01  # optional, default today
+//	until: 2028-12-31  # optional, default a year after start
+//	target: 90         # optional, days
+//	skip: [Netflix]    # payees of scheduled transactions to leave out
+//	flows:
+//	  - name: Raise
+//	    amount: 500.00   # positive for income, negative for spending
+//	    frequency: monthly
+//	    start: 2027-01-01
+//	  - name: Eating out
+//	    amount: -300
+//	    frequency: monthly
+//	    end: 2026-12-31
+//
+// Dates are YYYY-MM-DD and amounts are decimal numbers of the plan's
+// currency. Unknown settings are an error.
+func ParseScenario(data []byte) (*Scenario, error) {
+	s := new(Scenario)
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(s); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if s.Target < 0 {
+		return nil, fmt.Errorf("target must be a positive number of days, found %g", s.Target)
+	}
+	return s, nil
+}
+
+// LoadScenario reads the scenario file at path.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// A Week is the projected age of money at the end of a day, a week after the
+// one before it.
+type Week struct {
+	Date ynab.Date `json:"date"`
+	// Age is the age of money YNAB would show: the average weighted age of
+	// the last DefaultRollingWindow spends.
+	Age float64 `json:"age"`
+	// Unfunded is the amount that was spent since the start of the
+	// simulation without any money to spend.
+	Unfunded int64 `json:"unfunded,omitempty"`
+}
+
+// A Simulation is the result of Simulate.
+type Simulation struct {
+	// Weeks is the age of money at the start of the scenario and at the end
+	// of each week after it.
+	Weeks []*Week `json:"weeks"`
+	// Spending is the projected spending, with the age of the money it
+	// spends.
+	Spending []*Spend `json:"spending"`
+	// Reached is the first week the age of money is at least the target, or
+	// nil if it isn't reached.
+	Reached *Week `json:"reached,omitempty"`
+}
+
+// A flow is an income (a positive amount) or spending (a negative one) on a
+// date in a simulation.
+type flow struct {
+	date        time.Time
+	amount      int64
+	id          string
+	accountID   string
+	accountName string
+	payeeName   string
+}
+
+// Simulate projects the age of money forward from the history in txns. Every
+// occurrence of the scheduled transactions, including income, and of the
+// scenario's flows is applied in date order, and the age of money is
+// recorded at the end of each week until the scenario's target or end. It
+// returns the errors Compute does, or an error if a frequency isn't known.
+//
+// Spending that isn't scheduled, like groceries, is only simulated if the
+// scenario has a flow for it.
+func Simulate(accounts []*ynab.Account, txns []*ynab.Transaction, scheduled []*ynab.ScheduledTransaction, s *Scenario) (*Simulation, error) {
+	start := time.Time(s.Start)
+	if start.IsZero() {
+		y, m, d := time.Now().Date()
+		start = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	until := time.Time(s.Until)
+	if until.IsZero() {
+		until = start.AddDate(1, 0, 0)
+	}
+	if until.Before(start) {
+		return nil, fmt.Errorf("ageofmoney: the scenario ends on %s, before it starts on %s", s.Until, ynab.Date(start))
+	}
+	res, err := Compute(accounts, txns, nil, &Options{Now: start})
+	if err != nil {
+		return nil, err
+	}
+	c := newComputer(accounts)
+	c.resume(res.Buckets)
+
+	var flows []*flow
+	for _, st := range scheduled {
+		if st.Deleted || slices.ContainsFunc(s.Skip, func(payee string) bool { return strings.EqualFold(payee, st.PayeeName) }) {
+			continue
+		}
+		amount, ok, err := c.outflow(st.AccountID, st.TransferAccountID, st.Amount, true)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			amount = -amount
+		} else if amount, ok, err = c.scheduledIncome(st); err != nil {
+			return nil, err
+		}
+		if !ok || amount == 0 {
+			continue
+		}
+		if !st.Frequency.Valid() {
+			return nil, fmt.Errorf("ageofmoney: scheduled transaction %s has unknown frequency %q", st.ID, st.Frequency)
+		}
+		for o := range st.Occurrences(time.Time{}, until) {
+			flows = append(flows, &flow{date: time.Time(o.Date), amount: amount, id: st.ID, accountID: st.AccountID, accountName: st.AccountName, payeeName: st.PayeeName})
+		}
+	}
+	for _, f := range s.Flows {
+		first := time.Time(f.Start)
+		if first.IsZero() {
+			first = start
+		}
+		last := until
+		if end := time.Time(f.End); !end.IsZero() && end.Before(last) {
+			last = end
+		}
+		if !f.Frequency.Valid() {
+			return nil, fmt.Errorf("ageofmoney: flow %q has unknown frequency %q", f.Name, f.Frequency)
+		}
+		for d := range f.Frequency.Dates(ynab.Date(first), first, last) {
+			flows = append(flows, &flow{date: time.Time(d), amount: f.Amount, payeeName: f.Name})
+		}
+	}
+	// Like Compute, income comes before spending on the same day, and smaller
+	// spending before larger.
+	sort.SliceStable(flows, func(i, j int) bool {
+		if !flows[i].date.Equal(flows[j].date) {
+			return flows[i].date.Before(flows[j].date)
+		}
+		return flows[i].amount > flows[j].amount
+	})
+
+	// Carry the rolling average over from the history.
+	var recent []float64
+	for _, sp := range res.Spending {
+		if !sp.Unfunded {
+			recent = append(recent, sp.WeightedAge)
+		}
+	}
+	recent = recent[max(0, len(recent)-DefaultRollingWindow):]
+	age := func() float64 {
+		if len(recent) == 0 {
+			return 0
+		}
+		total := 0.0
+		for _, a := range recent {
+			total += a
+		}
+		return total / float64(len(recent))
+	}
+
+	sim := new(Simulation)
+	unfunded := int64(0)
+	i := 0
+	for week := start; !week.After(until); week = week.AddDate(0, 0, 7) {
+		end := week.AddDate(0, 0, 1)
+		for ; i < len(flows) && flows[i].date.Before(end); i++ {
+			f := flows[i]
+			if f.amount > 0 {
+				c.buckets = append(c.buckets, &Bucket{
+					Date:        ynab.Date(f.date),
+					Amount:      f.amount,
+					Remaining:   f.amount,
+					AccountID:   f.accountID,
+					AccountName: f.accountName,
+					PayeeName:   f.payeeName,
+				})
+				continue
+			}
+			sp := &Spend{
+				TransactionID: f.id,
+				Date:          ynab.Date(f.date),
+				Amount:        -f.amount,
+				AccountID:     f.accountID,
+				AccountName:   f.accountName,
+				PayeeName:     f.payeeName,
+			}
+			c.spend(sp)
+			sim.Spending = append(sim.Spending, sp)
+			if sp.Unfunded {
+				unfunded += sp.Amount
+				for _, d := range sp.Draws {
+					unfunded -= d.Amount
+				}
+				continue
+			}
+			recent = append(recent, sp.WeightedAge)
+			if len(recent) > DefaultRollingWindow {
+				recent = recent[1:]
+			}
+		}
+		w := &Week{Date: ynab.Date(week), Age: age(), Unfunded: unfunded}
+		sim.Weeks = append(sim.Weeks, w)
+		if s.Target > 0 && w.Age >= s.Target {
+			sim.Reached = w
+			break
+		}
+	}
+	return sim, nil
+}
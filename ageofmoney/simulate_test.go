@@ -0,0 +1,150 @@
+package ageofmoney
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+const testScenario = `# What if I get a raise?
+start: 2024-02-01
+until: "2024-04-30"
+target: 35
+skip: [Netflix, 'Trader Joe''s']   # cancelling
+flows:
+  - name: Raise   # after review
+    amount: 400.00
+    frequency: monthly
+    start: 2024-02-15
+  -
+    name: Trader Joe's # groceries
+    amount: -12.5
+    frequency: never
+    end: 2024-03-01
+`
+
+func TestParseScenario(t *testing.T) {
+	s, err := ParseScenario([]byte(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Start.String() != "2024-02-01" || s.Until.String() != "2024-04-30" || s.Target != 35 {
+		t.Errorf("got %+v", s)
+	}
+	if !slices.Equal(s.Skip, []string{"Netflix", "Trader Joe's"}) {
+		t.Errorf("got skip %q", s.Skip)
+	}
+	if len(s.Flows) != 2 {
+		t.Fatalf("got %d flows", len(s.Flows))
+	}
+	if f := s.Flows[0]; f.Name != "Raise" || f.Amount != 400000 || f.Frequency != "monthly" || f.Start.String() != "2024-02-15" || !time.Time(f.End).IsZero() {
+		t.Errorf("got %+v", f)
+	}
+	if f := s.Flows[1]; f.Name != "Trader Joe's" || f.Amount != -12500 || f.Frequency != "never" || f.End.String() != "2024-03-01" {
+		t.Errorf("got %+v", f)
+	}
+
+	s, err = ParseScenario([]byte("---\n# nothing\n"))
+	if err != nil || len(s.Flows) != 0 {
+		t.Errorf("got %+v, %v", s, err)
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"budget: Personal\n", "line 1: field budget not found"},
+		{"start: 02/01/2024\n", `invalid date "02/01/2024"`},
+		{"target: soon\n", "line 1: cannot unmarshal !!str `soon` into float64"},
+		{"target: -5\n", "target must be a positive number of days"},
+		{"skip: Netflix\n", "line 1: cannot unmarshal !!str `Netflix` into []string"},
+		{"flows: monthly\n", "line 1: cannot unmarshal !!str `monthly`"},
+		{"flows:\n  - name: Raise\n    amount: lots\n", `line 2: flow "Raise": invalid amount "lots"`},
+		{"flows:\n  - name: Raise\n    amount: 400\n", `line 2: flow "Raise" needs a frequency`},
+		{"flows:\n  - name: Raise\n    frequency: monthly\n", `line 2: flow "Raise" needs an amount`},
+		{"flows:\n  - name: Raise\n    amount: 400\n    frequency: fortnightly\n", `unknown frequency "fortnightly"`},
+		{"flows:\n  - name: Raise\n    every: month\n", `line 3: unknown flow setting "every"`},
+		{"flows:\n  - Raise\n", "line 2: a flow must be a mapping"},
+		{"- a\n- b\n", "line 1: cannot unmarshal !!seq into ageofmoney.Scenario"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseScenario([]byte(tt.in)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseScenario(%q): got error %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	txns := []*ynab.Transaction{
+		{ID: "pay", AccountID: "checking", Date: date("2024-01-01"), Amount: 100000},
+		{ID: "spend", AccountID: "checking", Date: date("2024-01-10"), Amount: -50000},
+	}
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "rent", AccountID: "checking", DateNext: date("2024-02-05"), Frequency: "monthly", Amount: -30000, PayeeName: "Landlord"},
+		{ID: "tv", AccountID: "checking", DateNext: date("2024-02-02"), Frequency: "weekly", Amount: -1000, PayeeName: "Netflix"},
+		{ID: "old", AccountID: "checking", DateNext: date("2024-02-02"), Frequency: "daily", Amount: -1000, Deleted: true},
+	}
+	s := &Scenario{
+		Start: date("2024-02-01"),
+		Until: date("2024-04-30"),
+		Skip:  []string{"netflix"},
+		Flows: []*Flow{{Name: "Raise", Amount: 40000, Frequency: "monthly", Start: date("2024-02-15")}},
+	}
+	sim, err := Simulate(testAccounts, txns, scheduled, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first rent is paid from January's pay, 35 days old. The second
+	// takes the rest of it, 64 days old, and 10 from the raise, 19 days old,
+	// and the third takes 30 of the raise, 50 days old.
+	var ages []float64
+	for _, w := range sim.Weeks {
+		ages = append(ages, w.Age)
+	}
+	want := []float64{9, 22, 22, 22, 22, 31, 31, 31, 31, 31, 35.75, 35.75, 35.75}
+	if !slices.Equal(ages, want) {
+		t.Errorf("got ages %v, want %v", ages, want)
+	}
+	if sim.Weeks[12].Date.String() != "2024-04-25" || sim.Reached != nil {
+		t.Errorf("got last week %v, reached %v", sim.Weeks[12].Date, sim.Reached)
+	}
+	var spent []string
+	for _, sp := range sim.Spending {
+		spent = append(spent, sp.TransactionID+"@"+sp.Date.String())
+	}
+	if got := strings.Join(spent, " "); got != "rent@2024-02-05 rent@2024-03-05 rent@2024-04-05" {
+		t.Errorf("got spending %s", got)
+	}
+
+	s.Target = 35
+	sim, err = Simulate(testAccounts, txns, scheduled, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Reached == nil || sim.Reached.Date.String() != "2024-04-11" || len(sim.Weeks) != 11 {
+		t.Errorf("got reached %+v after %d weeks", sim.Reached, len(sim.Weeks))
+	}
+
+	s = &Scenario{
+		Start: date("2024-02-01"),
+		Until: date("2024-02-10"),
+		Flows: []*Flow{{Name: "Car", Amount: -200000, Frequency: "never"}},
+	}
+	sim, err = Simulate(testAccounts, txns, nil, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := sim.Weeks[0]; w.Unfunded != 150000 || w.Age != 9 || !sim.Spending[0].Unfunded {
+		t.Errorf("got %+v", w)
+	}
+
+	s.Until = date("2024-01-01")
+	if _, err := Simulate(testAccounts, txns, nil, s); err == nil || !strings.Contains(err.Error(), "before it starts") {
+		t.Errorf("got %v", err)
+	}
+}
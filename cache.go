@@ -0,0 +1,406 @@
+package ynab
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Cache serves GET responses from a CacheStore so that data that rarely
+// changes, like the plan list, accounts and scheduled transactions, isn't
+// downloaded on every run. Install it with WithCache.
+//
+// Responses are cached by path and query string. The access token isn't part
+// of the key, so cached responses outlive token refreshes, but clients for
+// different YNAB users need their own CacheStore. A cached response is used without contacting YNAB until its TTL
+// runs out. After that, if the response had an ETag, the request is sent
+// with If-None-Match and a 304 Not Modified response renews the cached one.
+//
+// Any other request to a plan, e.g. UpdateTransaction or CreateCategory,
+// removes the cached responses for that plan and the plan list, whether or
+// not it succeeds.
+//
+// Delta requests, which set last_knowledge_of_server, are never cached; they
+// are already cheap, and Syncer relies on them being current.
+type Cache struct {
+	Store CacheStore
+	// DefaultTTL is the TTL for resources that aren't in TTLs. Zero means
+	// responses are only reused if YNAB confirms they haven't changed.
+	DefaultTTL time.Duration
+	// TTLs holds the TTL for each resource, keyed by the path segment after
+	// the plan ID, e.g. "accounts" or "scheduled_transactions". "plans" is
+	// the plan list, "plan" a single plan, and "user" the current user.
+	TTLs map[string]time.Duration
+
+	now func() time.Time
+}
+
+// NewCache returns a Cache that stores responses in store, with TTLs for the
+// resources that change least often: an hour for the plan list, the user and
+// plan settings, and 15 minutes for accounts, payees, categories and
+// scheduled transactions.
+func NewCache(store CacheStore) *Cache {
+	return &Cache{
+		Store: store,
+		TTLs: map[string]time.Duration{
+			"plans":                  time.Hour,
+			"user":                   time.Hour,
+			"settings":               time.Hour,
+			"accounts":               15 * time.Minute,
+			"payees":                 15 * time.Minute,
+			"categories":             15 * time.Minute,
+			"scheduled_transactions": 15 * time.Minute,
+		},
+		now: time.Now,
+	}
+}
+
+// WithCache caches responses in c.
+func WithCache(c *Cache) ClientOption {
+	return func(cl *Client) {
+		cl.cache = c
+	}
+}
+
+// A CachedResponse is a response saved in a CacheStore.
+type CachedResponse struct {
+	Key        string      `json:"key"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	ETag       string      `json:"etag,omitempty"`
+	// Stored is when the response was received or last confirmed to be
+	// current.
+	Stored time.Time `json:"stored"`
+}
+
+// A CacheStore holds cached responses. Each response belongs to a group, so a
+// change to a plan can drop its responses at once: the group is the plan ID
+// for a plan's resources, and "plans" or "user" for the plan list and the
+// user. Implementations must be safe for concurrent use. Errors are not
+// reported; a store that can't read an entry returns a miss.
+type CacheStore interface {
+	Get(group, key string) (*CachedResponse, bool)
+	Set(group, key string, r *CachedResponse)
+	// DeleteGroup removes every entry in group.
+	DeleteGroup(group string)
+	// Clear removes every entry.
+	Clear()
+}
+
+func (c *Cache) ttl(path string) time.Duration {
+	if ttl, ok := c.TTLs[resourceOf(path)]; ok {
+		return ttl
+	}
+	return c.DefaultTTL
+}
+
+func (c *Cache) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// resourceOf returns the kind of resource a request path refers to, e.g.
+// "accounts" for /v1/plans/{plan_id}/accounts/{account_id}.
+func resourceOf(path string) string {
+	i := strings.Index(path, "/plans")
+	if i < 0 {
+		return strings.Trim(path[strings.LastIndex(strings.TrimSuffix(path, "/"), "/")+1:], "/")
+	}
+	segments := strings.Split(strings.Trim(path[i:], "/"), "/")
+	switch len(segments) {
+	case 1:
+		return "plans"
+	case 2:
+		return "plan"
+	}
+	return segments[2]
+}
+
+// groupOf returns the CacheStore group of a request path: the plan ID for
+// /v1/plans/{plan_id}/..., or else the resource, e.g. "plans" or "user".
+func groupOf(path string) string {
+	i := strings.Index(path, "/plans/")
+	if i < 0 {
+		return resourceOf(path)
+	}
+	planID, _, _ := strings.Cut(path[i+len("/plans/"):], "/")
+	return planID
+}
+
+// cacheKey returns the key for a GET request: its path and query.
+func cacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.Query().Encode()
+}
+
+// cacheable reports whether r may be served from the cache.
+func cacheable(r *http.Request) bool {
+	return r.Method == "GET" && !r.URL.Query().Has("last_knowledge_of_server")
+}
+
+// invalidate removes the cached responses a change to path could affect.
+func (c *Cache) invalidate(path string) {
+	if !strings.Contains(path, "/plans/") {
+		return
+	}
+	planID := groupOf(path)
+	if planID == "last-used" || planID == "default" {
+		// we don't know which plan this is
+		c.Store.Clear()
+		return
+	}
+	for _, group := range []string{"plans", planID, "last-used", "default"} {
+		c.Store.DeleteGroup(group)
+	}
+}
+
+// middleware returns the transport middleware that implements the cache.
+// wait is called before each request that isn't answered from the cache is
+// sent, so cache hits don't use up the rate limit.
+func (c *Cache) middleware(next http.RoundTripper, wait func(context.Context) error) http.RoundTripper {
+	send := func(r *http.Request) (*http.Response, error) {
+		if err := wait(r.Context()); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(r)
+	}
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			c.invalidate(r.URL.Path)
+			return send(r)
+		}
+		if !cacheable(r) {
+			return send(r)
+		}
+		group, key := groupOf(r.URL.Path), cacheKey(r)
+		ttl := c.ttl(r.URL.Path)
+		entry, ok := c.Store.Get(group, key)
+		if ok && c.clock().Sub(entry.Stored) < ttl {
+			return entry.response(r), nil
+		}
+		if ok && entry.ETag != "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("If-None-Match", entry.ETag)
+		}
+		resp, err := send(r)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotModified && ok {
+			resp.Body.Close()
+			renewed := *entry
+			renewed.Stored = c.clock()
+			c.Store.Set(group, key, &renewed)
+			return renewed.response(r), nil
+		}
+		etag := resp.Header.Get("ETag")
+		if resp.StatusCode != http.StatusOK || (ttl <= 0 && etag == "") {
+			return resp, nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		c.Store.Set(group, key, &CachedResponse{
+			Key:        key,
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       body,
+			ETag:       etag,
+			Stored:     c.clock(),
+		})
+		return resp, nil
+	})
+}
+
+func (e *CachedResponse) response(r *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       r,
+	}
+}
+
+// MemoryCache is a CacheStore that keeps up to a fixed number of responses in
+// memory, evicting the least recently used.
+type MemoryCache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[memoryKey]*list.Element
+	lru     *list.List // of *memoryEntry, most recently used first
+}
+
+type memoryKey struct{ group, key string }
+
+type memoryEntry struct {
+	memoryKey
+	r *CachedResponse
+}
+
+// NewMemoryCache returns a MemoryCache that holds at most maxEntries
+// responses.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		panic("ynab: cache size must be positive")
+	}
+	return &MemoryCache{max: maxEntries, entries: make(map[memoryKey]*list.Element), lru: list.New()}
+}
+
+func (m *MemoryCache) Get(group, key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[memoryKey{group, key}]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(e)
+	return e.Value.(*memoryEntry).r, true
+}
+
+func (m *MemoryCache) Set(group, key string, r *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := memoryKey{group, key}
+	if e, ok := m.entries[k]; ok {
+		e.Value.(*memoryEntry).r = r
+		m.lru.MoveToFront(e)
+		return
+	}
+	m.entries[k] = m.lru.PushFront(&memoryEntry{k, r})
+	for m.lru.Len() > m.max {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).memoryKey)
+	}
+}
+
+func (m *MemoryCache) DeleteGroup(group string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, e := range m.entries {
+		if k.group == group {
+			m.lru.Remove(e)
+			delete(m.entries, k)
+		}
+	}
+}
+
+func (m *MemoryCache) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.entries)
+	m.lru.Init()
+}
+
+// DiskCache is a CacheStore that saves each response as a JSON file in a
+// directory, so cached responses survive restarts. Each group is a
+// subdirectory, so dropping a plan's responses is a single os.RemoveAll. The
+// directory is created if it does not exist.
+type DiskCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewDiskCache returns a DiskCache that stores responses in dir.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+// groupDir returns the directory for group. Plan IDs are used as they are;
+// anything that isn't a plain file name is hashed.
+func (d *DiskCache) groupDir(group string) string {
+	if !filepath.IsLocal(group) || strings.ContainsAny(group, `/\`) {
+		sum := sha256.Sum256([]byte(group))
+		group = hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(d.dir, group)
+}
+
+func (d *DiskCache) path(group, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.groupDir(group), hex.EncodeToString(sum[:])+".json")
+}
+
+func (d *DiskCache) Get(group, key string) (*CachedResponse, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	data, err := os.ReadFile(d.path(group, key))
+	if err != nil {
+		return nil, false
+	}
+	r := new(CachedResponse)
+	if err := json.Unmarshal(data, r); err != nil || r.Key != key {
+		return nil, false
+	}
+	return r, true
+}
+
+func (d *DiskCache) Set(group, key string, r *CachedResponse) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dir := d.groupDir(group)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return
+	}
+	// Write to a temporary file first so a crash can't leave a partial entry.
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), d.path(group, key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (d *DiskCache) DeleteGroup(group string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	os.RemoveAll(d.groupDir(group))
+}
+
+// Clear removes the cached responses in every group. Other files in the
+// directory are left alone.
+func (d *DiskCache) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(d.dir, "*", "*.json"))
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		os.Remove(f)
+		// Fails unless the group is now empty.
+		os.Remove(filepath.Dir(f))
+	}
+}
@@ -0,0 +1,240 @@
+package ynab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	revalidated := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := r.Method + " " + r.URL.Path
+		if q := r.URL.Query().Encode(); q != "" {
+			key += "?" + q
+		}
+		hits[key]++
+		switch r.URL.Path {
+		case "/plans":
+			w.Write([]byte(`{"data": {"plans": [{"id": "plan-1"}]}}`))
+		case "/plans/plan-1/accounts":
+			if r.Method == "POST" {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"data": {"account": {"id": "acct-2"}}}`))
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidated++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte(`{"data": {"accounts": [{"id": "acct-1"}], "server_knowledge": 3}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"id": "404.2", "name": "resource_not_found", "detail": "Not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewCache(NewMemoryCache(100))
+	cache.now = func() time.Time { return now }
+	client := NewClient("token", WithBaseURL(server.URL), WithCache(cache))
+	ctx := context.Background()
+	plan := client.Plans("plan-1")
+
+	accounts := func() {
+		t.Helper()
+		resp, err := plan.Accounts(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data.Accounts) != 1 || resp.Data.Accounts[0].ID != "acct-1" {
+			t.Fatalf("unexpected accounts: %+v", resp.Data.Accounts)
+		}
+	}
+	count := func(key string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[key]
+	}
+
+	accounts()
+	accounts()
+	if n := count("GET /plans/plan-1/accounts"); n != 1 {
+		t.Fatalf("expected one request while the response is fresh, got %d", n)
+	}
+
+	// After the TTL, the cached response is revalidated with its ETag.
+	now = now.Add(time.Hour)
+	accounts()
+	if n := count("GET /plans/plan-1/accounts"); n != 2 || revalidated != 1 {
+		t.Fatalf("expected a conditional request, got %d requests and %d revalidations", n, revalidated)
+	}
+	accounts()
+	if n := count("GET /plans/plan-1/accounts"); n != 2 {
+		t.Fatalf("expected a 304 to renew the cached response, got %d requests", n)
+	}
+
+	// Delta requests always go to the server.
+	for range 2 {
+		if _, err := plan.Accounts(ctx, url.Values{"last_knowledge_of_server": {"3"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := count("GET /plans/plan-1/accounts?last_knowledge_of_server=3"); n != 2 {
+		t.Fatalf("expected delta requests to skip the cache, got %d", n)
+	}
+
+	// A change to the plan drops its cached responses and the plan list.
+	if _, err := client.GetPlans(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plan.CreateAccount(ctx, &CreateAccountRequest{Account: &SaveAccount{Name: "Savings"}}); err != nil {
+		t.Fatal(err)
+	}
+	accounts()
+	if _, err := client.GetPlans(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("GET /plans/plan-1/accounts"); n != 3 {
+		t.Errorf("expected accounts to be fetched again after CreateAccount, got %d requests", n)
+	}
+	if n := count("GET /plans"); n != 2 {
+		t.Errorf("expected the plan list to be fetched again after CreateAccount, got %d requests", n)
+	}
+
+	// Errors are not cached.
+	for range 2 {
+		if _, err := client.Plans("plan-2").Accounts(ctx, nil); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if n := count("GET /plans/plan-2/accounts"); n != 2 {
+		t.Errorf("expected errors not to be cached, got %d requests", n)
+	}
+}
+
+func TestCacheSkipsRateLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"plans": []}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithCache(NewCache(NewMemoryCache(10))))
+	limiter, err := NewRateLimiter(1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetRateLimiter(limiter)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for range 3 {
+		if _, err := client.GetPlans(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCacheKeyIgnoresToken(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {"plans": []}}`))
+	}))
+	defer server.Close()
+
+	// e.g. an OAuth client before and after its access token is refreshed
+	cache := NewCache(NewMemoryCache(10))
+	for _, token := range []string{"token-1", "token-2"} {
+		client := NewClient(token, WithBaseURL(server.URL), WithCache(cache))
+		if _, err := client.GetPlans(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected a new token to reuse the cached response, got %d requests", requests)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	m := NewMemoryCache(2)
+	for _, key := range []string{"a", "b"} {
+		m.Set("plan", key, &CachedResponse{Key: key})
+	}
+	m.Get("plan", "a")
+	m.Set("plan", "c", &CachedResponse{Key: "c"})
+	if _, ok := m.Get("plan", "b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := m.Get("plan", key); !ok {
+			t.Errorf("expected %q to be cached", key)
+		}
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDiskCache(dir)
+	entries := []struct{ group, key string }{
+		{"a", "/plans/a/accounts?"},
+		{"a", "/plans/a?"},
+		{"b", "/plans/b/accounts?"},
+		{"plans", "/plans?"},
+	}
+	for i, e := range entries {
+		d.Set(e.group, e.key, &CachedResponse{Key: e.key, StatusCode: 200, Body: fmt.Appendf(nil, "body %d", i)})
+	}
+
+	// A new DiskCache on the same directory sees the same entries.
+	d = NewDiskCache(dir)
+	r, ok := d.Get("a", "/plans/a?")
+	if !ok || string(r.Body) != "body 1" {
+		t.Fatalf("expected a cached response, got %v, %v", r, ok)
+	}
+	d.DeleteGroup("a")
+	if _, err := os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("expected the group's directory to be removed, got %v", err)
+	}
+	for _, e := range entries {
+		if _, ok := d.Get(e.group, e.key); ok != (e.group != "a") {
+			t.Errorf("%s: got cached = %t after deleting group a", e.key, ok)
+		}
+	}
+
+	// Groups that aren't file names don't escape the directory.
+	d.Set("../x", "key", &CachedResponse{Key: "key"})
+	if _, ok := d.Get("../x", "key"); !ok {
+		t.Error("expected an entry for group ../x")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "x")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing outside the cache directory, got %v", err)
+	}
+
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	d.Clear()
+	for _, e := range entries {
+		if _, ok := d.Get(e.group, e.key); ok {
+			t.Errorf("%s: expected Clear to remove the entry", e.key)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("expected Clear to leave other files alone: %v", err)
+	}
+}
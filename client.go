@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -18,7 +20,16 @@ type Client struct {
 	*restclient.Client
 
 	userAgent string
-	Plans     func(planID string) *PlanService
+	retry     *RetryPolicy
+	limiter   *RateLimiter
+	// middleware wraps the transport set with SetTransport, outermost first.
+	middleware []Middleware
+	// instrumentation, if not nil, receives spans and metrics for each call.
+	instrumentation *Instrumentation
+	// cache, if not nil, serves GET responses without contacting YNAB.
+	cache *Cache
+
+	Plans func(planID string) *PlanService
 	// Budgets is deprecated. Use Plans.
 	Budgets func(budgetID string) *PlanService
 }
@@ -35,8 +46,12 @@ type TransactionWrapper struct {
 	Transaction *Transaction `json:"transaction"`
 }
 
+// CreateTransactionRequest is the request body for creating transactions. Set
+// either Transaction, to create a single transaction, or Transactions, to
+// create several at once.
 type CreateTransactionRequest struct {
-	Transaction *NewTransaction `json:"transaction"`
+	Transaction  *NewTransaction   `json:"transaction,omitempty"`
+	Transactions []*NewTransaction `json:"transactions,omitempty"`
 }
 
 type NewTransaction struct {
@@ -69,9 +84,11 @@ type CreateTransactionResponse struct {
 }
 
 type CreateTransactionData struct {
-	TransactionIDs  []string     `json:"transaction_ids"`
-	Transaction     *Transaction `json:"transaction,omitempty"`
-	ServerKnowledge int64        `json:"server_knowledge"`
+	TransactionIDs     []string       `json:"transaction_ids"`
+	Transaction        *Transaction   `json:"transaction,omitempty"`
+	Transactions       []*Transaction `json:"transactions,omitempty"`         // If multiple transactions were specified, the transactions that were saved
+	DuplicateImportIDs []string       `json:"duplicate_import_ids,omitempty"` // If multiple transactions were specified, the import_ids that were not created because of an existing import_id on the same account
+	ServerKnowledge    int64          `json:"server_knowledge"`
 }
 
 type UpdateTransactionRequest struct {
@@ -229,6 +246,21 @@ func (t Date) String() string {
 	return time.Time(t).Format("2006-01-02")
 }
 
+// UnmarshalText parses a date in YYYY-MM-DD format, in the local time zone.
+func (t *Date) UnmarshalText(b []byte) error {
+	t2, err := time.ParseInLocation("2006-01-02", string(b), time.Local)
+	if err != nil {
+		return fmt.Errorf("ynab: invalid date %q, use YYYY-MM-DD", b)
+	}
+	*t = Date(t2)
+	return nil
+}
+
+// MarshalText formats t in YYYY-MM-DD format.
+func (t Date) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
 func (t Date) GoString() string {
 	return time.Time(t).GoString()
 }
@@ -278,7 +310,7 @@ type ScheduledTransaction struct {
 	Deleted           bool
 	FlagColor         FlagColor        `json:"flag_color"`
 	FlagName          types.NullString `json:"flag_name"` // The customized name of a transaction flag
-	Frequency         string
+	Frequency         Frequency
 	ID                string `json:"id"`
 	Memo              string
 	PayeeID           types.NullString `json:"payee_id"`
@@ -399,14 +431,14 @@ type AccountListWrapper struct {
 }
 
 type Plan struct {
-	ID             string     `json:"id"`
-	Name           string     `json:"name"`
-	LastModifiedOn string     `json:"last_modified_on"` // The last time any changes were made to the plan from either a web or mobile client
-	FirstMonth     string     `json:"first_month"`      // The earliest plan month
-	LastMonth      string     `json:"last_month"`       // The latest plan month
-	DateFormat     any        `json:"date_format"`
-	CurrencyFormat any        `json:"currency_format"`
-	Accounts       []*Account `json:"accounts,omitempty"`
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	LastModifiedOn string          `json:"last_modified_on"` // The last time any changes were made to the plan from either a web or mobile client
+	FirstMonth     string          `json:"first_month"`      // The earliest plan month
+	LastMonth      string          `json:"last_month"`       // The latest plan month
+	DateFormat     *DateFormat     `json:"date_format"`
+	CurrencyFormat *CurrencyFormat `json:"currency_format"`
+	Accounts       []*Account      `json:"accounts,omitempty"`
 }
 
 // Budget is deprecated. Use Plan.
@@ -458,8 +490,8 @@ type PlanDetail struct {
 	LastModifiedOn           string                  `json:"last_modified_on"` // The last time any changes were made to the plan from either a web or mobile client
 	FirstMonth               string                  `json:"first_month"`      // The earliest plan month
 	LastMonth                string                  `json:"last_month"`       // The latest plan month
-	DateFormat               any                     `json:"date_format"`
-	CurrencyFormat           any                     `json:"currency_format"`
+	DateFormat               *DateFormat             `json:"date_format"`
+	CurrencyFormat           *CurrencyFormat         `json:"currency_format"`
 	Accounts                 []*Account              `json:"accounts"`
 	Payees                   []*Payee                `json:"payees"`
 	PayeeLocations           []*PayeeLocation        `json:"payee_locations"`
@@ -494,6 +526,7 @@ type BudgetDetailResponse struct {
 // GetPlan returns a full plan export with all months and categories.
 // This is more efficient than making per-month API calls.
 func (b *PlanService) GetPlan(ctx context.Context) (*PlanDetailResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetPlan")
 	req, err := b.client.NewRequestWithContext(ctx, "GET", "/plans/"+b.id, nil)
 	if err != nil {
 		return nil, err
@@ -507,6 +540,7 @@ func (b *PlanService) GetPlan(ctx context.Context) (*PlanDetailResponse, error)
 
 // GetBudget is deprecated. Use GetPlan.
 func (b *PlanService) GetBudget(ctx context.Context) (*BudgetDetailResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetBudget")
 	req, err := b.client.NewRequestWithContext(ctx, "GET", "/plans/"+b.id, nil)
 	if err != nil {
 		return nil, err
@@ -519,6 +553,7 @@ func (b *PlanService) GetBudget(ctx context.Context) (*BudgetDetailResponse, err
 }
 
 func (c *Client) GetPlans(ctx context.Context, data url.Values) (*PlanListResponse, error) {
+	ctx = withOperation(ctx, "Client.GetPlans")
 	req, err := c.NewRequestWithContext(ctx, "GET", "/plans?"+data.Encode(), nil)
 	if err != nil {
 		return nil, err
@@ -532,6 +567,7 @@ func (c *Client) GetPlans(ctx context.Context, data url.Values) (*PlanListRespon
 
 // GetBudgets is deprecated. Use GetPlans.
 func (c *Client) GetBudgets(ctx context.Context, data url.Values) (*BudgetListResponse, error) {
+	ctx = withOperation(ctx, "Client.GetBudgets")
 	req, err := c.NewRequestWithContext(ctx, "GET", "/plans?"+data.Encode(), nil)
 	if err != nil {
 		return nil, err
@@ -544,6 +580,7 @@ func (c *Client) GetBudgets(ctx context.Context, data url.Values) (*BudgetListRe
 }
 
 func (b *PlanService) Accounts(ctx context.Context, data url.Values) (*AccountListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.Accounts")
 	req, err := b.client.NewRequestWithContext(ctx, "GET", "/plans/"+b.id+"/accounts?"+data.Encode(), nil)
 	if err != nil {
 		return nil, err
@@ -556,6 +593,7 @@ func (b *PlanService) Accounts(ctx context.Context, data url.Values) (*AccountLi
 }
 
 func (b *PlanService) Transactions(ctx context.Context, data url.Values) (*TransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.Transactions")
 	req, err := b.client.NewRequestWithContext(ctx, "GET", "/plans/"+b.id+"/transactions?"+data.Encode(), nil)
 	if err != nil {
 		return nil, err
@@ -568,6 +606,7 @@ func (b *PlanService) Transactions(ctx context.Context, data url.Values) (*Trans
 }
 
 func (b *PlanService) ScheduledTransactions(ctx context.Context, data url.Values) (*ScheduledTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ScheduledTransactions")
 	req, err := b.client.NewRequestWithContext(ctx, "GET", "/plans/"+b.id+"/scheduled_transactions?"+data.Encode(), nil)
 	if err != nil {
 		return nil, err
@@ -580,6 +619,7 @@ func (b *PlanService) ScheduledTransactions(ctx context.Context, data url.Values
 }
 
 func (b *PlanService) Categories(ctx context.Context, data url.Values) (*CategoryListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.Categories")
 	req, err := b.client.NewRequestWithContext(ctx, "GET", "/plans/"+b.id+"/categories?"+data.Encode(), nil)
 	if err != nil {
 		return nil, err
@@ -595,6 +635,7 @@ func (b *PlanService) Categories(ctx context.Context, data url.Values) (*Categor
 // The month should be in ISO format (e.g., "2024-01-01") or "current" for the current month.
 // Amounts (budgeted, activity, balance) are specific to the requested month.
 func (b *PlanService) GetMonthCategory(ctx context.Context, month string, categoryID string) (*CategoryResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetMonthCategory")
 	resp := new(CategoryResponse)
 	path := "/plans/" + b.id + "/months/" + month + "/categories/" + categoryID
 	err := b.client.MakeRequest(ctx, "GET", path, nil, nil, resp)
@@ -608,6 +649,7 @@ func (b *PlanService) GetMonthCategory(ctx context.Context, month string, catego
 // The month should be in ISO format (e.g., "2024-01-01") or "current" for the current month.
 // The budgeted amount is in milliunits (e.g., $50.00 = 50000).
 func (b *PlanService) UpdateMonthCategory(ctx context.Context, month string, categoryID string, budgeted int64) (*SaveCategoryResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdateMonthCategory")
 	req := &UpdateMonthCategoryRequest{
 		Category: SaveMonthCategory{Budgeted: budgeted},
 	}
@@ -938,7 +980,7 @@ type SaveScheduledTransaction struct {
 	CategoryID types.NullString `json:"category_id"`      // Credit Card Payment categories are not permitted. Split scheduled transactions are not supported.
 	Memo       types.NullString `json:"memo"`
 	FlagColor  FlagColor        `json:"flag_color,omitempty"`
-	Frequency  string           `json:"frequency,omitempty"`
+	Frequency  Frequency        `json:"frequency,omitempty"`
 }
 
 // CreateScheduledTransactionRequest is the request body for creating a scheduled transaction.
@@ -966,6 +1008,7 @@ func (c *Client) PutResource(ctx context.Context, pathPart string, sid string, r
 }
 
 func (b *PlanService) CreateTransaction(ctx context.Context, req *CreateTransactionRequest) (*CreateTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.CreateTransaction")
 	resp := new(CreateTransactionResponse)
 	err := b.client.MakeRequest(ctx, "POST", "/plans/"+b.id+"/transactions", nil, req, resp)
 	if err != nil {
@@ -974,7 +1017,45 @@ func (b *PlanService) CreateTransaction(ctx context.Context, req *CreateTransact
 	return resp, nil
 }
 
+// CreateTransactionsBatchSize is the maximum number of transactions
+// CreateTransactions sends in a single request.
+const CreateTransactionsBatchSize = 500
+
+// CreateTransactionsResult is the combined result of every request made by
+// CreateTransactions.
+type CreateTransactionsResult struct {
+	TransactionIDs     []string       // The IDs of the transactions that were created
+	Transactions       []*Transaction // The transactions that were created
+	DuplicateImportIDs []string       // import_ids that were skipped because a transaction with the same import_id exists on the same account
+	ServerKnowledge    int64          // The server knowledge after the last request
+}
+
+// CreateTransactions creates several transactions, sending them in batches of
+// at most CreateTransactionsBatchSize. Transactions whose import_id already
+// exists on the same account are not created; their import_ids are reported
+// in DuplicateImportIDs.
+//
+// If a request fails, CreateTransactions stops and returns the error, along
+// with the result of the batches that were created before the failure.
+func (b *PlanService) CreateTransactions(ctx context.Context, txns []*NewTransaction) (*CreateTransactionsResult, error) {
+	ctx = withOperation(ctx, "PlanService.CreateTransactions")
+	result := new(CreateTransactionsResult)
+	for start := 0; start < len(txns); start += CreateTransactionsBatchSize {
+		end := min(start+CreateTransactionsBatchSize, len(txns))
+		resp, err := b.CreateTransaction(ctx, &CreateTransactionRequest{Transactions: txns[start:end]})
+		if err != nil {
+			return result, err
+		}
+		result.TransactionIDs = append(result.TransactionIDs, resp.Data.TransactionIDs...)
+		result.Transactions = append(result.Transactions, resp.Data.Transactions...)
+		result.DuplicateImportIDs = append(result.DuplicateImportIDs, resp.Data.DuplicateImportIDs...)
+		result.ServerKnowledge = resp.Data.ServerKnowledge
+	}
+	return result, nil
+}
+
 func (b *PlanService) UpdateTransaction(ctx context.Context, transactionID string, req *UpdateTransactionRequest) (*TransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdateTransaction")
 	resp := new(TransactionResponse)
 	err := b.client.PutResource(ctx, "/plans/"+b.id+"/transactions", transactionID, req, resp)
 	if err != nil {
@@ -984,6 +1065,7 @@ func (b *PlanService) UpdateTransaction(ctx context.Context, transactionID strin
 }
 
 func (b *PlanService) DeleteTransaction(ctx context.Context, transactionID string) (*TransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.DeleteTransaction")
 	resp := new(TransactionResponse)
 	err := b.client.MakeRequest(ctx, "DELETE", "/plans/"+b.id+"/transactions/"+transactionID, nil, nil, resp)
 	if err != nil {
@@ -994,6 +1076,7 @@ func (b *PlanService) DeleteTransaction(ctx context.Context, transactionID strin
 
 // GetUser returns the authenticated user.
 func (c *Client) GetUser(ctx context.Context) (*UserResponse, error) {
+	ctx = withOperation(ctx, "Client.GetUser")
 	resp := new(UserResponse)
 	err := c.MakeRequest(ctx, "GET", "/user", nil, nil, resp)
 	if err != nil {
@@ -1004,6 +1087,7 @@ func (c *Client) GetUser(ctx context.Context) (*UserResponse, error) {
 
 // GetSettings returns the settings for this plan.
 func (b *PlanService) GetSettings(ctx context.Context) (*BudgetSettingsResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetSettings")
 	resp := new(BudgetSettingsResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/settings", nil, nil, resp)
 	if err != nil {
@@ -1014,6 +1098,7 @@ func (b *PlanService) GetSettings(ctx context.Context) (*BudgetSettingsResponse,
 
 // CreateAccount creates a new account in this plan.
 func (b *PlanService) CreateAccount(ctx context.Context, req *CreateAccountRequest) (*AccountResponse, error) {
+	ctx = withOperation(ctx, "PlanService.CreateAccount")
 	resp := new(AccountResponse)
 	err := b.client.MakeRequest(ctx, "POST", "/plans/"+b.id+"/accounts", nil, req, resp)
 	if err != nil {
@@ -1024,6 +1109,7 @@ func (b *PlanService) CreateAccount(ctx context.Context, req *CreateAccountReque
 
 // GetAccount returns a single account by ID.
 func (b *PlanService) GetAccount(ctx context.Context, accountID string) (*AccountResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetAccount")
 	resp := new(AccountResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/accounts/"+accountID, nil, nil, resp)
 	if err != nil {
@@ -1034,6 +1120,7 @@ func (b *PlanService) GetAccount(ctx context.Context, accountID string) (*Accoun
 
 // GetCategory returns a single category by ID.
 func (b *PlanService) GetCategory(ctx context.Context, categoryID string) (*CategoryResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetCategory")
 	resp := new(CategoryResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/categories/"+categoryID, nil, nil, resp)
 	if err != nil {
@@ -1044,6 +1131,7 @@ func (b *PlanService) GetCategory(ctx context.Context, categoryID string) (*Cate
 
 // CreateCategory creates a category in this plan.
 func (b *PlanService) CreateCategory(ctx context.Context, req *CreateCategoryRequest) (*SaveCategoryResponse, error) {
+	ctx = withOperation(ctx, "PlanService.CreateCategory")
 	resp := new(SaveCategoryResponse)
 	err := b.client.MakeRequest(ctx, "POST", "/plans/"+b.id+"/categories", nil, req, resp)
 	if err != nil {
@@ -1054,6 +1142,7 @@ func (b *PlanService) CreateCategory(ctx context.Context, req *CreateCategoryReq
 
 // UpdateCategory updates a category.
 func (b *PlanService) UpdateCategory(ctx context.Context, categoryID string, req *UpdateCategoryRequest) (*SaveCategoryResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdateCategory")
 	resp := new(SaveCategoryResponse)
 	err := b.client.MakeRequest(ctx, "PATCH", "/plans/"+b.id+"/categories/"+categoryID, nil, req, resp)
 	if err != nil {
@@ -1064,6 +1153,7 @@ func (b *PlanService) UpdateCategory(ctx context.Context, categoryID string, req
 
 // CreateCategoryGroup creates a category group in this plan.
 func (b *PlanService) CreateCategoryGroup(ctx context.Context, req *CreateCategoryGroupRequest) (*SaveCategoryGroupResponse, error) {
+	ctx = withOperation(ctx, "PlanService.CreateCategoryGroup")
 	resp := new(SaveCategoryGroupResponse)
 	err := b.client.MakeRequest(ctx, "POST", "/plans/"+b.id+"/category_groups", nil, req, resp)
 	if err != nil {
@@ -1074,6 +1164,7 @@ func (b *PlanService) CreateCategoryGroup(ctx context.Context, req *CreateCatego
 
 // UpdateCategoryGroup updates a category group.
 func (b *PlanService) UpdateCategoryGroup(ctx context.Context, categoryGroupID string, req *UpdateCategoryGroupRequest) (*SaveCategoryGroupResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdateCategoryGroup")
 	resp := new(SaveCategoryGroupResponse)
 	err := b.client.MakeRequest(ctx, "PATCH", "/plans/"+b.id+"/category_groups/"+categoryGroupID, nil, req, resp)
 	if err != nil {
@@ -1084,6 +1175,7 @@ func (b *PlanService) UpdateCategoryGroup(ctx context.Context, categoryGroupID s
 
 // Payees returns the list of payees for this plan.
 func (b *PlanService) Payees(ctx context.Context, data url.Values) (*PayeeListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.Payees")
 	resp := new(PayeeListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/payees", data, nil, resp)
 	if err != nil {
@@ -1094,6 +1186,7 @@ func (b *PlanService) Payees(ctx context.Context, data url.Values) (*PayeeListRe
 
 // GetPayee returns a single payee by ID.
 func (b *PlanService) GetPayee(ctx context.Context, payeeID string) (*PayeeResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetPayee")
 	resp := new(PayeeResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/payees/"+payeeID, nil, nil, resp)
 	if err != nil {
@@ -1104,6 +1197,7 @@ func (b *PlanService) GetPayee(ctx context.Context, payeeID string) (*PayeeRespo
 
 // UpdatePayee updates a payee.
 func (b *PlanService) UpdatePayee(ctx context.Context, payeeID string, req *UpdatePayeeRequest) (*SavePayeeResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdatePayee")
 	resp := new(SavePayeeResponse)
 	err := b.client.MakeRequest(ctx, "PATCH", "/plans/"+b.id+"/payees/"+payeeID, nil, req, resp)
 	if err != nil {
@@ -1114,6 +1208,7 @@ func (b *PlanService) UpdatePayee(ctx context.Context, payeeID string, req *Upda
 
 // PayeeLocations returns all payee locations for this plan.
 func (b *PlanService) PayeeLocations(ctx context.Context) (*PayeeLocationListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.PayeeLocations")
 	resp := new(PayeeLocationListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/payee_locations", nil, nil, resp)
 	if err != nil {
@@ -1124,6 +1219,7 @@ func (b *PlanService) PayeeLocations(ctx context.Context) (*PayeeLocationListRes
 
 // GetPayeeLocation returns a single payee location by ID.
 func (b *PlanService) GetPayeeLocation(ctx context.Context, locationID string) (*PayeeLocationResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetPayeeLocation")
 	resp := new(PayeeLocationResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/payee_locations/"+locationID, nil, nil, resp)
 	if err != nil {
@@ -1134,6 +1230,7 @@ func (b *PlanService) GetPayeeLocation(ctx context.Context, locationID string) (
 
 // PayeeLocationsByPayee returns all payee locations for a specific payee.
 func (b *PlanService) PayeeLocationsByPayee(ctx context.Context, payeeID string) (*PayeeLocationListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.PayeeLocationsByPayee")
 	resp := new(PayeeLocationListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/payees/"+payeeID+"/payee_locations", nil, nil, resp)
 	if err != nil {
@@ -1144,6 +1241,7 @@ func (b *PlanService) PayeeLocationsByPayee(ctx context.Context, payeeID string)
 
 // Months returns the list of plan months for this plan.
 func (b *PlanService) Months(ctx context.Context, data url.Values) (*MonthSummaryListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.Months")
 	resp := new(MonthSummaryListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/months", data, nil, resp)
 	if err != nil {
@@ -1155,6 +1253,7 @@ func (b *PlanService) Months(ctx context.Context, data url.Values) (*MonthSummar
 // GetMonth returns a single plan month.
 // The month should be in ISO format (e.g., "2024-01-01") or "current".
 func (b *PlanService) GetMonth(ctx context.Context, month string) (*MonthDetailResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetMonth")
 	resp := new(MonthDetailResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/months/"+month, nil, nil, resp)
 	if err != nil {
@@ -1165,6 +1264,7 @@ func (b *PlanService) GetMonth(ctx context.Context, month string) (*MonthDetailR
 
 // MoneyMovements returns all money movements for this plan.
 func (b *PlanService) MoneyMovements(ctx context.Context, data url.Values) (*MoneyMovementsResponse, error) {
+	ctx = withOperation(ctx, "PlanService.MoneyMovements")
 	resp := new(MoneyMovementsResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/money_movements", data, nil, resp)
 	if err != nil {
@@ -1175,6 +1275,7 @@ func (b *PlanService) MoneyMovements(ctx context.Context, data url.Values) (*Mon
 
 // MonthMoneyMovements returns money movements for a specific month.
 func (b *PlanService) MonthMoneyMovements(ctx context.Context, month string, data url.Values) (*MoneyMovementsResponse, error) {
+	ctx = withOperation(ctx, "PlanService.MonthMoneyMovements")
 	resp := new(MoneyMovementsResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/months/"+month+"/money_movements", data, nil, resp)
 	if err != nil {
@@ -1185,6 +1286,7 @@ func (b *PlanService) MonthMoneyMovements(ctx context.Context, month string, dat
 
 // MoneyMovementGroups returns all money movement groups for this plan.
 func (b *PlanService) MoneyMovementGroups(ctx context.Context, data url.Values) (*MoneyMovementGroupsResponse, error) {
+	ctx = withOperation(ctx, "PlanService.MoneyMovementGroups")
 	resp := new(MoneyMovementGroupsResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/money_movement_groups", data, nil, resp)
 	if err != nil {
@@ -1195,6 +1297,7 @@ func (b *PlanService) MoneyMovementGroups(ctx context.Context, data url.Values)
 
 // MonthMoneyMovementGroups returns money movement groups for a specific month.
 func (b *PlanService) MonthMoneyMovementGroups(ctx context.Context, month string, data url.Values) (*MoneyMovementGroupsResponse, error) {
+	ctx = withOperation(ctx, "PlanService.MonthMoneyMovementGroups")
 	resp := new(MoneyMovementGroupsResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/months/"+month+"/money_movement_groups", data, nil, resp)
 	if err != nil {
@@ -1205,6 +1308,7 @@ func (b *PlanService) MonthMoneyMovementGroups(ctx context.Context, month string
 
 // GetTransaction returns a single transaction by ID.
 func (b *PlanService) GetTransaction(ctx context.Context, transactionID string) (*TransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetTransaction")
 	resp := new(TransactionResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/transactions/"+transactionID, nil, nil, resp)
 	if err != nil {
@@ -1215,6 +1319,7 @@ func (b *PlanService) GetTransaction(ctx context.Context, transactionID string)
 
 // UpdateTransactions bulk-updates multiple transactions.
 func (b *PlanService) UpdateTransactions(ctx context.Context, req *UpdateTransactionsRequest) (*CreateTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdateTransactions")
 	resp := new(CreateTransactionResponse)
 	err := b.client.MakeRequest(ctx, "PATCH", "/plans/"+b.id+"/transactions", nil, req, resp)
 	if err != nil {
@@ -1225,6 +1330,7 @@ func (b *PlanService) UpdateTransactions(ctx context.Context, req *UpdateTransac
 
 // ImportTransactions imports transactions from linked accounts.
 func (b *PlanService) ImportTransactions(ctx context.Context) (*TransactionsImportResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ImportTransactions")
 	resp := new(TransactionsImportResponse)
 	err := b.client.MakeRequest(ctx, "POST", "/plans/"+b.id+"/transactions/import", nil, nil, resp)
 	if err != nil {
@@ -1235,6 +1341,7 @@ func (b *PlanService) ImportTransactions(ctx context.Context) (*TransactionsImpo
 
 // AccountTransactions returns the transactions for a specific account.
 func (b *PlanService) AccountTransactions(ctx context.Context, accountID string, data url.Values) (*TransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.AccountTransactions")
 	resp := new(TransactionListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/accounts/"+accountID+"/transactions", data, nil, resp)
 	if err != nil {
@@ -1245,6 +1352,7 @@ func (b *PlanService) AccountTransactions(ctx context.Context, accountID string,
 
 // CategoryTransactions returns the transactions for a specific category.
 func (b *PlanService) CategoryTransactions(ctx context.Context, categoryID string, data url.Values) (*HybridTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.CategoryTransactions")
 	resp := new(HybridTransactionListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/categories/"+categoryID+"/transactions", data, nil, resp)
 	if err != nil {
@@ -1255,6 +1363,7 @@ func (b *PlanService) CategoryTransactions(ctx context.Context, categoryID strin
 
 // PayeeTransactions returns the transactions for a specific payee.
 func (b *PlanService) PayeeTransactions(ctx context.Context, payeeID string, data url.Values) (*HybridTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.PayeeTransactions")
 	resp := new(HybridTransactionListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/payees/"+payeeID+"/transactions", data, nil, resp)
 	if err != nil {
@@ -1265,6 +1374,7 @@ func (b *PlanService) PayeeTransactions(ctx context.Context, payeeID string, dat
 
 // MonthTransactions returns the transactions for a specific month.
 func (b *PlanService) MonthTransactions(ctx context.Context, month string, data url.Values) (*HybridTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.MonthTransactions")
 	resp := new(HybridTransactionListResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/months/"+month+"/transactions", data, nil, resp)
 	if err != nil {
@@ -1275,6 +1385,7 @@ func (b *PlanService) MonthTransactions(ctx context.Context, month string, data
 
 // CreateScheduledTransaction creates a new scheduled transaction.
 func (b *PlanService) CreateScheduledTransaction(ctx context.Context, req *CreateScheduledTransactionRequest) (*ScheduledTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.CreateScheduledTransaction")
 	resp := new(ScheduledTransactionResponse)
 	err := b.client.MakeRequest(ctx, "POST", "/plans/"+b.id+"/scheduled_transactions", nil, req, resp)
 	if err != nil {
@@ -1285,6 +1396,7 @@ func (b *PlanService) CreateScheduledTransaction(ctx context.Context, req *Creat
 
 // GetScheduledTransaction returns a single scheduled transaction by ID.
 func (b *PlanService) GetScheduledTransaction(ctx context.Context, scheduledTransactionID string) (*ScheduledTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.GetScheduledTransaction")
 	resp := new(ScheduledTransactionResponse)
 	err := b.client.MakeRequest(ctx, "GET", "/plans/"+b.id+"/scheduled_transactions/"+scheduledTransactionID, nil, nil, resp)
 	if err != nil {
@@ -1295,6 +1407,7 @@ func (b *PlanService) GetScheduledTransaction(ctx context.Context, scheduledTran
 
 // UpdateScheduledTransaction updates an existing scheduled transaction.
 func (b *PlanService) UpdateScheduledTransaction(ctx context.Context, scheduledTransactionID string, req *UpdateScheduledTransactionRequest) (*ScheduledTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.UpdateScheduledTransaction")
 	resp := new(ScheduledTransactionResponse)
 	err := b.client.PutResource(ctx, "/plans/"+b.id+"/scheduled_transactions", scheduledTransactionID, req, resp)
 	if err != nil {
@@ -1305,6 +1418,7 @@ func (b *PlanService) UpdateScheduledTransaction(ctx context.Context, scheduledT
 
 // DeleteScheduledTransaction deletes a scheduled transaction.
 func (b *PlanService) DeleteScheduledTransaction(ctx context.Context, scheduledTransactionID string) (*ScheduledTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.DeleteScheduledTransaction")
 	resp := new(ScheduledTransactionResponse)
 	err := b.client.MakeRequest(ctx, "DELETE", "/plans/"+b.id+"/scheduled_transactions/"+scheduledTransactionID, nil, nil, resp)
 	if err != nil {
@@ -1349,12 +1463,102 @@ func UpdateTransactionToTransfer(existingTxn *Transaction, targetAccount *Accoun
 }
 
 // Error represents an error from the YNAB API or this client library.
+//
+// Errors returned by the API have StatusCode, ID, Name and Detail set from the
+// response body, for example ID "404.2", Name "resource_not_found". Use
+// errors.Is with ErrNotFound, ErrRateLimited, etc. to branch on the kind of
+// error. Errors generated by this library only have Message set.
 type Error struct {
 	Message string
+
+	StatusCode int    // The HTTP status code of the response
+	ID         string // The YNAB error id, e.g. "404.2"
+	Name       string // The YNAB error name, e.g. "resource_not_found"
+	Detail     string // A human readable description of the error
+	// RetryAfter is the delay requested by the server's Retry-After header,
+	// usually set on 429 responses. Zero if the header was not present.
+	RetryAfter time.Duration
 }
 
 func (e *Error) Error() string {
-	return e.Message
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.ID == "" {
+		return fmt.Sprintf("ynab: unexpected HTTP status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ynab: %s (%s): %s", e.Name, e.ID, e.Detail)
+}
+
+// Is reports whether e matches target. It lets callers check API errors
+// against the sentinel values below with errors.Is.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// Sentinel errors for the error ids documented at
+// https://api.ynab.com/#errors. Compare against them with errors.Is; the
+// concrete error is always an *Error.
+var (
+	ErrBadRequest   = errors.New("ynab: bad request")           // 400
+	ErrUnauthorized = errors.New("ynab: not authorized")        // 401
+	ErrForbidden    = errors.New("ynab: forbidden")             // 403.x, e.g. subscription lapsed
+	ErrNotFound     = errors.New("ynab: not found")             // 404.1, 404.2
+	ErrConflict     = errors.New("ynab: conflict")              // 409
+	ErrRateLimited  = errors.New("ynab: too many requests")     // 429
+	ErrServer       = errors.New("ynab: internal server error") // 500, 503
+)
+
+// ErrorDetail is the error object in an API error response.
+type ErrorDetail struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+// ErrorResponse is the body of a non-2xx API response.
+type ErrorResponse struct {
+	Error *ErrorDetail `json:"error"`
+}
+
+// parseError converts a non-2xx response into an *Error. It is installed as
+// the restclient ErrorParser, so every request made through Client returns
+// it.
+func parseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	errResp := new(ErrorResponse)
+	if err := json.Unmarshal(body, errResp); err != nil || errResp.Error == nil {
+		// Not a YNAB error body, e.g. an HTML page from a proxy.
+		return e
+	}
+	e.ID = errResp.Error.ID
+	e.Name = errResp.Error.Name
+	e.Detail = errResp.Error.Detail
+	return e
 }
 
 func (c *Client) MakeRequest(ctx context.Context, method string, pathPart string, data url.Values, reqBody any, v any) error {
@@ -1409,9 +1613,26 @@ func (c *Client) SetUserAgent(userAgent string) {
 	c.userAgent = userAgent
 }
 
-func NewClient(token string) *Client {
-	client := restclient.NewBearerClient(token, "https://api.ynab.com/v1")
+// DefaultBaseURL is the base URL of the YNAB API.
+const DefaultBaseURL = "https://api.ynab.com/v1"
+
+// NewClient returns a Client that authenticates with a personal access token.
+// Options can change the base URL and HTTP client and add middleware,
+// instrumentation or a cache. If token is empty, requests are sent without an
+// Authorization header, for middleware that adds its own.
+func NewClient(token string, opts ...ClientOption) *Client {
+	client := restclient.New("", "", DefaultBaseURL)
+	if token != "" {
+		client = restclient.NewBearerClient(token, DefaultBaseURL)
+	}
+	client.ErrorParser = parseError
 	c := &Client{Client: client}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.middleware) > 0 || c.instrumentation != nil || c.cache != nil {
+		c.SetTransport(c.transport())
+	}
 	c.Plans = func(id string) *PlanService {
 		return &PlanService{
 			client: c,
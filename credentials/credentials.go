@@ -0,0 +1,254 @@
+// Package credentials finds the YNAB access token for command line tools.
+//
+// The token comes from the first of these that is set:
+//
+//   - the profile named by --profile or $YNAB_PROFILE, in the config file
+//   - the $YNAB_TOKEN environment variable
+//   - the config file's default profile
+//
+// The config file is ~/.config/ynab/config.toml (or
+// $XDG_CONFIG_HOME/ynab/config.toml), with a table for each profile:
+//
+//	default_profile = "personal"
+//
+//	[profiles.personal]
+//	token_command = "pass show ynab"
+//	plan = "Personal"
+//
+//	[profiles.work]
+//	token = "..."
+//
+// A profile sets either token, or token_command, a shell command that prints
+// the token, so it doesn't have to be stored in plain text. plan optionally
+// names the plan the tools use when one isn't given on the command line.
+//
+// Load finds the token, and Credentials.Check makes sure YNAB accepts it:
+//
+//	creds, err := credentials.Load(ctx, *profile)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := ynab.NewClient(creds.Token)
+//	if err := creds.Check(ctx, client); err != nil {
+//		log.Fatal(err)
+//	}
+package credentials
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/kevinburke/ynab-go"
+)
+
+// TokenEnv is the environment variable the token is read from by default.
+const TokenEnv = "YNAB_TOKEN"
+
+// ProfileEnv is the environment variable that names the profile to use if
+// Loader.Profile is empty.
+const ProfileEnv = "YNAB_PROFILE"
+
+// settingsURL is where users create personal access tokens.
+const settingsURL = "https://app.youneedabudget.com/settings"
+
+// Credentials are the token a tool should use and where it came from.
+type Credentials struct {
+	Token string
+	// Profile is the name of the profile the token came from, or empty if it
+	// came from the environment.
+	Profile string
+	// Plan is the profile's plan setting, if any.
+	Plan string
+	// Source describes where the token came from, for error messages, e.g.
+	// "$YNAB_TOKEN" or `profile "work" in /home/me/.config/ynab/config.toml`.
+	Source string
+}
+
+// A Profile is a [profiles.<name>] table in the config file.
+type Profile struct {
+	Token        string `toml:"token"`
+	TokenCommand string `toml:"token_command"`
+	Plan         string `toml:"plan"`
+}
+
+// Config is the contents of the config file.
+type Config struct {
+	// DefaultProfile is the profile to use if none is named and $YNAB_TOKEN
+	// is not set. If empty, the profile named "default" is used, if there is
+	// one.
+	DefaultProfile string              `toml:"default_profile"`
+	Profiles       map[string]*Profile `toml:"profiles"`
+}
+
+// ParseConfig parses the contents of a config file. Settings it doesn't know
+// are an error, so typos like token-command aren't ignored.
+func ParseConfig(data []byte) (*Config, error) {
+	c := new(Config)
+	md, err := toml.Decode(string(data), c)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		key := undecoded[0]
+		if len(key) > 1 && key[0] != "profiles" {
+			return nil, fmt.Errorf("unknown table [%s], profiles go in [profiles.<name>]", key[0])
+		}
+		return nil, fmt.Errorf("unknown setting %q", key.String())
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		if p := c.Profiles[name]; p.Token != "" && p.TokenCommand != "" {
+			return nil, fmt.Errorf("profile %q sets both token and token_command", name)
+		}
+	}
+	return c, nil
+}
+
+// LoadConfig reads the config file at path. If the file doesn't exist, the
+// error wraps fs.ErrNotExist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// DefaultPath returns the path of the config file,
+// $XDG_CONFIG_HOME/ynab/config.toml or ~/.config/ynab/config.toml.
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "ynab", "config.toml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "ynab", "config.toml"), nil
+}
+
+// A Loader finds credentials. The zero value is ready to use and follows the
+// rules in the package documentation.
+type Loader struct {
+	// Profile is the profile to use. If empty, $YNAB_PROFILE is used.
+	Profile string
+	// Path is the config file. If empty, DefaultPath is used.
+	Path string
+	// Env is the environment variable to read the token from. If empty,
+	// TokenEnv is used.
+	Env string
+}
+
+// Load finds credentials with a zero Loader, using profile if it is not empty.
+func Load(ctx context.Context, profile string) (*Credentials, error) {
+	l := &Loader{Profile: profile}
+	return l.Load(ctx)
+}
+
+// Load returns the credentials to use. If a profile is named, it must exist.
+func (l *Loader) Load(ctx context.Context) (*Credentials, error) {
+	env := cmp.Or(l.Env, TokenEnv)
+	profile := cmp.Or(l.Profile, os.Getenv(ProfileEnv))
+	if profile == "" {
+		if token := os.Getenv(env); token != "" {
+			return &Credentials{Token: token, Source: "$" + env}, nil
+		}
+	}
+	path := l.Path
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	conf, err := LoadConfig(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if profile != "" {
+			return nil, fmt.Errorf("can't use profile %q: %s does not exist", profile, path)
+		}
+		return nil, fmt.Errorf("please set %s in the environment, or add a profile to %s: %s", env, path, settingsURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	explicit := profile != ""
+	if !explicit {
+		profile = cmp.Or(conf.DefaultProfile, "default")
+	}
+	p, ok := conf.Profiles[profile]
+	if !ok {
+		if explicit || conf.DefaultProfile != "" {
+			return nil, fmt.Errorf("%s has no profile %q", path, profile)
+		}
+		return nil, fmt.Errorf("please set %s in the environment, or set default_profile in %s", env, path)
+	}
+	creds := &Credentials{
+		Profile: profile,
+		Plan:    p.Plan,
+		Source:  fmt.Sprintf("profile %q in %s", profile, path),
+	}
+	switch {
+	case p.Token != "":
+		creds.Token = p.Token
+	case p.TokenCommand != "":
+		if creds.Token, err = runTokenCommand(ctx, p.TokenCommand); err != nil {
+			return nil, fmt.Errorf("token_command of profile %q: %w", profile, err)
+		}
+		creds.Source = fmt.Sprintf("the token_command of profile %q in %s", profile, path)
+	default:
+		return nil, fmt.Errorf("profile %q in %s has no token or token_command", profile, path)
+	}
+	return creds, nil
+}
+
+// runTokenCommand runs command with the shell and returns the first line it
+// prints. The command can prompt on the terminal, e.g. for a passphrase.
+func runTokenCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	// Tools like pass print the secret on the first line and other data
+	// after it.
+	token, _, _ := bytes.Cut(out, []byte("\n"))
+	if t := strings.TrimSpace(string(token)); t != "" {
+		return t, nil
+	}
+	return "", errors.New("command printed no token")
+}
+
+// Check makes sure YNAB accepts the token by fetching the current user, so a
+// missing or revoked token is reported clearly before any real work is done.
+func (c *Credentials) Check(ctx context.Context, client *ynab.Client) error {
+	_, err := client.GetUser(ctx)
+	if errors.Is(err, ynab.ErrUnauthorized) {
+		return fmt.Errorf("YNAB rejected the access token from %s, it may have been revoked or mistyped; create a new one at %s: %w", c.Source, settingsURL, err)
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/ynabtest"
+)
+
+const testConfig = `# YNAB tools
+default_profile = "personal"
+
+[profiles.personal]
+token = "personal-token" # inline comment
+plan = 'My "Plan"'
+
+[profiles."work stuff"]
+token_command = "echo work-token; echo ignored"
+
+[profiles]
+default.token = "default-token"
+`
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DefaultProfile != "personal" {
+		t.Errorf("DefaultProfile = %q", c.DefaultProfile)
+	}
+	if p := c.Profiles["personal"]; p == nil || p.Token != "personal-token" || p.Plan != `My "Plan"` {
+		t.Errorf("personal = %+v", p)
+	}
+	if p := c.Profiles["work stuff"]; p == nil || p.TokenCommand != "echo work-token; echo ignored" {
+		t.Errorf("work stuff = %+v", p)
+	}
+	if p := c.Profiles["default"]; p == nil || p.Token != "default-token" {
+		t.Errorf("default = %+v", p)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"[profiles.a]\ntoken-command = \"x\"", `unknown setting "profiles.a.token-command"`},
+		{"[profile.a]\ntoken = \"x\"", "unknown table [profile]"},
+		{"[profiles.a]\ntoken = \"x\"\ntoken_command = \"y\"", `profile "a" sets both token and token_command`},
+		{`budget = "Personal"`, `unknown setting "budget"`},
+		{`default_profile = 3`, "default_profile"},
+	}
+	for _, tt := range tests {
+		_, err := ParseConfig([]byte(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseConfig(%q): got error %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token_command uses echo")
+	}
+	path := writeConfig(t, testConfig)
+	ctx := context.Background()
+	tests := []struct {
+		name             string
+		profile, env     string
+		profileEnv       string
+		wantToken        string
+		wantProfile      string
+		wantSourcePrefix string
+	}{
+		{name: "env", env: "env-token", wantToken: "env-token", wantSourcePrefix: "$YNAB_TOKEN"},
+		{name: "profile beats env", profile: "work stuff", env: "env-token", wantToken: "work-token", wantProfile: "work stuff", wantSourcePrefix: "the token_command"},
+		{name: "profile env", profileEnv: "default", env: "env-token", wantToken: "default-token", wantProfile: "default", wantSourcePrefix: `profile "default"`},
+		{name: "default profile", wantToken: "personal-token", wantProfile: "personal", wantSourcePrefix: `profile "personal"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TokenEnv, tt.env)
+			t.Setenv(ProfileEnv, tt.profileEnv)
+			l := &Loader{Profile: tt.profile, Path: path}
+			creds, err := l.Load(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds.Token != tt.wantToken || creds.Profile != tt.wantProfile || !strings.HasPrefix(creds.Source, tt.wantSourcePrefix) {
+				t.Errorf("Load: got %+v", creds)
+			}
+		})
+	}
+
+	t.Setenv(TokenEnv, "")
+	t.Setenv(ProfileEnv, "")
+	l := &Loader{Profile: "missing", Path: path}
+	if _, err := l.Load(ctx); err == nil || !strings.Contains(err.Error(), `no profile "missing"`) {
+		t.Errorf("Load(missing): got error %v", err)
+	}
+	l = &Loader{Path: filepath.Join(t.TempDir(), "config.toml")}
+	if _, err := l.Load(ctx); err == nil || !strings.Contains(err.Error(), "please set YNAB_TOKEN") {
+		t.Errorf("Load without config: got error %v", err)
+	}
+	l = &Loader{Path: writeConfig(t, "[profiles.other]\ntoken = \"x\"\n")}
+	if _, err := l.Load(ctx); err == nil || !strings.Contains(err.Error(), "set default_profile") {
+		t.Errorf("Load without a default profile: got error %v", err)
+	}
+	l = &Loader{Profile: "bad", Path: writeConfig(t, "[profiles.bad]\ntoken_command = \"exit 3\"\n")}
+	if _, err := l.Load(ctx); err == nil || !strings.Contains(err.Error(), `token_command of profile "bad"`) {
+		t.Errorf("Load with a failing command: got error %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := ynabtest.NewServer()
+	defer s.Close()
+	ctx := context.Background()
+
+	creds := &Credentials{Token: ynabtest.DefaultToken, Source: "$YNAB_TOKEN"}
+	if err := creds.Check(ctx, ynab.NewClient(creds.Token, ynab.WithBaseURL(s.URL))); err != nil {
+		t.Fatal(err)
+	}
+	creds.Token = "revoked"
+	err := creds.Check(ctx, ynab.NewClient(creds.Token, ynab.WithBaseURL(s.URL)))
+	if !errors.Is(err, ynab.ErrUnauthorized) {
+		t.Fatalf("Check: got %v, want ErrUnauthorized", err)
+	}
+	if !strings.Contains(err.Error(), "access token from $YNAB_TOKEN") {
+		t.Errorf("Check: error %q doesn't say where the token came from", err)
+	}
+}
@@ -0,0 +1,75 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIErrorParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		sentinel error
+		id       string
+		errName  string
+	}{
+		{"NotFound", 404, `{"error": {"id": "404.2", "name": "resource_not_found", "detail": "Resource not found"}}`, ErrNotFound, "404.2", "resource_not_found"},
+		{"Conflict", 409, `{"error": {"id": "409", "name": "conflict", "detail": "A transaction with the same import_id already exists"}}`, ErrConflict, "409", "conflict"},
+		{"RateLimited", 429, `{"error": {"id": "429", "name": "too_many_requests", "detail": "Too many requests"}}`, ErrRateLimited, "429", "too_many_requests"},
+		{"Unauthorized", 401, `{"error": {"id": "401", "name": "unauthorized", "detail": "Unauthorized"}}`, ErrUnauthorized, "401", "unauthorized"},
+		{"Forbidden", 403, `{"error": {"id": "403.1", "name": "subscription_lapsed", "detail": "Subscription lapsed"}}`, ErrForbidden, "403.1", "subscription_lapsed"},
+		{"ServerHTML", 503, `<html>Service Unavailable</html>`, ErrServer, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient("test-token")
+			client.Base = server.URL
+
+			_, err := client.Plans("plan-id").GetTransaction(context.Background(), "txn-id")
+			if err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("expected errors.Is(err, %v) to be true, got err %v", tt.sentinel, err)
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *Error, got %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if apiErr.ID != tt.id {
+				t.Errorf("expected id %q, got %q", tt.id, apiErr.ID)
+			}
+			if apiErr.Name != tt.errName {
+				t.Errorf("expected name %q, got %q", tt.errName, apiErr.Name)
+			}
+		})
+	}
+}
+
+func TestAPIErrorIsDoesNotMatchOtherKinds(t *testing.T) {
+	err := error(&Error{StatusCode: 404, ID: "404.2", Name: "resource_not_found", Detail: "Resource not found"})
+	if errors.Is(err, ErrRateLimited) {
+		t.Error("404 should not match ErrRateLimited")
+	}
+	if want := "ynab: resource_not_found (404.2): Resource not found"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	clientErr := &Error{Message: "target account does not have a valid transfer_payee_id"}
+	if errors.Is(clientErr, ErrNotFound) {
+		t.Error("client-side error should not match ErrNotFound")
+	}
+}
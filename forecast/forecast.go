@@ -0,0 +1,230 @@
+// Package forecast projects the balances of the cash accounts in a YNAB plan
+// forward in time, from their current balances and the scheduled
+// transactions that will be entered into them.
+//
+// Compute returns the balance of each on-budget cash account, like checking
+// and savings, and their total at the end of each day, and the first day
+// each account drops below a floor:
+//
+//	f, err := forecast.Compute(accounts, scheduled, &forecast.Options{Days: 60})
+//	if err != nil {
+//		return err
+//	}
+//	if f.Low != nil {
+//		fmt.Println(f.Low.AccountName, "goes below the floor on", f.Low.Date)
+//	}
+//
+// Like the ageofmoney package, Compute doesn't change its arguments or make
+// any requests.
+package forecast
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// DefaultDays is the number of days forecast if Options.Days is zero.
+const DefaultDays = 30
+
+// Options change how Compute works. A nil *Options uses the defaults.
+type Options struct {
+	// Start is the first day forecast. If zero, it's today.
+	Start time.Time
+	// Days is the number of days forecast, starting with Start. If zero,
+	// DefaultDays is used.
+	Days int
+	// Floor is the balance, in milliunits, an account is flagged for going
+	// below. If zero, accounts are flagged when they go negative.
+	Floor int64
+}
+
+// An Account is an account whose balance is forecast.
+type Account struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Balance is the current balance of the account.
+	Balance int64 `json:"balance"`
+	// Low is the first day the balance of the account is below the floor,
+	// or nil if it isn't.
+	Low *Low `json:"low,omitempty"`
+}
+
+// A Low is a day an account's balance is below the floor.
+type Low struct {
+	Date        ynab.Date `json:"date"`
+	AccountID   string    `json:"account_id"`
+	AccountName string    `json:"account_name"`
+	Balance     int64     `json:"balance"`
+}
+
+// A Day is the forecast balance of each account at the end of a day.
+type Day struct {
+	Date ynab.Date `json:"date"`
+	// Balances are the balances of Forecast.Accounts, in the same order.
+	Balances []int64 `json:"balances"`
+	Total    int64   `json:"total"`
+	// Transactions are the scheduled transactions that change the balances
+	// on this day.
+	Transactions []*Transaction `json:"transactions,omitempty"`
+}
+
+// A Transaction is an occurrence of a scheduled transaction in an account
+// whose balance is forecast. A transfer between two of them is a Transaction
+// in each.
+type Transaction struct {
+	ScheduledTransactionID string `json:"scheduled_transaction_id"`
+	AccountID              string `json:"account_id"`
+	AccountName            string `json:"account_name"`
+	PayeeName              string `json:"payee_name"`
+	// Amount is positive for money coming into the account and negative for
+	// money leaving it, in milliunits.
+	Amount int64 `json:"amount"`
+}
+
+// A Forecast is the result of Compute.
+type Forecast struct {
+	// Floor is the balance accounts are flagged for going below.
+	Floor int64 `json:"floor"`
+	// Accounts are the open, on-budget cash accounts, in the order of the
+	// accounts passed to Compute.
+	Accounts []*Account `json:"accounts"`
+	Days     []*Day     `json:"days"`
+	// Low is the first day any account is below the floor, or nil if none
+	// is. If several accounts first go below it on the same day, it's the
+	// first of them in Accounts.
+	Low *Low `json:"low,omitempty"`
+}
+
+// Compute forecasts the balances of the open, on-budget cash accounts in
+// accounts, starting from their current Balance and applying every
+// occurrence of the scheduled transactions. Transfers from other accounts,
+// like credit card payments from checking, are applied to the side that's a
+// cash account; spending on a credit card doesn't change any balance until
+// the card is paid.
+//
+// Scheduled transactions that are overdue, which can happen with plan data
+// saved a while ago, are applied on the first day. Compute returns an error if
+// the frequency of a scheduled transaction isn't known.
+func Compute(accounts []*ynab.Account, scheduled []*ynab.ScheduledTransaction, opts *Options) (*Forecast, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	start := opts.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	y, m, d := start.Date()
+	start = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	days := opts.Days
+	if days == 0 {
+		days = DefaultDays
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("forecast: can't forecast %d days", days)
+	}
+	end := start.AddDate(0, 0, days-1)
+
+	f := &Forecast{Floor: opts.Floor, Accounts: []*Account{}, Days: make([]*Day, days)}
+	index := make(map[string]int)
+	for _, acct := range accounts {
+		if acct.Deleted || acct.Closed || !acct.OnBudget || !acct.CashBacked() {
+			continue
+		}
+		index[acct.ID] = len(f.Accounts)
+		f.Accounts = append(f.Accounts, &Account{ID: acct.ID, Name: acct.Name, Balance: acct.Balance})
+	}
+	byDate := make(map[string]*Day, days)
+	for i := range f.Days {
+		f.Days[i] = &Day{Date: ynab.Date(start.AddDate(0, 0, i))}
+		byDate[f.Days[i].Date.String()] = f.Days[i]
+	}
+
+	for _, st := range scheduled {
+		if st.Deleted {
+			continue
+		}
+		txns := transactions(st, f.Accounts, index)
+		if len(txns) == 0 {
+			continue
+		}
+		if !st.Frequency.Valid() {
+			return nil, fmt.Errorf("forecast: scheduled transaction %s has unknown frequency %q", st.ID, st.Frequency)
+		}
+		for o := range st.Occurrences(time.Time{}, end) {
+			day := f.Days[0]
+			if time.Time(o.Date).After(start) {
+				day = byDate[o.Date.String()]
+			}
+			day.Transactions = append(day.Transactions, txns...)
+		}
+	}
+
+	balances := make([]int64, len(f.Accounts))
+	for i, acct := range f.Accounts {
+		balances[i] = acct.Balance
+	}
+	for _, day := range f.Days {
+		for _, t := range day.Transactions {
+			balances[index[t.AccountID]] += t.Amount
+		}
+		day.Balances = append([]int64(nil), balances...)
+		for i, b := range balances {
+			day.Total += b
+			acct := f.Accounts[i]
+			if b >= opts.Floor || acct.Low != nil {
+				continue
+			}
+			acct.Low = &Low{Date: day.Date, AccountID: acct.ID, AccountName: acct.Name, Balance: b}
+			if f.Low == nil {
+				f.Low = acct.Low
+			}
+		}
+	}
+	return f, nil
+}
+
+// Lows returns the first day each account is below the floor, earliest
+// first.
+func (f *Forecast) Lows() []*Low {
+	var lows []*Low
+	for _, acct := range f.Accounts {
+		if acct.Low != nil {
+			lows = append(lows, acct.Low)
+		}
+	}
+	slices.SortStableFunc(lows, func(x, y *Low) int { return time.Time(x.Date).Compare(time.Time(y.Date)) })
+	return lows
+}
+
+// transactions returns the changes an occurrence of st makes to the balances
+// of accounts, whose indexes are in index.
+func transactions(st *ynab.ScheduledTransaction, accounts []*Account, index map[string]int) []*Transaction {
+	var txns []*Transaction
+	add := func(accountID string, amount int64) {
+		i, ok := index[accountID]
+		if !ok || amount == 0 {
+			return
+		}
+		txns = append(txns, &Transaction{
+			ScheduledTransactionID: st.ID,
+			AccountID:              accountID,
+			AccountName:            accounts[i].Name,
+			PayeeName:              st.PayeeName,
+			Amount:                 amount,
+		})
+	}
+	add(st.AccountID, st.Amount)
+	// The API only returns one side of a scheduled transfer.
+	if st.TransferAccountID.Valid {
+		add(st.TransferAccountID.String, -st.Amount)
+	}
+	for _, sub := range st.Subtransactions {
+		if !sub.Deleted && sub.TransferAccountID.Valid {
+			add(sub.TransferAccountID.String, -sub.Amount)
+		}
+	}
+	return txns
+}
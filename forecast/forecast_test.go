@@ -0,0 +1,145 @@
+package forecast
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+func date(s string) ynab.Date {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return ynab.Date(t)
+}
+
+func transfer(id string) types.NullString {
+	return types.NullString{Valid: true, String: id}
+}
+
+var testAccounts = []*ynab.Account{
+	{ID: "checking", Name: "Checking", Type: "checking", OnBudget: true, Balance: 500000},
+	{ID: "savings", Name: "Savings", Type: "savings", OnBudget: true, Balance: 1000000},
+	{ID: "visa", Name: "Visa", Type: "creditCard", OnBudget: true, Balance: -200000},
+	{ID: "brokerage", Name: "Brokerage", Type: "otherAsset", Balance: 9000000},
+	{ID: "old", Name: "Old Checking", Type: "checking", OnBudget: true, Closed: true},
+}
+
+func TestCompute(t *testing.T) {
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "pay", AccountID: "checking", DateNext: date("2026-11-06"), Frequency: ynab.FrequencyEveryOtherWeek, Amount: 300000, PayeeName: "Employer"},
+		{ID: "rent", AccountID: "checking", DateNext: date("2026-11-01"), Frequency: ynab.FrequencyMonthly, Amount: -700000, PayeeName: "Landlord"},
+		// the card payment is entered on the card, so checking is the
+		// other side
+		{ID: "card", AccountID: "visa", DateNext: date("2026-11-03"), Frequency: ynab.FrequencyMonthly, Amount: 200000, PayeeName: "Transfer : Checking", TransferAccountID: transfer("checking")},
+		{ID: "save", AccountID: "checking", DateNext: date("2026-11-10"), Frequency: ynab.FrequencyNever, Amount: -50000, PayeeName: "Transfer : Savings", TransferAccountID: transfer("savings")},
+		// card spending doesn't change the balance of a cash account
+		{ID: "gym", AccountID: "visa", DateNext: date("2026-11-02"), Frequency: ynab.FrequencyMonthly, Amount: -40000, PayeeName: "Gym"},
+		{ID: "split", AccountID: "checking", DateNext: date("2026-11-12"), Frequency: ynab.FrequencyNever, Amount: -30000, PayeeName: "Split", Subtransactions: []ynab.Transaction{
+			{Amount: -10000},
+			{Amount: -20000, TransferAccountID: transfer("savings")},
+		}},
+		{ID: "deleted", AccountID: "checking", DateNext: date("2026-11-02"), Frequency: ynab.FrequencyNever, Amount: -99999999, Deleted: true},
+		// overdue, so it's applied on the first day
+		{ID: "late", AccountID: "savings", DateNext: date("2026-10-30"), Frequency: ynab.FrequencyNever, Amount: -1000, PayeeName: "Bank"},
+	}
+	f, err := Compute(testAccounts, scheduled, &Options{Start: time.Time(date("2026-11-01")), Days: 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, acct := range f.Accounts {
+		names = append(names, acct.Name)
+	}
+	if got := strings.Join(names, ","); got != "Checking,Savings" {
+		t.Fatalf("got accounts %s", got)
+	}
+	if len(f.Days) != 14 {
+		t.Fatalf("got %d days, want 14", len(f.Days))
+	}
+	want := map[string][2]int64{
+		"2026-11-01": {-200000, 999000},
+		"2026-11-02": {-200000, 999000},
+		"2026-11-03": {-400000, 999000},
+		"2026-11-06": {-100000, 999000},
+		"2026-11-10": {-150000, 1049000},
+		"2026-11-12": {-180000, 1069000},
+		"2026-11-14": {-180000, 1069000},
+	}
+	for _, day := range f.Days {
+		w, ok := want[day.Date.String()]
+		if !ok {
+			continue
+		}
+		if got := [2]int64{day.Balances[0], day.Balances[1]}; got != w {
+			t.Errorf("%s: got balances %v, want %v", day.Date, got, w)
+		}
+		if day.Total != w[0]+w[1] {
+			t.Errorf("%s: got total %d, want %d", day.Date, day.Total, w[0]+w[1])
+		}
+	}
+	if n := len(f.Days[0].Transactions); n != 2 {
+		t.Errorf("got %d transactions on the first day, want 2", n)
+	}
+	if f.Low == nil || f.Low.AccountID != "checking" || f.Low.Date.String() != "2026-11-01" || f.Low.Balance != -200000 {
+		t.Errorf("got low %+v, want checking on 2026-11-01", f.Low)
+	}
+	if f.Accounts[1].Low != nil {
+		t.Errorf("savings shouldn't be low, got %+v", f.Accounts[1].Low)
+	}
+}
+
+func TestComputeFloor(t *testing.T) {
+	accounts := []*ynab.Account{
+		{ID: "checking", Name: "Checking", Type: "checking", OnBudget: true, Balance: 300000},
+		{ID: "savings", Name: "Savings", Type: "savings", OnBudget: true, Balance: 300000},
+	}
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "groceries", AccountID: "checking", DateNext: date("2026-11-02"), Frequency: ynab.FrequencyWeekly, Amount: -100000},
+		{ID: "insurance", AccountID: "savings", DateNext: date("2026-11-09"), Frequency: ynab.FrequencyNever, Amount: -250000},
+	}
+	f, err := Compute(accounts, scheduled, &Options{Start: time.Time(date("2026-11-01")), Days: 30, Floor: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// checking is at 200 on the 2nd and 100 on the 9th, which isn't below
+	// the floor, and 0 on the 16th.
+	if low := f.Accounts[0].Low; low == nil || low.Date.String() != "2026-11-16" || low.Balance != 0 {
+		t.Errorf("got checking low %+v, want 2026-11-16", low)
+	}
+	if low := f.Accounts[1].Low; low == nil || low.Date.String() != "2026-11-09" || low.Balance != 50000 {
+		t.Errorf("got savings low %+v, want 2026-11-09", low)
+	}
+	if f.Low != f.Accounts[1].Low {
+		t.Errorf("got low %+v, want the savings low", f.Low)
+	}
+	if lows := f.Lows(); len(lows) != 2 || lows[0] != f.Accounts[1].Low || lows[1] != f.Accounts[0].Low {
+		t.Errorf("got lows %+v, want savings then checking", lows)
+	}
+	if f.Floor != 100000 {
+		t.Errorf("got floor %d", f.Floor)
+	}
+}
+
+func TestComputeErrors(t *testing.T) {
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "bad", AccountID: "checking", DateNext: date("2026-11-02"), Frequency: "fortnightly", Amount: -1000},
+	}
+	if _, err := Compute(testAccounts, scheduled, nil); err == nil || !strings.Contains(err.Error(), `unknown frequency "fortnightly"`) {
+		t.Errorf("got error %v, want unknown frequency", err)
+	}
+	if _, err := Compute(testAccounts, nil, &Options{Days: -1}); err == nil {
+		t.Error("expected an error for a negative number of days")
+	}
+	f, err := Compute(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Days) != DefaultDays || len(f.Accounts) != 0 || f.Low != nil {
+		t.Errorf("got %d days, %d accounts and low %v", len(f.Days), len(f.Accounts), f.Low)
+	}
+}
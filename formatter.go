@@ -0,0 +1,121 @@
+package ynab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultPlanSettings are the settings of a new US plan. They are used when a
+// plan does not report a currency or date format.
+var DefaultPlanSettings = PlanSettings{
+	DateFormat: DateFormat{Format: "MM/DD/YYYY"},
+	CurrencyFormat: CurrencyFormat{
+		ISOCode:          "USD",
+		ExampleFormat:    "123,456.78",
+		DecimalDigits:    2,
+		DecimalSeparator: ".",
+		SymbolFirst:      true,
+		GroupSeparator:   ",",
+		CurrencySymbol:   "$",
+		DisplaySymbol:    true,
+	},
+}
+
+// A Formatter renders amounts and dates the way the YNAB app does for a given
+// plan, e.g. "-$1,234.56" and "01/31/2024" for a US plan, or "1.234,56€" and
+// "31.01.2024" for a German one.
+type Formatter struct {
+	CurrencyFormat CurrencyFormat
+	DateFormat     DateFormat
+}
+
+// NewFormatter returns a Formatter for a plan with the given settings.
+func NewFormatter(settings PlanSettings) *Formatter {
+	return &Formatter{CurrencyFormat: settings.CurrencyFormat, DateFormat: settings.DateFormat}
+}
+
+// Formatter returns a Formatter for the plan's currency and date format.
+// Formats that the plan does not report are taken from DefaultPlanSettings.
+func (p *Plan) Formatter() *Formatter {
+	f := NewFormatter(DefaultPlanSettings)
+	if p.CurrencyFormat != nil {
+		f.CurrencyFormat = *p.CurrencyFormat
+	}
+	if p.DateFormat != nil {
+		f.DateFormat = *p.DateFormat
+	}
+	return f
+}
+
+// Formatter fetches the plan's settings and returns a Formatter for them.
+func (b *PlanService) Formatter(ctx context.Context) (*Formatter, error) {
+	resp, err := b.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewFormatter(resp.Data.Settings), nil
+}
+
+// Amount formats m with the plan's currency symbol, separators and number of
+// decimal digits.
+func (f *Formatter) Amount(m Milliunits) string {
+	return m.Format(f.CurrencyFormat)
+}
+
+// AmountWithCode formats m without the currency symbol and followed by the
+// currency's ISO code, e.g. "1.234,56 EUR". This is unambiguous when a report
+// mixes plans in different currencies.
+func (f *Formatter) AmountWithCode(m Milliunits) string {
+	cf := f.CurrencyFormat
+	cf.DisplaySymbol = false
+	s := m.Format(cf)
+	if cf.ISOCode == "" {
+		return s
+	}
+	return s + " " + cf.ISOCode
+}
+
+// ParseAmount parses an amount formatted for the plan, e.g. "$1,234.56". See
+// ParseMilliunits.
+func (f *Formatter) ParseAmount(s string) (Milliunits, error) {
+	return ParseMilliunits(s, f.CurrencyFormat)
+}
+
+// Date formats d in the plan's date format.
+func (f *Formatter) Date(d Date) string {
+	return f.DateFormat.FormatTime(time.Time(d))
+}
+
+// FormatTime formats t using the YNAB date format string df.Format, e.g.
+// "DD.MM.YYYY". The tokens YYYY, YY, MM and DD are replaced with the year,
+// the two digit year, the month and the day; any other characters are copied
+// as is. An empty format uses ISO 8601, "YYYY-MM-DD".
+func (df DateFormat) FormatTime(t time.Time) string {
+	format := df.Format
+	if format == "" {
+		format = "YYYY-MM-DD"
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		switch {
+		case strings.HasPrefix(format[i:], "YYYY"):
+			fmt.Fprintf(&b, "%04d", t.Year())
+			i += 4
+		case strings.HasPrefix(format[i:], "YY"):
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+			i += 2
+		case strings.HasPrefix(format[i:], "MM"):
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+			i += 2
+		case strings.HasPrefix(format[i:], "DD"):
+			fmt.Fprintf(&b, "%02d", t.Day())
+			i += 2
+		default:
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package ynab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFormatterFromSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plans/plan-id/settings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data": {"settings": {"date_format": {"format": "DD.MM.YYYY"}, "currency_format": {"iso_code": "EUR", "example_format": "123.456,78", "decimal_digits": 2, "decimal_separator": ",", "symbol_first": false, "group_separator": ".", "currency_symbol": "€", "display_symbol": true}}}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+
+	f, err := client.Plans("plan-id").Formatter(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Amount(-1234560); got != "-1.234,56€" {
+		t.Errorf("Amount = %q", got)
+	}
+	if got := f.AmountWithCode(1234560); got != "1.234,56 EUR" {
+		t.Errorf("AmountWithCode = %q", got)
+	}
+	if got := f.Date(Date(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))); got != "31.01.2024" {
+		t.Errorf("Date = %q", got)
+	}
+	amount, err := f.ParseAmount("1.234,56€")
+	if err != nil || amount != 1234560 {
+		t.Errorf("ParseAmount = %d, %v", amount, err)
+	}
+}
+
+func TestDateFormatFormatTime(t *testing.T) {
+	d := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"MM/DD/YYYY", "03/05/2024"},
+		{"DD/MM/YYYY", "05/03/2024"},
+		{"YYYY-MM-DD", "2024-03-05"},
+		{"YYYY/MM/DD", "2024/03/05"},
+		{"DD.MM.YYYY", "05.03.2024"},
+		{"DD-MM-YY", "05-03-24"},
+		{"", "2024-03-05"},
+	}
+	for _, tt := range tests {
+		if got := (DateFormat{Format: tt.format}).FormatTime(d); got != tt.want {
+			t.Errorf("FormatTime(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestPlanFormatter(t *testing.T) {
+	var plan Plan
+	err := json.Unmarshal([]byte(`{"id": "plan-id", "name": "Japan", "date_format": {"format": "YYYY/MM/DD"}, "currency_format": {"iso_code": "JPY", "decimal_digits": 0, "decimal_separator": ".", "symbol_first": true, "group_separator": ",", "currency_symbol": "¥", "display_symbol": true}}`), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := plan.Formatter()
+	if got := f.Amount(1234500); got != "¥1,235" {
+		t.Errorf("Amount = %q", got)
+	}
+	if got := f.Date(Date(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))); got != "2024/03/05" {
+		t.Errorf("Date = %q", got)
+	}
+
+	// A plan without formats falls back to DefaultPlanSettings.
+	f = (&Plan{ID: "plan-id"}).Formatter()
+	if got := f.Amount(-1234560); got != "-$1,234.56" {
+		t.Errorf("default Amount = %q", got)
+	}
+	if got := f.Date(Date(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))); got != "03/05/2024" {
+		t.Errorf("default Date = %q", got)
+	}
+}
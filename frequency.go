@@ -0,0 +1,181 @@
+package ynab
+
+import (
+	"iter"
+	"time"
+)
+
+// Frequency is how often a scheduled transaction recurs.
+type Frequency string
+
+const (
+	FrequencyNever           Frequency = "never"
+	FrequencyDaily           Frequency = "daily"
+	FrequencyWeekly          Frequency = "weekly"
+	FrequencyEveryOtherWeek  Frequency = "everyOtherWeek"
+	FrequencyTwiceAMonth     Frequency = "twiceAMonth"
+	FrequencyEvery4Weeks     Frequency = "every4Weeks"
+	FrequencyMonthly         Frequency = "monthly"
+	FrequencyEveryOtherMonth Frequency = "everyOtherMonth"
+	FrequencyEvery3Months    Frequency = "every3Months"
+	FrequencyEvery4Months    Frequency = "every4Months"
+	FrequencyTwiceAYear      Frequency = "twiceAYear"
+	FrequencyYearly          Frequency = "yearly"
+	FrequencyEveryOtherYear  Frequency = "everyOtherYear"
+)
+
+// Frequencies are the frequencies the API supports, most frequent first.
+var Frequencies = []Frequency{
+	FrequencyNever, FrequencyDaily, FrequencyWeekly, FrequencyEveryOtherWeek,
+	FrequencyTwiceAMonth, FrequencyEvery4Weeks, FrequencyMonthly,
+	FrequencyEveryOtherMonth, FrequencyEvery3Months, FrequencyEvery4Months,
+	FrequencyTwiceAYear, FrequencyYearly, FrequencyEveryOtherYear,
+}
+
+// Valid reports whether f is one of the frequencies the API supports.
+func (f Frequency) Valid() bool {
+	_, _, ok := f.step()
+	return ok
+}
+
+// step returns the number of days or months between occurrences of f. Both
+// are zero for never.
+func (f Frequency) step() (days, months int, ok bool) {
+	switch f {
+	case FrequencyNever:
+	case FrequencyDaily:
+		days = 1
+	case FrequencyWeekly:
+		days = 7
+	case FrequencyEveryOtherWeek:
+		days = 14
+	case FrequencyEvery4Weeks:
+		days = 28
+	case FrequencyTwiceAMonth, FrequencyMonthly:
+		months = 1
+	case FrequencyEveryOtherMonth:
+		months = 2
+	case FrequencyEvery3Months:
+		months = 3
+	case FrequencyEvery4Months:
+		months = 4
+	case FrequencyTwiceAYear:
+		months = 6
+	case FrequencyYearly:
+		months = 12
+	case FrequencyEveryOtherYear:
+		months = 24
+	default:
+		return 0, 0, false
+	}
+	return days, months, true
+}
+
+// Dates returns the dates a transaction recurring at f falls on, starting at
+// first, that are between from and to, inclusive. Frequencies of a month or
+// more keep the day of the month of first, and fall on the last day of months
+// that are too short, like YNAB: a monthly transaction on January 31 recurs on
+// February 28 (or 29) and March 31. twiceAMonth recurs on the day of first and
+// the day 15 days before or after it, e.g. the 10th and 25th, or the 15th and
+// the 30th, which is the last day of February.
+//
+// A zero from or to leaves that end open; stop ranging over the dates of a
+// recurring transaction to end them. A Frequency that isn't Valid recurs like
+// never. The dates have the time and location of first.
+func (f Frequency) Dates(first Date, from, to time.Time) iter.Seq[Date] {
+	return f.dates(time.Time(first), time.Time(first).Day(), from, to)
+}
+
+// dates is like Dates, with the occurrences of monthly frequencies on day,
+// which may be later than the day of first if first was moved to the end of a
+// short month.
+func (f Frequency) dates(first time.Time, day int, from, to time.Time) iter.Seq[Date] {
+	days, months, _ := f.step()
+	// Compare whole days, so a from or to in the middle of a day includes it.
+	from = startOfDay(from, first.Location())
+	end := func(d time.Time) bool { return false }
+	if !to.IsZero() {
+		to = startOfDay(to, first.Location()).AddDate(0, 0, 1)
+		end = func(d time.Time) bool { return !d.Before(to) }
+	}
+	inRange := func(d time.Time) bool {
+		return !d.Before(from) && !d.Before(first) && !end(d)
+	}
+	return func(yield func(Date) bool) {
+		if f == FrequencyTwiceAMonth {
+			lo, hi := day, day+15
+			if day > 15 {
+				lo, hi = day-15, day
+			}
+			for n := 0; ; n++ {
+				month := time.Date(first.Year(), first.Month()+time.Month(n), 1, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), first.Location())
+				if end(month) {
+					return
+				}
+				for _, day := range []int{lo, hi} {
+					d := month.AddDate(0, 0, min(day, daysIn(month))-1)
+					if inRange(d) && !yield(Date(d)) {
+						return
+					}
+				}
+			}
+		}
+		for n := 0; ; n++ {
+			d := first
+			switch {
+			case days > 0:
+				d = first.AddDate(0, 0, n*days)
+			case months > 0:
+				month := time.Date(first.Year(), first.Month()+time.Month(n*months), 1, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), first.Location())
+				d = month.AddDate(0, 0, min(day, daysIn(month))-1)
+			case n > 0:
+				return
+			}
+			if end(d) {
+				return
+			}
+			if inRange(d) && !yield(Date(d)) {
+				return
+			}
+		}
+	}
+}
+
+// An Occurrence is a date a scheduled transaction falls on.
+type Occurrence struct {
+	Date                 Date
+	ScheduledTransaction *ScheduledTransaction
+}
+
+// Occurrences returns the occurrences of st from its DateNext on that are
+// between from and to, inclusive, in date order. See Frequency.Dates for how
+// the dates are found. If DateNext was moved to the end of a short month,
+// later occurrences are on the day of DateFirst, like YNAB. Occurrences
+// doesn't check whether st is deleted.
+func (st *ScheduledTransaction) Occurrences(from, to time.Time) iter.Seq[Occurrence] {
+	next, first := time.Time(st.DateNext), time.Time(st.DateFirst)
+	day := next.Day()
+	if !first.IsZero() && first.Day() > day && day == daysIn(next) {
+		day = first.Day()
+	}
+	return func(yield func(Occurrence) bool) {
+		for d := range st.Frequency.dates(next, day, from, to) {
+			if !yield(Occurrence{Date: d, ScheduledTransaction: st}) {
+				return
+			}
+		}
+	}
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// daysIn returns the number of days in the month of t.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
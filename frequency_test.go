@@ -0,0 +1,113 @@
+package ynab
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDate(s string) Date {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return Date(t)
+}
+
+func TestFrequencyDates(t *testing.T) {
+	tests := []struct {
+		frequency       Frequency
+		first, from, to string
+		want            string
+	}{
+		{FrequencyMonthly, "2024-01-31", "", "2024-04-30", "2024-01-31 2024-02-29 2024-03-31 2024-04-30"},
+		{FrequencyMonthly, "2024-01-31", "2024-03-01", "2024-05-01", "2024-03-31 2024-04-30"},
+		{FrequencyWeekly, "2024-01-01", "", "2024-01-22", "2024-01-01 2024-01-08 2024-01-15 2024-01-22"},
+		{FrequencyEveryOtherWeek, "2024-01-01", "2024-01-10", "2024-02-01", "2024-01-15 2024-01-29"},
+		{FrequencyEvery4Weeks, "2024-01-01", "", "2024-03-01", "2024-01-01 2024-01-29 2024-02-26"},
+		{FrequencyDaily, "2024-02-28", "", "2024-03-01", "2024-02-28 2024-02-29 2024-03-01"},
+		{FrequencyNever, "2024-01-01", "", "2025-01-01", "2024-01-01"},
+		{FrequencyNever, "2024-01-01", "2024-01-02", "2025-01-01", ""},
+		{FrequencyEveryOtherMonth, "2023-12-31", "", "2024-06-30", "2023-12-31 2024-02-29 2024-04-30 2024-06-30"},
+		{FrequencyEvery3Months, "2024-01-15", "", "2024-12-31", "2024-01-15 2024-04-15 2024-07-15 2024-10-15"},
+		{FrequencyEvery4Months, "2024-01-15", "", "2024-12-31", "2024-01-15 2024-05-15 2024-09-15"},
+		{FrequencyTwiceAYear, "2024-08-31", "", "2025-09-01", "2024-08-31 2025-02-28 2025-08-31"},
+		{FrequencyYearly, "2024-02-29", "", "2028-03-01", "2024-02-29 2025-02-28 2026-02-28 2027-02-28 2028-02-29"},
+		{FrequencyEveryOtherYear, "2024-02-29", "", "2028-03-01", "2024-02-29 2026-02-28 2028-02-29"},
+		{FrequencyTwiceAMonth, "2024-01-15", "", "2024-02-29", "2024-01-15 2024-01-30 2024-02-15 2024-02-29"},
+		{FrequencyTwiceAMonth, "2024-01-20", "", "2024-02-29", "2024-01-20 2024-02-05 2024-02-20"},
+		{FrequencyTwiceAMonth, "2024-01-01", "2024-01-10", "2024-02-10", "2024-01-16 2024-02-01"},
+		{FrequencyMonthly, "2024-03-01", "", "2024-02-01", ""},
+		{"sometimes", "2024-01-01", "", "2024-03-01", "2024-01-01"},
+	}
+	for _, tt := range tests {
+		var from time.Time
+		if tt.from != "" {
+			from = time.Time(testDate(tt.from))
+		}
+		// A time during the last day includes it.
+		to := time.Time(testDate(tt.to)).Add(15 * time.Hour)
+		var got []string
+		for d := range tt.frequency.Dates(testDate(tt.first), from, to) {
+			got = append(got, d.String())
+		}
+		if g := strings.Join(got, " "); g != tt.want {
+			t.Errorf("%s.Dates(%s, %q, %s): got %s, want %s", tt.frequency, tt.first, tt.from, tt.to, g, tt.want)
+		}
+	}
+
+	// Without an end, the dates go on until the caller stops.
+	n := 0
+	for range FrequencyDaily.Dates(testDate("2024-01-01"), time.Time{}, time.Time{}) {
+		n++
+		if n == 1000 {
+			break
+		}
+	}
+	if n != 1000 {
+		t.Errorf("got %d dates", n)
+	}
+}
+
+func TestFrequencyValid(t *testing.T) {
+	for _, f := range Frequencies {
+		if !f.Valid() {
+			t.Errorf("%s is not valid", f)
+		}
+	}
+	if Frequency("fortnightly").Valid() || Frequency("").Valid() {
+		t.Error("expected unknown frequencies to be invalid")
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	st := new(ScheduledTransaction)
+	if err := json.Unmarshal([]byte(`{"id": "rent", "date_first": "2024-01-31", "date_next": "2024-02-29", "frequency": "monthly", "amount": -1500000}`), st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Frequency != FrequencyMonthly {
+		t.Fatalf("got frequency %q", st.Frequency)
+	}
+	var got []string
+	for o := range st.Occurrences(time.Time{}, time.Time(testDate("2024-05-31"))) {
+		if o.ScheduledTransaction != st {
+			t.Errorf("got scheduled transaction %v", o.ScheduledTransaction)
+		}
+		got = append(got, o.Date.String())
+	}
+	// The end of February doesn't move the rent to the 29th of every month.
+	if g := strings.Join(got, " "); g != "2024-02-29 2024-03-31 2024-04-30 2024-05-31" {
+		t.Errorf("got %s", g)
+	}
+
+	// A schedule that was moved to the 29th stays on the 29th.
+	st.DateFirst = testDate("2023-11-10")
+	got = nil
+	for o := range st.Occurrences(time.Time(testDate("2024-03-01")), time.Time(testDate("2024-04-30"))) {
+		got = append(got, o.Date.String())
+	}
+	if g := strings.Join(got, " "); g != "2024-03-29 2024-04-29" {
+		t.Errorf("got %s", g)
+	}
+}
@@ -0,0 +1,251 @@
+package ynab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Instrumentation reports a span and metrics for every API call a Client
+// makes. Install it with WithInstrumentation.
+//
+// The interfaces are a small subset of OpenTelemetry's tracing and metrics
+// APIs, so this package does not depend on OpenTelemetry. The ynabotel
+// package adapts an OpenTelemetry TracerProvider and MeterProvider to them:
+//
+//	inst, err := ynabotel.New(otel.GetTracerProvider(), otel.GetMeterProvider())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := ynab.NewClient(token, ynab.WithInstrumentation(inst))
+//
+// Each call gets one span, named after the method that was called, e.g.
+// "PlanService.UpdateTransactions", that covers every retry. Requests sent
+// with MakeRequest or Do are named after their method and path instead, e.g.
+// "GET /user". Spans and metrics carry these attributes:
+//
+//	ynab.operation             the span name (spans and metrics)
+//	http.request.method        e.g. "PATCH" (spans and metrics)
+//	http.response.status_code  the status of the last attempt (spans and metrics)
+//	ynab.error_id              the YNAB error id, e.g. "404.2" (spans and metrics)
+//	url.path                   the request path (spans)
+//	ynab.plan_id               the plan in the path (spans)
+//	ynab.transaction_ids       transactions in the path or request body (spans)
+//	http.response.body.size    bytes read from the response body (spans)
+//	ynab.attempts              the number of times the request was sent (spans)
+//
+// The access token and request and response bodies are never recorded. For
+// the Stream methods, the span ends when the response headers arrive.
+type Instrumentation struct {
+	// Tracer, if not nil, starts a span for each call.
+	Tracer Tracer
+	// Requests, if not nil, is incremented once per call. A good name for the
+	// instrument is "ynab.client.requests".
+	Requests Int64Counter
+	// Duration, if not nil, records the duration of each call in seconds,
+	// including retries. A good name for the instrument is
+	// "ynab.client.request.duration".
+	Duration Float64Histogram
+}
+
+// An Attribute is a key-value pair attached to a span or measurement. Value
+// is a string, an int64 or a []string.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// A Tracer starts spans.
+type Tracer interface {
+	// Start starts a span and returns a context that contains it.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// A Span is a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError records that the operation failed with err.
+	RecordError(err error)
+	End()
+}
+
+// An Int64Counter is a metric that only goes up.
+type Int64Counter interface {
+	Add(ctx context.Context, n int64, attrs ...Attribute)
+}
+
+// A Float64Histogram records a distribution of values.
+type Float64Histogram interface {
+	Record(ctx context.Context, v float64, attrs ...Attribute)
+}
+
+// WithInstrumentation reports spans and metrics for every API call to inst.
+func WithInstrumentation(inst *Instrumentation) ClientOption {
+	return func(c *Client) {
+		c.instrumentation = inst
+	}
+}
+
+type operationKey struct{}
+
+// withOperation names the operation for requests made with ctx. Every method
+// that sends a request calls it first. A name already in ctx is kept, so a
+// method that wraps another, like ListAccounts or CreateTransactions, names
+// the calls it makes.
+func withOperation(ctx context.Context, name string) context.Context {
+	if _, ok := ctx.Value(operationKey{}).(string); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, operationKey{}, name)
+}
+
+// callKey is the context key for the *callStats of an instrumented call.
+type callKey struct{}
+
+// callStats collects what the transport sees during one instrumented call.
+type callStats struct {
+	attempts atomic.Int64
+	status   atomic.Int64
+	size     atomic.Int64
+}
+
+// observe is the middleware that fills in the callStats of an instrumented
+// call. SetTransport installs it innermost, so it sees every attempt.
+func observe(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		stats, _ := r.Context().Value(callKey{}).(*callStats)
+		if stats == nil {
+			return next.RoundTrip(r)
+		}
+		stats.attempts.Add(1)
+		resp, err := next.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		stats.status.Store(int64(resp.StatusCode))
+		stats.size.Store(0)
+		resp.Body = &countingBody{ReadCloser: resp.Body, n: &stats.size}
+		return resp, nil
+	})
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// instrumented runs send for r inside a span and records metrics.
+func (c *Client) instrumented(r *http.Request, send func(*http.Request) error) error {
+	inst := c.instrumentation
+	ctx := r.Context()
+	op, ok := ctx.Value(operationKey{}).(string)
+	if !ok {
+		// A request sent with MakeRequest or Do.
+		op = r.Method + " " + r.URL.Path
+	}
+	attrs := []Attribute{
+		{"ynab.operation", op},
+		{"http.request.method", r.Method},
+	}
+	var span Span
+	if inst.Tracer != nil {
+		spanAttrs := append(attrs[:len(attrs):len(attrs)], Attribute{"url.path", r.URL.Path})
+		if id := pathSegmentAfter(r.URL.Path, "plans"); id != "" {
+			spanAttrs = append(spanAttrs, Attribute{"ynab.plan_id", id})
+		}
+		if ids := transactionIDs(r); len(ids) > 0 {
+			spanAttrs = append(spanAttrs, Attribute{"ynab.transaction_ids", ids})
+		}
+		ctx, span = inst.Tracer.Start(ctx, op, spanAttrs...)
+	}
+	stats := new(callStats)
+	ctx = context.WithValue(ctx, callKey{}, stats)
+	start := time.Now()
+	err := send(r.WithContext(ctx))
+	elapsed := time.Since(start)
+
+	if status := stats.status.Load(); status != 0 {
+		attrs = append(attrs, Attribute{"http.response.status_code", status})
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.ID != "" {
+		attrs = append(attrs, Attribute{"ynab.error_id", apiErr.ID})
+	}
+	if span != nil {
+		span.SetAttributes(attrs[2:]...)
+		span.SetAttributes(
+			Attribute{"http.response.body.size", stats.size.Load()},
+			Attribute{"ynab.attempts", stats.attempts.Load()},
+		)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+	if inst.Requests != nil {
+		inst.Requests.Add(ctx, 1, attrs...)
+	}
+	if inst.Duration != nil {
+		inst.Duration.Record(ctx, elapsed.Seconds(), attrs...)
+	}
+	return err
+}
+
+// pathSegmentAfter returns the path segment that follows the segment named
+// key, e.g. the plan ID after "plans".
+func pathSegmentAfter(path, key string) string {
+	segments := strings.Split(path, "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == key {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+// transactionIDs returns the IDs of the transactions a request refers to, in
+// its path or in the "id" fields of its body.
+func transactionIDs(r *http.Request) []string {
+	var ids []string
+	if id := pathSegmentAfter(r.URL.Path, "transactions"); id != "" && id != "import" {
+		ids = append(ids, id)
+	}
+	if r.GetBody == nil {
+		return ids
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return ids
+	}
+	defer body.Close()
+	type withID struct {
+		ID string `json:"id"`
+	}
+	var req struct {
+		Transaction  *withID  `json:"transaction"`
+		Transactions []withID `json:"transactions"`
+	}
+	if json.NewDecoder(body).Decode(&req) != nil {
+		return ids
+	}
+	if req.Transaction != nil && req.Transaction.ID != "" {
+		ids = append(ids, req.Transaction.ID)
+	}
+	for _, t := range req.Transactions {
+		if t.ID != "" {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,213 @@
+package ynab
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) { s.err = err }
+func (s *testSpan) End()                  { s.ended = true }
+
+type testTelemetry struct {
+	mu        sync.Mutex
+	spans     []*testSpan
+	requests  map[string]int64
+	durations int
+}
+
+func (tt *testTelemetry) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	s := &testSpan{name: name, attrs: make(map[string]any)}
+	s.SetAttributes(attrs...)
+	tt.spans = append(tt.spans, s)
+	return ctx, s
+}
+
+func (tt *testTelemetry) Add(ctx context.Context, n int64, attrs ...Attribute) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	var key []string
+	for _, a := range attrs {
+		key = append(key, fmt.Sprint(a.Value))
+	}
+	tt.requests[strings.Join(key, " ")] += n
+}
+
+func (tt *testTelemetry) Record(ctx context.Context, v float64, attrs ...Attribute) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.durations++
+}
+
+func TestInstrumentation(t *testing.T) {
+	const updated = `{"data": {"transaction_ids": ["txn-1"], "server_knowledge": 5}}`
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plans/plan-1/transactions":
+			if r.Method == "PATCH" && hits.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write(rateLimitedBody)
+				return
+			}
+			w.Write([]byte(updated))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"id": "404.2", "name": "resource_not_found", "detail": "Not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	tel := &testTelemetry{requests: make(map[string]int64)}
+	client := NewClient("secret-token", WithBaseURL(server.URL), WithInstrumentation(&Instrumentation{
+		Tracer:   tel,
+		Requests: tel,
+		Duration: tel,
+	}))
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	ctx := context.Background()
+	plan := client.Plans("plan-1")
+	if _, err := plan.UpdateTransactions(ctx, &UpdateTransactionsRequest{Transactions: []*UpdateTransaction{{}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plan.GetTransaction(ctx, "txn-2"); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, err := range plan.StreamTransactions(ctx, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(tel.spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(tel.spans))
+	}
+	update, get, stream := tel.spans[0], tel.spans[1], tel.spans[2]
+	for _, tc := range []struct {
+		span *testSpan
+		name string
+		want map[string]any
+	}{
+		{update, "PlanService.UpdateTransactions", map[string]any{
+			"http.request.method":       "PATCH",
+			"ynab.plan_id":              "plan-1",
+			"http.response.status_code": int64(200),
+			"http.response.body.size":   int64(len(updated)),
+			"ynab.attempts":             int64(2),
+		}},
+		{get, "PlanService.GetTransaction", map[string]any{
+			"ynab.plan_id":              "plan-1",
+			"ynab.transaction_ids":      []string{"txn-2"},
+			"http.response.status_code": int64(404),
+			"ynab.error_id":             "404.2",
+			"ynab.attempts":             int64(1),
+		}},
+		{stream, "PlanService.StreamTransactions", map[string]any{
+			"http.request.method": "GET",
+			"url.path":            "/plans/plan-1/transactions",
+		}},
+	} {
+		if tc.span.name != tc.name {
+			t.Errorf("expected span %s, got %s", tc.name, tc.span.name)
+		}
+		if !tc.span.ended {
+			t.Errorf("%s: span was not ended", tc.name)
+		}
+		for k, v := range tc.want {
+			if got := tc.span.attrs[k]; fmt.Sprint(got) != fmt.Sprint(v) {
+				t.Errorf("%s: expected %s = %v, got %v", tc.name, k, v, got)
+			}
+		}
+		for k, v := range tc.span.attrs {
+			if strings.Contains(fmt.Sprint(v), "secret-token") {
+				t.Errorf("%s: attribute %s contains the token", tc.name, k)
+			}
+		}
+	}
+	if get.err == nil || update.err != nil {
+		t.Errorf("expected only the GetTransaction span to record an error, got %v and %v", update.err, get.err)
+	}
+
+	want := map[string]int64{
+		"PlanService.UpdateTransactions PATCH 200": 1,
+		"PlanService.GetTransaction GET 404 404.2": 1,
+		"PlanService.StreamTransactions GET 200":   1,
+	}
+	keys := slices.Sorted(maps.Keys(tel.requests))
+	if len(tel.requests) != len(want) {
+		t.Errorf("unexpected request counts: %q", keys)
+	}
+	for k, n := range want {
+		if tel.requests[k] != n {
+			t.Errorf("expected %d requests for %q, got %d (have %q)", n, k, tel.requests[k], keys)
+		}
+	}
+	if tel.durations != 3 {
+		t.Errorf("expected 3 durations, got %d", tel.durations)
+	}
+}
+
+func TestInstrumentationOperationNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer server.Close()
+
+	tel := &testTelemetry{requests: make(map[string]int64)}
+	client := NewClient("secret-token", WithBaseURL(server.URL), WithInstrumentation(&Instrumentation{Tracer: tel}))
+	ctx := context.Background()
+	plan := client.Plans("plan-1")
+	if _, err := plan.UpdateTransaction(ctx, "txn-1", &UpdateTransactionRequest{Transaction: &UpdateTransaction{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plan.UpdateScheduledTransaction(ctx, "st-1", &UpdateScheduledTransactionRequest{ScheduledTransaction: &SaveScheduledTransaction{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plan.ListAccounts(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plan.CreateTransactions(ctx, []*NewTransaction{{AccountID: "acct-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MakeRequest(ctx, "GET", "/user", nil, nil, new(UserResponse)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"PlanService.UpdateTransaction",
+		"PlanService.UpdateScheduledTransaction",
+		"PlanService.ListAccounts",
+		"PlanService.CreateTransactions",
+		"GET /user",
+	}
+	var names []string
+	for _, s := range tel.spans {
+		names = append(names, s.name)
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("got spans %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,122 @@
+// Package cli holds code shared by the ynab command and the single-purpose
+// ynab-* commands.
+package cli
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/credentials"
+	"github.com/kevinburke/ynab-go/forecast"
+	"github.com/kevinburke/ynab-go/store"
+)
+
+// OpenPlan returns a client and the plan the ynab-* commands work on. Unless
+// offline is true, it loads the access token for profile, checks it, and
+// asks YNAB for plan, or for the profile's plan if plan is empty; responses
+// that rarely change are cached in cacheDir if it isn't empty. If offline is
+// true, the client is nil and the plan is looked up in the store at storeDir.
+func OpenPlan(ctx context.Context, profile, plan, cacheDir, storeDir string, offline bool) (*ynab.Client, *ynab.Plan, error) {
+	var client *ynab.Client
+	if !offline {
+		creds, err := credentials.Load(ctx, profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		var opts []ynab.ClientOption
+		if cacheDir != "" {
+			opts = append(opts, ynab.WithCache(ynab.NewCache(ynab.NewDiskCache(cacheDir))))
+		}
+		client = ynab.NewClient(creds.Token, opts...)
+		if err := creds.Check(ctx, client); err != nil {
+			return nil, nil, err
+		}
+		plan = cmp.Or(plan, creds.Plan)
+	}
+	p, err := ResolvePlan(ctx, client, storeDir, plan)
+	var planErr *ynab.PlanError
+	if errors.As(err, &planErr) && planErr.Ambiguous {
+		return nil, nil, fmt.Errorf("%w\nplease use --budget-name to tell us which budget to use", err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, p, nil
+}
+
+// ResolvePlan returns the plan that nameOrID refers to. If client is nil,
+// the plan is looked up in the store at storeDir instead of asking YNAB.
+func ResolvePlan(ctx context.Context, client *ynab.Client, storeDir, nameOrID string) (*ynab.Plan, error) {
+	if client != nil {
+		return client.ResolvePlan(ctx, nameOrID)
+	}
+	st, err := store.Open(ctx, storeDir)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := st.Plans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ynab.FindPlan(plans, nil, nameOrID)
+}
+
+// Snapshot returns the plan data saved in the store at dir. Unless offline is true, the
+// saved data is brought up to date with YNAB first.
+func Snapshot(ctx context.Context, client *ynab.Client, dir string, plan *ynab.Plan, offline bool) (*ynab.PlanSnapshot, error) {
+	st, err := store.Open(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		return st.Load(ctx, plan.ID)
+	}
+	return store.Sync(ctx, st, client, plan)
+}
+
+// PlainAmount formats amounts in csv output, e.g. "-1234.56", so spreadsheets
+// can parse them. decimalDigits is the plan's number of decimal digits.
+func PlainAmount(amount int64, decimalDigits int) string {
+	cf := ynab.CurrencyFormat{DecimalDigits: decimalDigits, DecimalSeparator: "."}
+	return ynab.Milliunits(amount).Format(cf)
+}
+
+// ForecastRows returns a header and a row for each day of f: the date, the
+// balance of each account and the total, and the accounts that are below the
+// floor that day, separated by "; ". amount and date format the cells.
+func ForecastRows(f *forecast.Forecast, amount func(int64) string, date func(ynab.Date) string) (header []string, rows [][]string) {
+	header = []string{"Date"}
+	for _, acct := range f.Accounts {
+		header = append(header, acct.Name)
+	}
+	header = append(header, "Total", "Below Floor")
+	for _, day := range f.Days {
+		row := []string{date(day.Date)}
+		var below []string
+		for i, b := range day.Balances {
+			row = append(row, amount(b))
+			if b < f.Floor {
+				below = append(below, f.Accounts[i].Name)
+			}
+		}
+		rows = append(rows, append(row, amount(day.Total), strings.Join(below, "; ")))
+	}
+	return header, rows
+}
+
+// ForecastSummary returns a sentence for each account that goes below the
+// floor, saying when, earliest first, or one saying that none does.
+func ForecastSummary(f *forecast.Forecast, amount func(int64) string, date func(ynab.Date) string) []string {
+	if f.Low == nil {
+		return []string{fmt.Sprintf("No account goes below %s by %s.", amount(f.Floor), date(f.Days[len(f.Days)-1].Date))}
+	}
+	var lines []string
+	for _, low := range f.Lows() {
+		lines = append(lines, fmt.Sprintf("%s goes below %s on %s (%s).", low.AccountName, amount(f.Floor), date(low.Date), amount(low.Balance)))
+	}
+	return lines
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/forecast"
+	"github.com/kevinburke/ynab-go/store"
+	"github.com/kevinburke/ynab-go/ynabtest"
+)
+
+func TestResolvePlan(t *testing.T) {
+	s := ynabtest.NewServer()
+	defer s.Close()
+	s.AddPlan("Personal")
+	business := s.AddPlan("Business")
+	client := s.Client()
+	ctx := context.Background()
+
+	p, err := ResolvePlan(ctx, client, "", "busi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != business.ID {
+		t.Errorf("got plan %s, want %s", p.ID, business.ID)
+	}
+
+	dir := t.TempDir()
+	if _, err := store.Sync(ctx, store.NewFileStore(dir), client, p); err != nil {
+		t.Fatal(err)
+	}
+	p, err = ResolvePlan(ctx, nil, dir, "Business")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != business.ID {
+		t.Errorf("got offline plan %s, want %s", p.ID, business.ID)
+	}
+	if _, err := ResolvePlan(ctx, nil, dir, "Personal"); !errors.Is(err, ynab.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a plan that wasn't saved, got %v", err)
+	}
+}
+
+func TestPlainAmount(t *testing.T) {
+	for _, tt := range []struct {
+		amount int64
+		digits int
+		want   string
+	}{
+		{-1234560, 2, "-1234.56"},
+		{1500, 0, "2"},
+		{12345, 3, "12.345"},
+	} {
+		if got := PlainAmount(tt.amount, tt.digits); got != tt.want {
+			t.Errorf("PlainAmount(%d, %d) = %q, want %q", tt.amount, tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestForecastRows(t *testing.T) {
+	start := time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local)
+	accounts := []*ynab.Account{
+		{ID: "checking", Name: "Checking", Type: "checking", OnBudget: true, Balance: 150000},
+		{ID: "savings", Name: "Savings", Type: "savings", OnBudget: true, Balance: 50000},
+	}
+	scheduled := []*ynab.ScheduledTransaction{
+		{ID: "rent", AccountID: "checking", DateNext: ynab.Date(start.AddDate(0, 0, 1)), Frequency: ynab.FrequencyNever, Amount: -100000},
+	}
+	f, err := forecast.Compute(accounts, scheduled, &forecast.Options{Start: start, Days: 2, Floor: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := func(m int64) string { return PlainAmount(m, 2) }
+	header, rows := ForecastRows(f, amount, ynab.Date.String)
+	if want := []string{"Date", "Checking", "Savings", "Total", "Below Floor"}; !slices.Equal(header, want) {
+		t.Errorf("got header %q, want %q", header, want)
+	}
+	want := [][]string{
+		{"2026-11-01", "150.00", "50.00", "200.00", "Savings"},
+		{"2026-11-02", "50.00", "50.00", "100.00", "Checking; Savings"},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("got rows %q, want %q", rows, want)
+	}
+	summary := strings.Join(ForecastSummary(f, amount, ynab.Date.String), "\n")
+	if want := "Savings goes below 100.00 on 2026-11-01 (50.00).\nChecking goes below 100.00 on 2026-11-02 (50.00)."; summary != want {
+		t.Errorf("got summary %q, want %q", summary, want)
+	}
+}
@@ -121,6 +121,19 @@ func TestDateUnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestDateUnmarshalText(t *testing.T) {
+	var date Date
+	if err := date.UnmarshalText([]byte("2023-05-15")); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := date.MarshalText(); string(text) != "2023-05-15" {
+		t.Errorf("expected 2023-05-15, got %s", text)
+	}
+	if err := date.UnmarshalText([]byte("05/15/2023")); err == nil {
+		t.Error("expected an error for a date that isn't YYYY-MM-DD")
+	}
+}
+
 func TestUpdateTransaction(t *testing.T) {
 	var receivedMethod, receivedPath string
 	var receivedBody []byte
@@ -329,6 +342,67 @@ func TestCreateTransaction(t *testing.T) {
 	}
 }
 
+func TestCreateTransactionsBatches(t *testing.T) {
+	var batchSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTransactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error("failed to decode request body:", err)
+			return
+		}
+		if req.Transaction != nil {
+			t.Error("expected bulk request to omit transaction")
+		}
+		batchSizes = append(batchSizes, len(req.Transactions))
+		var ids, dups []string
+		for _, txn := range req.Transactions {
+			if txn.ImportID.String == "dup" {
+				dups = append(dups, txn.ImportID.String)
+				continue
+			}
+			ids = append(ids, txn.Memo.String)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"transaction_ids":      ids,
+			"duplicate_import_ids": dups,
+			"server_knowledge":     len(batchSizes),
+		}})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+
+	n := CreateTransactionsBatchSize + 3
+	txns := make([]*NewTransaction, n)
+	for i := range txns {
+		txns[i] = &NewTransaction{
+			AccountID: "account-id",
+			Amount:    -1000,
+			Memo:      types.NullString{String: fmt.Sprintf("txn-%d", i), Valid: true},
+		}
+	}
+	txns[1].ImportID = types.NullString{String: "dup", Valid: true}
+
+	result, err := client.Plans("plan-id").CreateTransactions(context.Background(), txns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batchSizes) != 2 || batchSizes[0] != CreateTransactionsBatchSize || batchSizes[1] != 3 {
+		t.Errorf("expected batches of %d and 3, got %v", CreateTransactionsBatchSize, batchSizes)
+	}
+	if len(result.TransactionIDs) != n-1 {
+		t.Errorf("expected %d transaction IDs, got %d", n-1, len(result.TransactionIDs))
+	}
+	if len(result.DuplicateImportIDs) != 1 || result.DuplicateImportIDs[0] != "dup" {
+		t.Errorf("expected one duplicate import ID, got %v", result.DuplicateImportIDs)
+	}
+	if result.ServerKnowledge != 2 {
+		t.Errorf("expected server knowledge from last batch, got %d", result.ServerKnowledge)
+	}
+}
+
 func TestDeleteTransaction(t *testing.T) {
 	var receivedMethod, receivedPath string
 
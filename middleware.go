@@ -0,0 +1,93 @@
+package ynab
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A ClientOption configures a Client. Pass options to NewClient.
+type ClientOption func(*Client)
+
+// WithBaseURL sets the base URL requests are sent to, e.g. the URL of a fake
+// server in tests. The default is DefaultBaseURL.
+func WithBaseURL(base string) ClientOption {
+	return func(c *Client) {
+		c.Base = strings.TrimSuffix(base, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests, e.g. one with a
+// proxy-aware transport or a different timeout. Middleware wraps its
+// transport; hc itself is not modified.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.Client.Client = hc
+	}
+}
+
+// WithMiddleware adds middleware around the client's transport. The first
+// middleware passed is the outermost: it sees each request first and each
+// response last. Middleware runs once per attempt, so a retried request
+// passes through it again.
+func WithMiddleware(mw ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// A Middleware wraps an http.RoundTripper to observe or change requests and
+// responses, e.g. to log them, record metrics or add tracing headers:
+//
+//	logRequests := func(next http.RoundTripper) http.RoundTripper {
+//		return ynab.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+//			resp, err := next.RoundTrip(r)
+//			log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+//			return resp, err
+//		})
+//	}
+//	client := ynab.NewClient(token, ynab.WithMiddleware(logRequests))
+//
+// Requests reach middleware with the Authorization header set.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// transport returns the transport of the client's HTTP client.
+func (c *Client) transport() http.RoundTripper {
+	if c.Client.Client == nil {
+		return nil
+	}
+	return c.Client.Client.Transport
+}
+
+// SetTransport sets the http.RoundTripper the client uses to send requests,
+// e.g. a Recorder or a Replayer. Middleware added with WithMiddleware wraps
+// rt. Retries and rate limiting still apply, since they happen before the
+// transport is called. A Cache set with WithCache sits between rt and the
+// middleware, so cached responses never reach rt.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	hc := new(http.Client)
+	if c.Client.Client != nil {
+		*hc = *c.Client.Client
+	}
+	if rt == nil && (len(c.middleware) > 0 || c.instrumentation != nil || c.cache != nil) {
+		rt = http.DefaultTransport
+	}
+	if c.cache != nil {
+		rt = c.cache.middleware(rt, c.waitForLimiter)
+	}
+	if c.instrumentation != nil {
+		rt = observe(rt)
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		rt = c.middleware[i](rt)
+	}
+	hc.Transport = rt
+	c.Client.Client = hc
+}
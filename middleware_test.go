@@ -0,0 +1,116 @@
+package ynab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientOptions(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/user" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Trace-Id"); got != "trace-1" {
+			t.Errorf("expected trace header, got %q", got)
+		}
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(rateLimitedBody)
+			return
+		}
+		w.Write([]byte(`{"data": {"user": {"id": "user-123"}}}`))
+	}))
+	defer server.Close()
+
+	var events []string
+	hook := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				events = append(events, name+" request")
+				resp, err := next.RoundTrip(r)
+				if err == nil {
+					events = append(events, name+" "+resp.Status)
+				}
+				return resp, err
+			})
+		}
+	}
+	trace := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.Header.Set("X-Trace-Id", "trace-1")
+			return next.RoundTrip(r)
+		})
+	}
+	hc := &http.Client{Timeout: 5 * time.Second}
+	client := NewClient("test-token",
+		WithBaseURL(server.URL+"/v1/"),
+		WithHTTPClient(hc),
+		WithMiddleware(hook("outer"), hook("inner")),
+		WithMiddleware(trace),
+	)
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	resp, err := client.GetUser(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.User.ID != "user-123" {
+		t.Errorf("expected user-123, got %q", resp.Data.User.ID)
+	}
+	want := []string{
+		"outer request", "inner request", "inner 429 Too Many Requests", "outer 429 Too Many Requests",
+		"outer request", "inner request", "inner 200 OK", "outer 200 OK",
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("unexpected events:\n got %q\nwant %q", events, want)
+	}
+	if hc.Transport != nil {
+		t.Error("WithHTTPClient modified the caller's http.Client")
+	}
+}
+
+func TestSetTransportKeepsMiddleware(t *testing.T) {
+	var seen int
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			seen++
+			return next.RoundTrip(r)
+		})
+	}
+	client := NewClient("test-token", WithMiddleware(count))
+	client.SetTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		w.Write([]byte(`{"data": {"user": {"id": "user-123"}}}`))
+		return w.Result(), nil
+	}))
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if seen != 1 {
+		t.Errorf("expected middleware to see 1 request, got %d", seen)
+	}
+}
+
+func TestNewClientWithoutToken(t *testing.T) {
+	client := NewClient("")
+	var auth []string
+	client.SetTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		auth = r.Header.Values("Authorization")
+		w := httptest.NewRecorder()
+		w.Write([]byte(`{"data": {"user": {"id": "user-123"}}}`))
+		return w.Result(), nil
+	}))
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(auth) != 0 {
+		t.Errorf("expected no Authorization header, got %q", auth)
+	}
+}
@@ -0,0 +1,267 @@
+package ynab
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Milliunits is an amount of money in the milliunits format used by the YNAB
+// API: 1000 milliunits is one unit of currency, so $12.34 is 12340. It
+// marshals to and from JSON as an integer, like the API.
+//
+// The arithmetic methods return ErrAmountOverflow instead of silently
+// wrapping around.
+type Milliunits int64
+
+// ErrAmountOverflow is returned when an arithmetic operation on Milliunits
+// does not fit in an int64.
+var ErrAmountOverflow = errors.New("ynab: amount overflows int64 milliunits")
+
+// Add returns m + n.
+func (m Milliunits) Add(n Milliunits) (Milliunits, error) {
+	s := m + n
+	if (n > 0 && s < m) || (n < 0 && s > m) {
+		return 0, ErrAmountOverflow
+	}
+	return s, nil
+}
+
+// Sub returns m - n.
+func (m Milliunits) Sub(n Milliunits) (Milliunits, error) {
+	s := m - n
+	if (n > 0 && s > m) || (n < 0 && s < m) {
+		return 0, ErrAmountOverflow
+	}
+	return s, nil
+}
+
+// Neg returns -m, e.g. to turn an outflow into a positive amount.
+func (m Milliunits) Neg() (Milliunits, error) {
+	if m == math.MinInt64 {
+		return 0, ErrAmountOverflow
+	}
+	return -m, nil
+}
+
+// Sum returns the sum of amounts.
+func Sum(amounts ...Milliunits) (Milliunits, error) {
+	var total Milliunits
+	for _, a := range amounts {
+		var err error
+		total, err = total.Add(a)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+var pow10 = [...]uint64{1, 10, 100, 1000}
+
+func clampDigits(decimalDigits int) int {
+	return min(max(decimalDigits, 0), 3)
+}
+
+// Allocate splits m into parts proportional to weights, e.g. weights of
+// 1, 1, 2 split $100.00 into $25.00, $25.00 and $50.00. Every part but the
+// last is rounded toward zero to a whole number of the smallest unit of a
+// currency with decimalDigits digits after the decimal point (10 milliunits
+// for 2 digits). The last part gets whatever is left, so the parts always sum
+// to m.
+func (m Milliunits) Allocate(weights []int64, decimalDigits int) ([]Milliunits, error) {
+	if len(weights) == 0 {
+		return nil, &Error{Message: "ynab: cannot allocate an amount to zero parts"}
+	}
+	total := new(big.Int)
+	for _, w := range weights {
+		if w < 0 {
+			return nil, &Error{Message: fmt.Sprintf("ynab: cannot allocate an amount using negative weight %d", w)}
+		}
+		total.Add(total, big.NewInt(w))
+	}
+	if total.Sign() == 0 {
+		return nil, &Error{Message: "ynab: cannot allocate an amount when every weight is zero"}
+	}
+	unit := big.NewInt(int64(pow10[3-clampDigits(decimalDigits)]))
+	parts := make([]Milliunits, len(weights))
+	remaining := m
+	amount := big.NewInt(int64(m))
+	for i, w := range weights[:len(weights)-1] {
+		part := new(big.Int).Mul(amount, big.NewInt(w))
+		part.Quo(part, total)
+		part.Quo(part, unit)
+		part.Mul(part, unit)
+		parts[i] = Milliunits(part.Int64())
+		remaining -= parts[i]
+	}
+	parts[len(parts)-1] = remaining
+	return parts, nil
+}
+
+// Split splits m into n parts that are as equal as possible, e.g. $10.00 into
+// $3.33, $3.33 and $3.34. See Allocate.
+func (m Milliunits) Split(n int, decimalDigits int) ([]Milliunits, error) {
+	if n <= 0 {
+		return nil, &Error{Message: fmt.Sprintf("ynab: cannot split an amount into %d parts", n)}
+	}
+	weights := make([]int64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return m.Allocate(weights, decimalDigits)
+}
+
+// Format formats m according to cf: rounded to cf.DecimalDigits, with
+// cf.GroupSeparator between groups of thousands, and with the currency symbol
+// if cf.DisplaySymbol is true. Negative amounts are prefixed with a minus
+// sign, e.g. "-$1,234.56" or "-1.234,56€".
+func (m Milliunits) Format(cf CurrencyFormat) string {
+	digits := clampDigits(cf.DecimalDigits)
+	neg := m < 0
+	abs := uint64(m)
+	if neg {
+		abs = -abs
+	}
+	scale := pow10[3-digits]
+	q, r := abs/scale, abs%scale
+	if r*2 >= scale && scale > 1 {
+		q++
+	}
+	whole, frac := q/pow10[digits], q%pow10[digits]
+	s := groupDigits(strconv.FormatUint(whole, 10), cf.GroupSeparator)
+	if digits > 0 {
+		sep := cf.DecimalSeparator
+		if sep == "" {
+			sep = "."
+		}
+		s += sep + fmt.Sprintf("%0*d", digits, frac)
+	}
+	if cf.DisplaySymbol && cf.CurrencySymbol != "" {
+		if cf.SymbolFirst {
+			s = cf.CurrencySymbol + s
+		} else {
+			s = s + cf.CurrencySymbol
+		}
+	}
+	if neg && q != 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+func groupDigits(s string, sep string) string {
+	if sep == "" || len(s) <= 3 {
+		return s
+	}
+	var b strings.Builder
+	first := len(s) % 3
+	if first == 0 {
+		first = 3
+	}
+	b.WriteString(s[:first])
+	for i := first; i < len(s); i += 3 {
+		b.WriteString(sep)
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// String formats m as a plain decimal number with two digits after the
+// decimal point, or three if the amount has fractional cents, e.g. "-12.34"
+// or "0.005".
+func (m Milliunits) String() string {
+	digits := 2
+	if m%10 != 0 {
+		digits = 3
+	}
+	return m.Format(CurrencyFormat{DecimalDigits: digits, DecimalSeparator: "."})
+}
+
+// ParseMilliunits parses an amount formatted according to cf, e.g.
+// "$1,234.56" or "-1.234,56€", into milliunits. The currency symbol and group
+// separators are optional. A zero CurrencyFormat parses plain decimal numbers
+// like "-12.34".
+func ParseMilliunits(s string, cf CurrencyFormat) (Milliunits, error) {
+	invalid := func() (Milliunits, error) {
+		return 0, &Error{Message: fmt.Sprintf("ynab: invalid amount %q", s)}
+	}
+	str := strings.TrimSpace(s)
+	if cf.CurrencySymbol != "" {
+		str = strings.Replace(str, cf.CurrencySymbol, "", 1)
+	}
+	str = strings.TrimSpace(str)
+	neg := false
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		neg = str[0] == '-'
+		str = strings.TrimSpace(str[1:])
+	}
+	decSep := cf.DecimalSeparator
+	if decSep == "" {
+		decSep = "."
+	}
+	if cf.GroupSeparator != "" && cf.GroupSeparator != decSep {
+		str = strings.ReplaceAll(str, cf.GroupSeparator, "")
+	}
+	whole, frac, _ := strings.Cut(str, decSep)
+	if whole == "" && frac == "" {
+		return invalid()
+	}
+	if len(frac) > 3 {
+		return 0, &Error{Message: fmt.Sprintf("ynab: amount %q has more than 3 decimal places", s)}
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return invalid()
+	}
+	var w uint64
+	if whole != "" {
+		var err error
+		w, err = strconv.ParseUint(whole, 10, 64)
+		if err != nil {
+			return 0, ErrAmountOverflow
+		}
+	}
+	var f uint64
+	if frac != "" {
+		f, _ = strconv.ParseUint(frac, 10, 64)
+		f *= pow10[3-len(frac)]
+	}
+	if w > (math.MaxInt64-f)/1000 {
+		return 0, ErrAmountOverflow
+	}
+	v := Milliunits(w*1000 + f)
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Inflow returns the amount of the transaction if it is positive, and zero
+// otherwise.
+func (t *Transaction) Inflow() Milliunits {
+	if t.Amount > 0 {
+		return Milliunits(t.Amount)
+	}
+	return 0
+}
+
+// Outflow returns the amount of the transaction as a positive number if it is
+// negative, and zero otherwise.
+func (t *Transaction) Outflow() Milliunits {
+	if t.Amount < 0 {
+		return Milliunits(-t.Amount)
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package ynab
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+var (
+	usdFormat = CurrencyFormat{ISOCode: "USD", DecimalDigits: 2, DecimalSeparator: ".", SymbolFirst: true, GroupSeparator: ",", CurrencySymbol: "$", DisplaySymbol: true}
+	eurFormat = CurrencyFormat{ISOCode: "EUR", DecimalDigits: 2, DecimalSeparator: ",", SymbolFirst: false, GroupSeparator: ".", CurrencySymbol: "€", DisplaySymbol: true}
+	jpyFormat = CurrencyFormat{ISOCode: "JPY", DecimalDigits: 0, DecimalSeparator: ".", SymbolFirst: true, GroupSeparator: ",", CurrencySymbol: "¥", DisplaySymbol: true}
+	bhdFormat = CurrencyFormat{ISOCode: "BHD", DecimalDigits: 3, DecimalSeparator: ".", SymbolFirst: true, GroupSeparator: ",", CurrencySymbol: "BD", DisplaySymbol: true}
+)
+
+func TestMilliunitsFormat(t *testing.T) {
+	tests := []struct {
+		amount Milliunits
+		cf     CurrencyFormat
+		want   string
+	}{
+		{1234560, usdFormat, "$1,234.56"},
+		{-1234560, usdFormat, "-$1,234.56"},
+		{5, usdFormat, "$0.01"},
+		{-4, usdFormat, "$0.00"},
+		{1234560, eurFormat, "1.234,56€"},
+		{-1000000000, eurFormat, "-1.000.000,00€"},
+		{1234500, jpyFormat, "¥1,235"},
+		{1234567, bhdFormat, "BD1,234.567"},
+		{123000, CurrencyFormat{DecimalDigits: 2}, "123.00"},
+		{math.MinInt64, CurrencyFormat{DecimalDigits: 3}, "-9223372036854775.808"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Format(tt.cf); got != tt.want {
+			t.Errorf("Milliunits(%d).Format(%s) = %q, want %q", tt.amount, tt.cf.ISOCode, got, tt.want)
+		}
+	}
+}
+
+func TestMilliunitsString(t *testing.T) {
+	if got := Milliunits(-12340).String(); got != "-12.34" {
+		t.Errorf("got %q", got)
+	}
+	if got := Milliunits(5).String(); got != "0.005" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseMilliunits(t *testing.T) {
+	tests := []struct {
+		in   string
+		cf   CurrencyFormat
+		want Milliunits
+	}{
+		{"$1,234.56", usdFormat, 1234560},
+		{"-$1,234.56", usdFormat, -1234560},
+		{"12", usdFormat, 12000},
+		{"-1.234,56€", eurFormat, -1234560},
+		{"¥1,235", jpyFormat, 1235000},
+		{"BD1.005", bhdFormat, 1005},
+		{"-0.5", CurrencyFormat{}, -500},
+		{".25", CurrencyFormat{}, 250},
+	}
+	for _, tt := range tests {
+		got, err := ParseMilliunits(tt.in, tt.cf)
+		if err != nil {
+			t.Errorf("ParseMilliunits(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMilliunits(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "$", "1.2345", "12a", "1.2.3", "--1"} {
+		if _, err := ParseMilliunits(in, usdFormat); err == nil {
+			t.Errorf("ParseMilliunits(%q): expected an error", in)
+		}
+	}
+	if _, err := ParseMilliunits("99999999999999999999", CurrencyFormat{}); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+}
+
+func TestMilliunitsArithmeticOverflow(t *testing.T) {
+	if _, err := Milliunits(math.MaxInt64).Add(1); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected overflow from Add, got %v", err)
+	}
+	if _, err := Milliunits(math.MinInt64).Sub(1); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected overflow from Sub, got %v", err)
+	}
+	if _, err := Milliunits(math.MinInt64).Neg(); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected overflow from Neg, got %v", err)
+	}
+	sum, err := Sum(1000, -250, 5)
+	if err != nil || sum != 755 {
+		t.Errorf("Sum = %d, %v; want 755", sum, err)
+	}
+}
+
+func TestMilliunitsSplitAndAllocate(t *testing.T) {
+	parts, err := Milliunits(10000).Split(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Milliunits{3330, 3330, 3340}; !slices.Equal(parts, want) {
+		t.Errorf("Split = %v, want %v", parts, want)
+	}
+	parts, err = Milliunits(-100000).Allocate([]int64{1, 1, 2}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Milliunits{-25000, -25000, -50000}; !slices.Equal(parts, want) {
+		t.Errorf("Allocate = %v, want %v", parts, want)
+	}
+	parts, err = Milliunits(1000000).Allocate([]int64{1, 2}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Milliunits{333000, 667000}; !slices.Equal(parts, want) {
+		t.Errorf("Allocate with 0 decimal digits = %v, want %v", parts, want)
+	}
+	if _, err := Milliunits(1000).Allocate([]int64{0, 0}, 2); err == nil {
+		t.Error("expected an error for zero weights")
+	}
+}
+
+func TestMilliunitsJSON(t *testing.T) {
+	var v struct {
+		Amount Milliunits `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": -2500}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Amount != -2500 {
+		t.Errorf("expected -2500, got %d", v.Amount)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"amount":-2500}` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+}
@@ -0,0 +1,371 @@
+// Package oauth implements YNAB's OAuth 2.0 authorization code and implicit
+// grant flows, for applications that act on behalf of other YNAB users.
+// Personal scripts should use a personal access token with ynab.NewClient
+// instead.
+//
+// With the authorization code flow, send the user to AuthCodeURL, exchange
+// the code YNAB passes to your redirect URL for a token, and create a client
+// that refreshes the token as it expires:
+//
+//	conf := &oauth.Config{ClientID: id, ClientSecret: secret, RedirectURL: "https://example.com/callback"}
+//	http.Redirect(w, r, conf.AuthCodeURL(state), http.StatusFound)
+//	// ... in the callback handler:
+//	tok, err := conf.Exchange(ctx, r.FormValue("code"))
+//	client := oauth.NewClient(conf.TokenSource(tok))
+//
+// Access tokens expire after two hours. Set Config.TokenRefreshed to save the
+// new token each time one is refreshed.
+//
+// See https://api.ynab.com/#oauth-applications for details.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// The default YNAB OAuth endpoints.
+const (
+	AuthURL  = "https://app.ynab.com/oauth/authorize"
+	TokenURL = "https://app.ynab.com/oauth/token"
+)
+
+// ScopeReadOnly requests read-only access. Without it, the application can
+// also make changes.
+const ScopeReadOnly = "read-only"
+
+// expiryDelta is how long before its expiry a token is refreshed, so that it
+// does not expire while a request is in flight.
+const expiryDelta = 30 * time.Second
+
+// Config describes a YNAB OAuth application.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL must match one of the redirect URIs registered for the
+	// application.
+	RedirectURL string
+	// Scopes are the scopes to request, e.g. ScopeReadOnly. Empty requests
+	// full access.
+	Scopes []string
+
+	// AuthURL and TokenURL default to the YNAB endpoints.
+	AuthURL  string
+	TokenURL string
+
+	// HTTPClient sends requests to TokenURL. If nil, http.DefaultClient is
+	// used.
+	HTTPClient *http.Client
+
+	// TokenRefreshed, if not nil, is called with each token a TokenSource
+	// obtains by refreshing, e.g. to save it for the user.
+	TokenRefreshed func(*Token)
+}
+
+// A Token is an OAuth access token and, for the authorization code flow, the
+// refresh token used to renew it.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Expiry is when the access token expires. The zero value means it does
+	// not expire.
+	Expiry time.Time `json:"expiry,omitzero"`
+	Scope  string    `json:"scope,omitempty"`
+}
+
+// Valid reports whether t has an access token that will not expire in the
+// next 30 seconds.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Until(t.Expiry) > expiryDelta
+}
+
+// Error is returned when the token endpoint rejects a request, e.g. because
+// the code was already used or the refresh token was revoked.
+type Error struct {
+	StatusCode int
+	// Code is the OAuth error code, e.g. "invalid_grant".
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("oauth: token request failed with status %d", e.StatusCode)
+	}
+	if e.Description == "" {
+		return "oauth: " + e.Code
+	}
+	return "oauth: " + e.Code + ": " + e.Description
+}
+
+// AuthCodeURL returns the URL to send the user to for the authorization code
+// flow. state is returned to the redirect URL unchanged; use it to protect
+// against cross-site request forgery.
+func (c *Config) AuthCodeURL(state string) string {
+	return c.authURL("code", state)
+}
+
+// ImplicitGrantURL returns the URL to send the user to for the implicit grant
+// flow, which is meant for applications that run entirely in the browser.
+// The access token is returned in the fragment of the redirect URL; parse it
+// with ParseFragment. Implicit grant tokens can't be refreshed.
+func (c *Config) ImplicitGrantURL(state string) string {
+	return c.authURL("token", state)
+}
+
+func (c *Config) authURL(responseType, state string) string {
+	v := url.Values{
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURL},
+		"response_type": {responseType},
+	}
+	if state != "" {
+		v.Set("state", state)
+	}
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	base := c.AuthURL
+	if base == "" {
+		base = AuthURL
+	}
+	if strings.Contains(base, "?") {
+		return base + "&" + v.Encode()
+	}
+	return base + "?" + v.Encode()
+}
+
+// ParseFragment parses the fragment of an implicit grant redirect URL, e.g.
+// "access_token=...&token_type=bearer&expires_in=7200&state=...", and returns
+// the token and the state.
+func ParseFragment(fragment string) (*Token, string, error) {
+	v, err := url.ParseQuery(strings.TrimPrefix(fragment, "#"))
+	if err != nil {
+		return nil, "", err
+	}
+	if code := v.Get("error"); code != "" {
+		return nil, "", &Error{Code: code, Description: v.Get("error_description")}
+	}
+	if v.Get("access_token") == "" {
+		return nil, "", errors.New("oauth: no access_token in fragment")
+	}
+	tok := &Token{AccessToken: v.Get("access_token"), TokenType: v.Get("token_type"), Scope: v.Get("scope")}
+	if s := v.Get("expires_in"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("oauth: invalid expires_in %q", s)
+		}
+		tok.Expiry = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return tok, v.Get("state"), nil
+}
+
+// Exchange exchanges an authorization code for a token.
+func (c *Config) Exchange(ctx context.Context, code string) (*Token, error) {
+	return c.retrieveToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.RedirectURL},
+	})
+}
+
+// Refresh returns a new token for refreshToken. YNAB issues a new refresh
+// token along with the access token; the old one can't be used again.
+func (c *Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	if refreshToken == "" {
+		return nil, errors.New("oauth: token has no refresh token")
+	}
+	return c.retrieveToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// tokenResponse is the body of a successful response from the token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+func (c *Config) retrieveToken(ctx context.Context, v url.Values) (*Token, error) {
+	v.Set("client_id", c.ClientID)
+	v.Set("client_secret", c.ClientSecret)
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = TokenURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		e := &Error{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &errResp) == nil {
+			e.Code, e.Description = errResp.Error, errResp.Description
+		}
+		return nil, e
+	}
+	tr := new(tokenResponse)
+	if err := json.Unmarshal(body, tr); err != nil {
+		return nil, fmt.Errorf("oauth: invalid token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("oauth: token response has no access_token")
+	}
+	tok := &Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+		Scope:        tr.Scope,
+	}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// A TokenSource returns a valid token for a request.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// StaticTokenSource returns a TokenSource that always returns t. Use it for
+// implicit grant tokens, which can't be refreshed.
+func StaticTokenSource(t *Token) TokenSource {
+	return staticTokenSource{t}
+}
+
+type staticTokenSource struct{ t *Token }
+
+func (s staticTokenSource) Token(context.Context) (*Token, error) { return s.t, nil }
+
+// TokenSource returns a TokenSource that returns t until it is about to
+// expire, and then refreshes it with c. It is safe for concurrent use; only
+// one refresh is made at a time.
+func (c *Config) TokenSource(t *Token) TokenSource {
+	return &refreshingSource{conf: c, t: t}
+}
+
+type refreshingSource struct {
+	conf *Config
+	mu   sync.Mutex
+	t    *Token
+}
+
+func (s *refreshingSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.t.Valid() {
+		return s.t, nil
+	}
+	var refreshToken string
+	if s.t != nil {
+		refreshToken = s.t.RefreshToken
+	}
+	t, err := s.conf.Refresh(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	s.t = t
+	if s.conf.TokenRefreshed != nil {
+		s.conf.TokenRefreshed(t)
+	}
+	return t, nil
+}
+
+// A RefreshError is returned by a Transport, wrapped in the *url.Error from
+// the http.Client, when Source can't provide a token, e.g. because the refresh
+// token was revoked. The request isn't sent. Err is the error from Source,
+// usually an *Error from the token endpoint.
+//
+// A RefreshError matches ynab.ErrUnauthorized with errors.Is, so a ynab.Client
+// doesn't retry it.
+type RefreshError struct {
+	Err error
+}
+
+func (e *RefreshError) Error() string {
+	return "oauth: can't get a token: " + strings.TrimPrefix(e.Err.Error(), "oauth: ")
+}
+
+func (e *RefreshError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ynab.ErrUnauthorized.
+func (e *RefreshError) Is(target error) bool {
+	return target == ynab.ErrUnauthorized
+}
+
+// Transport is an http.RoundTripper that sets the Authorization header of
+// each request to a token from Source. If Source returns an error, RoundTrip
+// returns it in a *RefreshError.
+type Transport struct {
+	Source TokenSource
+	// Base sends the requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.Source.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, &RefreshError{Err: err}
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// NewClient returns a ynab.Client that authenticates with tokens from src.
+// The token is added by the outermost middleware, so middleware in opts sees
+// it. The client has no static token of its own, and the middleware stays in
+// place if the transport is replaced with SetTransport.
+func NewClient(src TokenSource, opts ...ynab.ClientOption) *ynab.Client {
+	auth := ynab.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &Transport{Source: src, Base: next}
+	})
+	return ynab.NewClient("", append([]ynab.ClientOption{auth}, opts...)...)
+}
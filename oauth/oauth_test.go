@@ -0,0 +1,228 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/ynabtest"
+)
+
+// tokenServer is a stand-in for the YNAB token endpoint. It issues tokens
+// named "access-1", "access-2", ... and accepts refresh tokens it issued.
+type tokenServer struct {
+	*httptest.Server
+	requests  atomic.Int32
+	issued    atomic.Int32
+	expiresIn int
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.requests.Add(1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if r.PostForm.Get("client_id") != "client" || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			if r.PostForm.Get("code") != "good-code" || r.PostForm.Get("redirect_uri") != "https://example.com/callback" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant", "error_description": "The provided authorization grant is invalid"}`))
+				return
+			}
+		case "refresh_token":
+			if !strings.HasPrefix(r.PostForm.Get("refresh_token"), "refresh-") {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+		default:
+			t.Errorf("unexpected grant_type %q", r.PostForm.Get("grant_type"))
+		}
+		n := ts.issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "access-%d", "token_type": "Bearer", "expires_in": %d, "refresh_token": "refresh-%d", "scope": "read-only"}`, n, ts.expiresIn, n)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testConfig(ts *tokenServer) *Config {
+	return &Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://example.com/callback",
+		Scopes:       []string{ScopeReadOnly},
+		TokenURL:     ts.URL,
+	}
+}
+
+func TestAuthURLs(t *testing.T) {
+	conf := &Config{ClientID: "client", RedirectURL: "https://example.com/callback", Scopes: []string{ScopeReadOnly}}
+	u, err := url.Parse(conf.AuthCodeURL("xyz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != AuthURL {
+		t.Errorf("expected %s, got %s", AuthURL, got)
+	}
+	want := url.Values{
+		"client_id":     {"client"},
+		"redirect_uri":  {"https://example.com/callback"},
+		"response_type": {"code"},
+		"state":         {"xyz"},
+		"scope":         {"read-only"},
+	}
+	if u.RawQuery != want.Encode() {
+		t.Errorf("expected query %q, got %q", want.Encode(), u.RawQuery)
+	}
+	if !strings.Contains(conf.ImplicitGrantURL(""), "response_type=token") {
+		t.Errorf("implicit grant URL should request a token: %s", conf.ImplicitGrantURL(""))
+	}
+
+	tok, state, err := ParseFragment("#access_token=abc&token_type=bearer&expires_in=7200&state=xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "abc" || state != "xyz" || !tok.Valid() {
+		t.Errorf("unexpected token %+v, state %q", tok, state)
+	}
+	if _, _, err := ParseFragment("error=access_denied"); err == nil {
+		t.Error("expected an error for a denied request")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ts := newTokenServer(t, 7200)
+	conf := testConfig(ts)
+	ctx := context.Background()
+	tok, err := conf.Exchange(ctx, "good-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "access-1" || tok.RefreshToken != "refresh-1" || tok.Scope != "read-only" {
+		t.Errorf("unexpected token %+v", tok)
+	}
+	if d := time.Until(tok.Expiry); d < 7100*time.Second || d > 7200*time.Second {
+		t.Errorf("unexpected expiry in %v", d)
+	}
+
+	_, err = conf.Exchange(ctx, "bad-code")
+	var oerr *Error
+	if !errors.As(err, &oerr) || oerr.Code != "invalid_grant" || oerr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected invalid_grant, got %v", err)
+	}
+	if _, err := conf.Refresh(ctx, ""); err == nil {
+		t.Error("expected an error refreshing without a refresh token")
+	}
+}
+
+func TestClientRefreshesToken(t *testing.T) {
+	ts := newTokenServer(t, 7200)
+	conf := testConfig(ts)
+	var saved []*Token
+	conf.TokenRefreshed = func(tok *Token) { saved = append(saved, tok) }
+
+	api := ynabtest.NewServer()
+	defer api.Close()
+	api.SetToken("access-1")
+
+	expired := &Token{AccessToken: "access-0", RefreshToken: "refresh-0", Expiry: time.Now().Add(-time.Minute)}
+	client := NewClient(conf.TokenSource(expired), ynab.WithBaseURL(api.URL))
+	ctx := context.Background()
+	for range 2 {
+		if _, err := client.GetUser(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(saved) != 1 || saved[0].AccessToken != "access-1" {
+		t.Errorf("expected one refresh to access-1, got %+v", saved)
+	}
+
+	revoked := &Token{AccessToken: "access-0", RefreshToken: "revoked", Expiry: time.Now().Add(-time.Minute)}
+	client = NewClient(conf.TokenSource(revoked), ynab.WithBaseURL(api.URL))
+	var oerr *Error
+	if _, err := client.GetUser(ctx); !errors.As(err, &oerr) || oerr.Code != "invalid_grant" {
+		t.Errorf("expected invalid_grant, got %v", err)
+	}
+}
+
+func TestClientSetTransportKeepsToken(t *testing.T) {
+	ts := newTokenServer(t, 7200)
+	tok := &Token{AccessToken: "access-0", RefreshToken: "refresh-0", Expiry: time.Now().Add(time.Hour)}
+	client := NewClient(testConfig(ts).TokenSource(tok))
+	var auth []string
+	client.SetTransport(ynab.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		auth = r.Header.Values("Authorization")
+		w := httptest.NewRecorder()
+		w.Write([]byte(`{"data": {"user": {"id": "user-123"}}}`))
+		return w.Result(), nil
+	}))
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(auth) != 1 || auth[0] != "Bearer access-0" {
+		t.Errorf("expected only the OAuth token, got %q", auth)
+	}
+}
+
+func TestClientDoesNotRetryFailedRefresh(t *testing.T) {
+	ts := newTokenServer(t, 7200)
+	api := ynabtest.NewServer()
+	defer api.Close()
+
+	revoked := &Token{AccessToken: "access-0", RefreshToken: "revoked", Expiry: time.Now().Add(-time.Minute)}
+	client := NewClient(testConfig(ts).TokenSource(revoked), ynab.WithBaseURL(api.URL))
+	client.SetRetryPolicy(&ynab.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	_, err := client.GetUser(context.Background())
+	var rerr *RefreshError
+	if !errors.As(err, &rerr) || !errors.Is(err, ynab.ErrUnauthorized) {
+		t.Fatalf("expected a RefreshError matching ErrUnauthorized, got %v", err)
+	}
+	var oerr *Error
+	if !errors.As(err, &oerr) || oerr.Code != "invalid_grant" {
+		t.Errorf("expected invalid_grant, got %v", err)
+	}
+	if n := ts.requests.Load(); n != 1 {
+		t.Errorf("expected one refresh request, got %d", n)
+	}
+}
+
+func TestClientCacheSkipsRateLimiter(t *testing.T) {
+	ts := newTokenServer(t, 7200)
+	api := ynabtest.NewServer()
+	defer api.Close()
+	api.SetToken("access-0")
+
+	tok := &Token{AccessToken: "access-0", RefreshToken: "refresh-0", Expiry: time.Now().Add(time.Hour)}
+	client := NewClient(testConfig(ts).TokenSource(tok), ynab.WithBaseURL(api.URL), ynab.WithCache(ynab.NewCache(ynab.NewMemoryCache(10))))
+	limiter, err := ynab.NewRateLimiter(1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetRateLimiter(limiter)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for range 3 {
+		if _, err := client.GetUser(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := limiter.Remaining(); n != 0 {
+		t.Errorf("expected the first request to use the only token, have %d left", n)
+	}
+}
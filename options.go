@@ -0,0 +1,139 @@
+package ynab
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TransactionFilterType restricts a transaction list to transactions of a
+// given type.
+type TransactionFilterType string
+
+const (
+	// TransactionFilterUncategorized returns only transactions without a
+	// category.
+	TransactionFilterUncategorized TransactionFilterType = "uncategorized"
+	// TransactionFilterUnapproved returns only transactions that have not
+	// been approved.
+	TransactionFilterUnapproved TransactionFilterType = "unapproved"
+)
+
+// ListOptions are the query parameters accepted by list endpoints that support
+// delta requests, e.g. accounts, categories, payees and months.
+type ListOptions struct {
+	// If not zero, only entities that have changed since this server
+	// knowledge are returned.
+	LastKnowledgeOfServer int64
+}
+
+// Values returns the options as query parameters. A nil *ListOptions returns
+// empty Values.
+func (o *ListOptions) Values() url.Values {
+	data := url.Values{}
+	if o == nil {
+		return data
+	}
+	if o.LastKnowledgeOfServer > 0 {
+		data.Set("last_knowledge_of_server", strconv.FormatInt(o.LastKnowledgeOfServer, 10))
+	}
+	return data
+}
+
+// TransactionListOptions are the query parameters accepted by the transaction
+// list endpoints.
+type TransactionListOptions struct {
+	// If not zero, only transactions on or after this date are returned.
+	SinceDate Date
+	// If not empty, only transactions of this type are returned.
+	Type TransactionFilterType
+	// If not zero, only transactions that have changed since this server
+	// knowledge are returned.
+	LastKnowledgeOfServer int64
+}
+
+// Values returns the options as query parameters. A nil
+// *TransactionListOptions returns empty Values.
+func (o *TransactionListOptions) Values() url.Values {
+	data := url.Values{}
+	if o == nil {
+		return data
+	}
+	if !time.Time(o.SinceDate).IsZero() {
+		data.Set("since_date", o.SinceDate.String())
+	}
+	if o.Type != "" {
+		data.Set("type", string(o.Type))
+	}
+	if o.LastKnowledgeOfServer > 0 {
+		data.Set("last_knowledge_of_server", strconv.FormatInt(o.LastKnowledgeOfServer, 10))
+	}
+	return data
+}
+
+// ListAccounts returns the accounts in this plan. opts may be nil.
+func (b *PlanService) ListAccounts(ctx context.Context, opts *ListOptions) (*AccountListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListAccounts")
+	return b.Accounts(ctx, opts.Values())
+}
+
+// ListCategories returns the category groups and categories in this plan.
+// opts may be nil.
+func (b *PlanService) ListCategories(ctx context.Context, opts *ListOptions) (*CategoryListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListCategories")
+	return b.Categories(ctx, opts.Values())
+}
+
+// ListPayees returns the payees in this plan. opts may be nil.
+func (b *PlanService) ListPayees(ctx context.Context, opts *ListOptions) (*PayeeListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListPayees")
+	return b.Payees(ctx, opts.Values())
+}
+
+// ListMonths returns the months in this plan. opts may be nil.
+func (b *PlanService) ListMonths(ctx context.Context, opts *ListOptions) (*MonthSummaryListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListMonths")
+	return b.Months(ctx, opts.Values())
+}
+
+// ListScheduledTransactions returns the scheduled transactions in this plan.
+// opts may be nil.
+func (b *PlanService) ListScheduledTransactions(ctx context.Context, opts *ListOptions) (*ScheduledTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListScheduledTransactions")
+	return b.ScheduledTransactions(ctx, opts.Values())
+}
+
+// ListTransactions returns the transactions in this plan. opts may be nil.
+func (b *PlanService) ListTransactions(ctx context.Context, opts *TransactionListOptions) (*TransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListTransactions")
+	return b.Transactions(ctx, opts.Values())
+}
+
+// ListAccountTransactions returns the transactions for a specific account.
+// opts may be nil.
+func (b *PlanService) ListAccountTransactions(ctx context.Context, accountID string, opts *TransactionListOptions) (*TransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListAccountTransactions")
+	return b.AccountTransactions(ctx, accountID, opts.Values())
+}
+
+// ListCategoryTransactions returns the transactions for a specific category.
+// opts may be nil.
+func (b *PlanService) ListCategoryTransactions(ctx context.Context, categoryID string, opts *TransactionListOptions) (*HybridTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListCategoryTransactions")
+	return b.CategoryTransactions(ctx, categoryID, opts.Values())
+}
+
+// ListPayeeTransactions returns the transactions for a specific payee. opts
+// may be nil.
+func (b *PlanService) ListPayeeTransactions(ctx context.Context, payeeID string, opts *TransactionListOptions) (*HybridTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListPayeeTransactions")
+	return b.PayeeTransactions(ctx, payeeID, opts.Values())
+}
+
+// ListMonthTransactions returns the transactions for a specific month, in ISO
+// format (e.g. "2024-01-01") or "current". opts may be nil.
+func (b *PlanService) ListMonthTransactions(ctx context.Context, month string, opts *TransactionListOptions) (*HybridTransactionListResponse, error) {
+	ctx = withOperation(ctx, "PlanService.ListMonthTransactions")
+	return b.MonthTransactions(ctx, month, opts.Values())
+}
@@ -0,0 +1,90 @@
+package ynab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTransactionListOptionsValues(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *TransactionListOptions
+		want string
+	}{
+		{"Nil", nil, ""},
+		{"Empty", &TransactionListOptions{}, ""},
+		{"All", &TransactionListOptions{
+			SinceDate:             Date(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)),
+			Type:                  TransactionFilterUnapproved,
+			LastKnowledgeOfServer: 55,
+		}, "last_knowledge_of_server=55&since_date=2024-03-01&type=unapproved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Values().Encode(); got != tt.want {
+				t.Errorf("Values() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCategoryTransactionsSendsOptions(t *testing.T) {
+	var receivedPath string
+	var receivedQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		receivedQuery = r.URL.Query()
+		w.Write([]byte(`{"data": {"transactions": [], "server_knowledge": 1}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+
+	_, err := client.Plans("plan-id").ListCategoryTransactions(context.Background(), "cat-id", &TransactionListOptions{
+		SinceDate: Date(time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)),
+		Type:      TransactionFilterUncategorized,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receivedPath != "/plans/plan-id/categories/cat-id/transactions" {
+		t.Errorf("unexpected path %s", receivedPath)
+	}
+	if got := receivedQuery.Get("since_date"); got != "2024-01-15" {
+		t.Errorf("expected since_date=2024-01-15, got %q", got)
+	}
+	if got := receivedQuery.Get("type"); got != "uncategorized" {
+		t.Errorf("expected type=uncategorized, got %q", got)
+	}
+	if receivedQuery.Has("last_knowledge_of_server") {
+		t.Errorf("expected no last_knowledge_of_server, got %q", receivedQuery.Get("last_knowledge_of_server"))
+	}
+}
+
+func TestListPayeesNilOptions(t *testing.T) {
+	var rawQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+		w.Write([]byte(`{"data": {"payees": [{"id": "payee-1", "name": "Grocer"}], "server_knowledge": 3}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+
+	resp, err := client.Plans("plan-id").ListPayees(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawQuery != "" {
+		t.Errorf("expected empty query, got %q", rawQuery)
+	}
+	if len(resp.Data.Payees) != 1 {
+		t.Errorf("expected 1 payee, got %d", len(resp.Data.Payees))
+	}
+}
@@ -0,0 +1,263 @@
+package ynab
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// redacted replaces the value of the Authorization header in recordings.
+const redacted = "REDACTED"
+
+// An Interaction is a request and the response it received, as written by a
+// Recorder. Each line of a recording is one Interaction encoded as JSON.
+type Interaction struct {
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	// Error is the error returned by the transport, e.g. a timeout, if the
+	// request did not get a response.
+	Error string `json:"error,omitempty"`
+}
+
+// RecordedRequest is the part of an HTTP request saved in a recording.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the part of an HTTP response saved in a recording.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// A Recorder is an http.RoundTripper that sends requests with Transport and
+// writes each request and response to a JSONL recording, which a Replayer can
+// serve back later. The bearer token in the Authorization header is replaced
+// with "REDACTED"; other headers and bodies are saved as they are, so treat
+// recordings as containing financial data.
+//
+// Install a Recorder with Client.SetTransport:
+//
+//	f, err := os.Create("sync.jsonl")
+//	client.SetTransport(ynab.NewRecorder(f, nil))
+//
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	// Transport sends the requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewRecorder returns a Recorder that writes to w and sends requests with
+// transport, or http.DefaultTransport if transport is nil.
+func NewRecorder(w io.Writer, transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport, w: w}
+}
+
+// RoundTrip implements http.RoundTripper. If the recording can't be written,
+// RoundTrip still returns the response; call Err to check.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := Interaction{Request: RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: redactHeader(req.Header),
+	}}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		rec.Request.Body = string(body)
+		// RoundTrippers must not modify the request, so send a copy.
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+		r.write(&rec)
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		rec.Error = err.Error()
+		r.write(&rec)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	rec.Response = &RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       string(body),
+	}
+	r.write(&rec)
+	return resp, nil
+}
+
+func (r *Recorder) write(rec *Interaction) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		// http.Header and strings always encode
+		panic(err)
+	}
+	line = append(line, '\n')
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	_, r.err = r.w.Write(line)
+}
+
+// Err returns the first error encountered writing the recording, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	if h.Get("Authorization") == "" {
+		return h
+	}
+	scheme, _, ok := strings.Cut(h.Get("Authorization"), " ")
+	if ok {
+		h.Set("Authorization", scheme+" "+redacted)
+	} else {
+		h.Set("Authorization", redacted)
+	}
+	return h
+}
+
+// ErrNoRecording is returned by a Replayer when a request does not match any
+// unused interaction in the recording.
+var ErrNoRecording = errors.New("ynab: no recorded response for request")
+
+// A Replayer is an http.RoundTripper that answers requests from a recording
+// made by a Recorder, without using the network. Install it with
+// Client.SetTransport.
+//
+// A request matches an interaction with the same method, path and query
+// string. The scheme and host are ignored, so the replaying client may use a
+// different Base as long as the path prefix is the same.
+// Each interaction is served once, in the order it was recorded, so a
+// request that was retried gets the same sequence of responses as it did
+// when it was recorded. Request bodies are not compared.
+//
+// A Replayer is safe for concurrent use.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewReplayer reads a JSONL recording from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	rp := new(Replayer)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		rec := new(Interaction)
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("ynab: invalid recording on line %d: %w", line, err)
+		}
+		if rec.Response == nil && rec.Error == "" {
+			return nil, fmt.Errorf("ynab: invalid recording on line %d: no response or error", line)
+		}
+		rp.interactions = append(rp.interactions, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	rp.used = make([]bool, len(rp.interactions))
+	return rp, nil
+}
+
+// RoundTrip implements http.RoundTripper. It returns an error wrapping
+// ErrNoRecording if no unused interaction matches req.
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	rec := rp.next(req)
+	if rec == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoRecording, req.Method, req.URL.RequestURI())
+	}
+	if rec.Response == nil {
+		return nil, errors.New(rec.Error)
+	}
+	header := rec.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Response.StatusCode, http.StatusText(rec.Response.StatusCode)),
+		StatusCode:    rec.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(rec.Response.Body)),
+		ContentLength: int64(len(rec.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next returns the first unused interaction that matches req and marks it
+// used.
+func (rp *Replayer) next(req *http.Request) *Interaction {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	want := req.URL.RequestURI()
+	for i, rec := range rp.interactions {
+		if rp.used[i] || rec.Request.Method != req.Method {
+			continue
+		}
+		u, err := req.URL.Parse(rec.Request.URL)
+		if err != nil || u.RequestURI() != want {
+			continue
+		}
+		rp.used[i] = true
+		return rec
+	}
+	return nil
+}
+
+// Unused returns the interactions that have not been served yet. A test can
+// check that it is empty to make sure the code under test made every request
+// in the recording.
+func (rp *Replayer) Unused() []*Interaction {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	var out []*Interaction
+	for i, rec := range rp.interactions {
+		if !rp.used[i] {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
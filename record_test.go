@@ -0,0 +1,99 @@
+package ynab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(rateLimitedBody)
+			return
+		}
+		w.Write([]byte(`{"data": {"transactions": [{"id": "txn-1", "amount": -12340}], "server_knowledge": 42}}`))
+	}))
+	defer server.Close()
+
+	retry := &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf, nil)
+	client := NewClient("secret-token")
+	client.Base = server.URL
+	client.SetRetryPolicy(retry)
+	client.SetTransport(rec)
+
+	ctx := context.Background()
+	opts := &TransactionListOptions{LastKnowledgeOfServer: 10}
+	if _, err := client.Plans("plan-id").ListTransactions(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret-token") {
+		t.Errorf("recording contains the access token:\n%s", buf.String())
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("expected 2 interactions, got %d", n)
+	}
+
+	rp, err := NewReplayer(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewClient("other-token")
+	replay.Base = "http://ynab.invalid"
+	replay.SetRetryPolicy(retry)
+	replay.SetTransport(rp)
+	resp, err := replay.Plans("plan-id").ListTransactions(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Transactions) != 1 || resp.Data.Transactions[0].Amount != -12340 {
+		t.Errorf("unexpected transactions: %+v", resp.Data.Transactions)
+	}
+	if resp.Data.ServerKnowledge != 42 {
+		t.Errorf("expected server knowledge 42, got %d", resp.Data.ServerKnowledge)
+	}
+	if unused := rp.Unused(); len(unused) != 0 {
+		t.Errorf("expected every interaction to be used, got %d unused", len(unused))
+	}
+
+	_, err = replay.Plans("plan-id").ListTransactions(ctx, opts)
+	if !errors.Is(err, ErrNoRecording) {
+		t.Errorf("expected ErrNoRecording, got %v", err)
+	}
+}
+
+func TestReplayerErrors(t *testing.T) {
+	recording := `{"request":{"method":"GET","url":"https://api.ynab.com/v1/user"},"error":"connection reset by peer"}
+{"request":{"method":"GET","url":"https://api.ynab.com/v1/user"},"response":{"status_code":404,"body":"{\"error\":{\"id\":\"404.2\",\"name\":\"resource_not_found\",\"detail\":\"Not found\"}}"}}
+`
+	rp, err := NewReplayer(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient("token")
+	client.SetTransport(rp)
+	ctx := context.Background()
+	if _, err := client.GetUser(ctx); err == nil || !strings.Contains(err.Error(), "connection reset by peer") {
+		t.Errorf("expected the recorded transport error, got %v", err)
+	}
+	if _, err := client.GetUser(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if _, err := NewReplayer(strings.NewReader("{\"request\":{}}\n")); err == nil {
+		t.Error("expected an error for an interaction without a response")
+	}
+}
@@ -0,0 +1,157 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResolvePlan returns the plan that nameOrID refers to, which can be:
+//
+//   - a plan ID
+//   - a plan's exact name, or a case-insensitive prefix of exactly one name
+//   - "default", the default plan, which YNAB only reports to OAuth
+//     applications that have default plan selection enabled
+//   - "last-used", the plan that was used most recently
+//   - "", if there is only one plan
+//
+// YNAB resolves "default" and "last-used" itself, which costs a full plan
+// export. If nameOrID doesn't match exactly one plan, the error is a
+// *PlanError listing the candidates.
+func (c *Client) ResolvePlan(ctx context.Context, nameOrID string) (*Plan, error) {
+	if nameOrID == "default" || nameOrID == "last-used" {
+		resp, err := c.Plans(nameOrID).GetPlan(ctx)
+		if errors.Is(err, ErrNotFound) {
+			return nil, &PlanError{Query: nameOrID}
+		}
+		if err != nil {
+			return nil, err
+		}
+		p := resp.Data.Plan
+		return &Plan{
+			ID:             p.ID,
+			Name:           p.Name,
+			LastModifiedOn: p.LastModifiedOn,
+			FirstMonth:     p.FirstMonth,
+			LastMonth:      p.LastMonth,
+			DateFormat:     p.DateFormat,
+			CurrencyFormat: p.CurrencyFormat,
+		}, nil
+	}
+	resp, err := c.GetPlans(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return FindPlan(resp.Data.Plans, resp.Data.DefaultPlan, nameOrID)
+}
+
+// FindPlan picks a plan from plans the same way ResolvePlan does, e.g. for
+// plans loaded from a store. defaultPlan may be nil.
+//
+// FindPlan can't ask YNAB, so "last-used" is only approximated by the plan
+// with the latest LastModifiedOn, and "default" only works if defaultPlan is
+// known.
+func FindPlan(plans []*Plan, defaultPlan *Plan, nameOrID string) (*Plan, error) {
+	switch nameOrID {
+	case "":
+		if len(plans) == 1 {
+			return plans[0], nil
+		}
+		return nil, &PlanError{Query: nameOrID, Candidates: plans, Ambiguous: len(plans) > 1}
+	case "default":
+		if defaultPlan == nil {
+			return nil, &PlanError{Query: nameOrID, Candidates: plans}
+		}
+		return defaultPlan, nil
+	case "last-used":
+		var last *Plan
+		var lastModified time.Time
+		for _, p := range plans {
+			t, err := time.Parse(time.RFC3339, p.LastModifiedOn)
+			if err == nil && (last == nil || t.After(lastModified)) {
+				last, lastModified = p, t
+			}
+		}
+		if last == nil {
+			return nil, &PlanError{Query: nameOrID, Candidates: plans}
+		}
+		return last, nil
+	}
+	for _, p := range plans {
+		if p.ID == nameOrID {
+			return p, nil
+		}
+	}
+	// Try the strictest match first, so that e.g. "Personal" picks the plan
+	// named "Personal" and not "Personal 2019".
+	matchers := []func(*Plan) bool{
+		func(p *Plan) bool { return p.Name == nameOrID },
+		func(p *Plan) bool { return strings.EqualFold(p.Name, nameOrID) },
+		func(p *Plan) bool {
+			return strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(nameOrID))
+		},
+	}
+	for _, match := range matchers {
+		var found []*Plan
+		for _, p := range plans {
+			if match(p) {
+				found = append(found, p)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			return nil, &PlanError{Query: nameOrID, Candidates: found, Ambiguous: true}
+		}
+	}
+	return nil, &PlanError{Query: nameOrID, Candidates: plans}
+}
+
+// A PlanError is returned by ResolvePlan and FindPlan when a name or ID
+// doesn't match exactly one plan. If no plan matched, it wraps ErrNotFound.
+type PlanError struct {
+	// Query is the name or ID that was looked up.
+	Query string
+	// Candidates are the plans that matched if the query was ambiguous, or
+	// every plan otherwise.
+	Candidates []*Plan
+	Ambiguous  bool
+}
+
+func (e *PlanError) Error() string {
+	var b strings.Builder
+	switch {
+	case e.Ambiguous && e.Query == "":
+		b.WriteString("ynab: there is more than one plan, choose one of:")
+	case e.Ambiguous:
+		fmt.Fprintf(&b, "ynab: %q matches more than one plan, choose one of:", e.Query)
+	case e.Query == "":
+		return "ynab: no plans found"
+	case e.Query == "default":
+		b.WriteString("ynab: no default plan is set")
+	default:
+		fmt.Fprintf(&b, "ynab: no plan matches %q", e.Query)
+	}
+	if len(e.Candidates) == 0 {
+		return b.String()
+	}
+	if !e.Ambiguous {
+		b.WriteString(", the plans are:")
+	}
+	for _, p := range e.Candidates {
+		fmt.Fprintf(&b, "\n\t%s (%s)", p.Name, p.ID)
+	}
+	return b.String()
+}
+
+func (e *PlanError) Unwrap() error {
+	if e.Ambiguous {
+		return nil
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,108 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFindPlan(t *testing.T) {
+	plans := []*Plan{
+		{ID: "id-personal", Name: "Personal", LastModifiedOn: "2024-03-01T10:00:00+00:00"},
+		{ID: "id-personal-2019", Name: "Personal 2019", LastModifiedOn: "2019-12-31T10:00:00+00:00"},
+		{ID: "id-business", Name: "Business", LastModifiedOn: "2024-03-02T09:00:00+00:00"},
+		{ID: "id-shared", Name: "shared", LastModifiedOn: "2023-01-01T00:00:00+00:00"},
+		// The Ohm sign is three bytes long, its lower case only two.
+		{ID: "id-ohm", Name: "\u2126 Savings", LastModifiedOn: "2022-01-01T00:00:00+00:00"},
+	}
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"id-shared", "id-shared"},
+		{"Personal", "id-personal"},
+		{"personal 2019", "id-personal-2019"},
+		{"busi", "id-business"},
+		{"SHA", "id-shared"},
+		{"ω sav", "id-ohm"},
+		{"default", "id-business"},
+		{"last-used", "id-business"},
+	} {
+		p, err := FindPlan(plans, plans[2], tc.query)
+		if err != nil {
+			t.Errorf("%q: %v", tc.query, err)
+			continue
+		}
+		if p.ID != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.query, tc.want, p.ID)
+		}
+	}
+
+	for _, tc := range []struct {
+		query     string
+		ambiguous bool
+		want      []string
+	}{
+		{"pers", true, []string{"id-personal", "id-personal-2019"}},
+		{"", true, []string{"id-personal", "id-personal-2019", "id-business", "id-shared"}},
+		{"Household", false, []string{"id-personal", "id-personal-2019", "id-business", "id-shared"}},
+	} {
+		_, err := FindPlan(plans, nil, tc.query)
+		var planErr *PlanError
+		if !errors.As(err, &planErr) {
+			t.Fatalf("%q: expected a *PlanError, got %v", tc.query, err)
+		}
+		if planErr.Ambiguous != tc.ambiguous {
+			t.Errorf("%q: expected Ambiguous = %t", tc.query, tc.ambiguous)
+		}
+		if errors.Is(err, ErrNotFound) == tc.ambiguous {
+			t.Errorf("%q: expected errors.Is(err, ErrNotFound) = %t", tc.query, !tc.ambiguous)
+		}
+		for _, id := range tc.want {
+			if !strings.Contains(err.Error(), id) {
+				t.Errorf("%q: expected the error to list %s, got %q", tc.query, id, err)
+			}
+		}
+	}
+
+	if _, err := FindPlan(plans, nil, "default"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound without a default plan, got %v", err)
+	}
+	if p, err := FindPlan(plans[:1], nil, ""); err != nil || p.ID != "id-personal" {
+		t.Errorf("expected the only plan, got %v, %v", p, err)
+	}
+}
+
+func TestResolvePlan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plans":
+			w.Write([]byte(`{"data": {"plans": [{"id": "plan-1", "name": "Personal"}, {"id": "plan-2", "name": "Business"}], "default_plan": {"id": "plan-2", "name": "Business"}}}`))
+		case "/plans/last-used":
+			w.Write([]byte(`{"data": {"plan": {"id": "plan-1", "name": "Personal"}, "server_knowledge": 1}}`))
+		case "/plans/default":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"id": "404.2", "name": "resource_not_found", "detail": "Resource not found"}}`))
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL))
+	if _, err := client.ResolvePlan(context.Background(), "default"); !errors.Is(err, ErrNotFound) || err.Error() != "ynab: no default plan is set" {
+		t.Errorf("expected no default plan, got %v", err)
+	}
+	for query, want := range map[string]string{"pers": "plan-1", "last-used": "plan-1"} {
+		p, err := client.ResolvePlan(context.Background(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != want {
+			t.Errorf("%q: expected %s, got %s", query, want, p.ID)
+		}
+	}
+}
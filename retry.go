@@ -0,0 +1,283 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimit is the number of requests YNAB allows per access token per
+// hour.
+const DefaultRateLimit = 200
+
+// RetryPolicy configures automatic retries for failed requests. Requests that
+// fail with 429 Too Many Requests are retried for every method, since YNAB did
+// not process them. Server errors (5xx) and network errors are only retried
+// for idempotent methods (GET, HEAD, PUT, DELETE and OPTIONS). Errors that
+// match ErrUnauthorized are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times a request is sent, including
+	// the first. Values less than 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. Each retry after that
+	// doubles the delay, up to MaxBackoff. A random jitter of up to half the
+	// delay is subtracted so concurrent clients do not retry in lockstep.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable policy for batch jobs.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  time.Minute,
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d - rand.N(d/2+1)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a request that failed with err may be sent
+// again.
+func shouldRetry(method string, err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	if !isIdempotent(method) {
+		return false
+	}
+	if errors.Is(err, ErrServer) {
+		return true
+	}
+	// A request that isn't authorized, like one whose OAuth token couldn't
+	// be refreshed, fails the same way every time.
+	if errors.Is(err, ErrUnauthorized) {
+		return false
+	}
+	var uerr *url.Error
+	return errors.As(err, &uerr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// parseRetryAfter parses a Retry-After header, which may be a number of
+// seconds or an HTTP date.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Do sends an HTTP request and decodes the response into v. If a RateLimiter
+// is configured, Do waits for a token before sending. If a RetryPolicy is
+// configured, failed requests are retried according to the policy.
+func (c *Client) Do(r *http.Request, v any) error {
+	return c.send(r, func(req *http.Request) error {
+		return c.Client.Do(req, v)
+	})
+}
+
+// send calls do with r, waiting for the rate limiter first unless the
+// response is cached, and retries with a copy of r according to the retry
+// policy. If instrumentation is configured, the whole call is reported as one
+// operation.
+func (c *Client) send(r *http.Request, do func(*http.Request) error) error {
+	if c.instrumentation != nil {
+		return c.instrumented(r, func(r *http.Request) error {
+			return c.retrying(r, do)
+		})
+	}
+	return c.retrying(r, do)
+}
+
+func (c *Client) retrying(r *http.Request, do func(*http.Request) error) error {
+	ctx := r.Context()
+	attempts := 1
+	if c.retry != nil && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		// With a cache, the cache middleware waits instead, once it knows
+		// the response isn't cached.
+		if c.cache == nil {
+			if werr := c.waitForLimiter(ctx); werr != nil {
+				return werr
+			}
+		}
+		req := r
+		if attempt > 1 {
+			req = r.Clone(ctx)
+			if r.GetBody != nil {
+				body, berr := r.GetBody()
+				if berr != nil {
+					return berr
+				}
+				req.Body = body
+			}
+		}
+		err = do(req)
+		if err == nil || attempt >= attempts || !shouldRetry(r.Method, err) {
+			return err
+		}
+		if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+			// can't replay the request body
+			return err
+		}
+		wait := c.retry.backoff(attempt)
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		if serr := sleep(ctx, wait); serr != nil {
+			return err
+		}
+	}
+}
+
+// SetRetryPolicy configures automatic retries for requests made by the
+// client. Pass nil to disable retries, which is the default.
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.retry = policy
+}
+
+// SetRateLimiter configures a client-side rate limiter. Every request waits for
+// a token from l before it is sent. Pass nil to disable rate limiting, which
+// is the default.
+func (c *Client) SetRateLimiter(l *RateLimiter) {
+	c.limiter = l
+}
+
+// waitForLimiter waits for a token from the client's rate limiter, if it has
+// one.
+func (c *Client) waitForLimiter(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.Wait(ctx)
+}
+
+// A RateLimiter is a token bucket that tracks a request budget, e.g. YNAB's
+// 200 requests per hour. The bucket starts full and refills continuously. A
+// RateLimiter is safe for concurrent use and may be shared by several Clients
+// that use the same access token.
+type RateLimiter struct {
+	limit int
+	per   time.Duration
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows limit requests every per.
+// Use NewRateLimiter(DefaultRateLimit, time.Hour) to match YNAB's limit. Both
+// limit and per must be positive.
+func NewRateLimiter(limit int, per time.Duration) (*RateLimiter, error) {
+	if limit <= 0 {
+		return nil, &Error{Message: fmt.Sprintf("ynab: rate limit must be positive, got %d", limit)}
+	}
+	if per <= 0 {
+		return nil, &Error{Message: fmt.Sprintf("ynab: rate limit period must be positive, got %v", per)}
+	}
+	return &RateLimiter{
+		limit:  limit,
+		per:    per,
+		now:    time.Now,
+		tokens: float64(limit),
+	}, nil
+}
+
+// refill adds tokens for the time elapsed since the last call. l.mu must be
+// held.
+func (l *RateLimiter) refill() time.Time {
+	now := l.now()
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last)
+		l.tokens += float64(elapsed) / float64(l.per) * float64(l.limit)
+		if l.tokens > float64(l.limit) {
+			l.tokens = float64(l.limit)
+		}
+	}
+	l.last = now
+	return now
+}
+
+// Remaining returns the number of requests that can be made right now without
+// waiting.
+func (l *RateLimiter) Remaining() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	return int(l.tokens)
+}
+
+// Limit returns the number of requests allowed per period.
+func (l *RateLimiter) Limit() int {
+	return l.limit
+}
+
+// Wait blocks until a request may be made, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		l.refill()
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / float64(l.limit) * float64(l.per))
+		l.mu.Unlock()
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var rateLimitedBody = []byte(`{"error": {"id": "429", "name": "too_many_requests", "detail": "Too many requests"}}`)
+
+func TestRetryOnRateLimit(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(rateLimitedBody)
+			return
+		}
+		w.Write([]byte(`{"data": {"user": {"id": "user-123"}}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+
+	resp, err := client.GetUser(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.User.ID != "user-123" {
+		t.Errorf("expected user-123, got %q", resp.Data.User.ID)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write(rateLimitedBody)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+
+	_, err := client.GetUser(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestRetryReplaysBodyOnRateLimit(t *testing.T) {
+	var hits atomic.Int32
+	var lastBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		lastBody = string(b)
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(rateLimitedBody)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data": {"transaction_ids": ["txn-1"], "server_knowledge": 1}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+
+	_, err := client.Plans("plan-id").CreateTransaction(context.Background(), &CreateTransactionRequest{
+		Transaction: &NewTransaction{AccountID: "acct", Amount: -1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+	if lastBody == "" {
+		t.Error("expected request body to be replayed on retry")
+	}
+}
+
+func TestNoRetryOnServerErrorForPost(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": {"id": "500", "name": "internal_server_error", "detail": "Internal Server Error"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond})
+
+	_, err := client.Plans("plan-id").CreateTransaction(context.Background(), &CreateTransactionRequest{
+		Transaction: &NewTransaction{AccountID: "acct"},
+	})
+	if !errors.Is(err, ErrServer) {
+		t.Fatalf("expected ErrServer, got %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("expected POST not to be retried, got %d requests", n)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	for method, want := range map[string]bool{
+		"GET": true, "HEAD": true, "PUT": true, "DELETE": true, "OPTIONS": true,
+		"POST": false, "PATCH": false,
+	} {
+		if got := isIdempotent(method); got != want {
+			t.Errorf("isIdempotent(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("120", now); d != 2*time.Minute {
+		t.Errorf("expected 2m, got %v", d)
+	}
+	if d := parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now); d != 30*time.Second {
+		t.Errorf("expected 30s, got %v", d)
+	}
+	if d := parseRetryAfter("garbage", now); d != 0 {
+		t.Errorf("expected 0, got %v", d)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l, err := NewRateLimiter(200, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for range 200 {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := l.Remaining(); r != 0 {
+		t.Errorf("expected 0 remaining, got %d", r)
+	}
+	// 200 per hour is one token every 18 seconds.
+	now = now.Add(18 * time.Second)
+	if r := l.Remaining(); r != 1 {
+		t.Errorf("expected 1 remaining, got %d", r)
+	}
+	now = now.Add(2 * time.Hour)
+	if r := l.Remaining(); r != 200 {
+		t.Errorf("expected bucket to be capped at 200, got %d", r)
+	}
+}
+
+func TestNewRateLimiterRejectsBadInput(t *testing.T) {
+	for _, tt := range []struct {
+		limit int
+		per   time.Duration
+	}{
+		{0, time.Hour},
+		{-1, time.Hour},
+		{200, 0},
+	} {
+		l, err := NewRateLimiter(tt.limit, tt.per)
+		var yerr *Error
+		if l != nil || !errors.As(err, &yerr) {
+			t.Errorf("NewRateLimiter(%d, %v): expected an *Error, got %v, %v", tt.limit, tt.per, l, err)
+		}
+	}
+}
+
+func TestRateLimiterWaitHonorsContext(t *testing.T) {
+	l, err := NewRateLimiter(1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
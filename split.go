@@ -0,0 +1,211 @@
+package ynab
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/kevinburke/go-types"
+)
+
+// percentScale is the precision of split percentages: four digits after the
+// decimal point.
+const percentScale = 10000
+
+// A SplitBuilder builds a split NewTransaction from its total and the parts it
+// is split into, making sure the subtransactions have the right sign and add
+// up to the total:
+//
+//	b := ynab.NewSplitBuilder(&ynab.NewTransaction{
+//		AccountID: checkingID,
+//		Date:      date,
+//		Amount:    -120000, // a $120.00 outflow
+//		PayeeName: types.NullString{Valid: true, String: "Costco"},
+//	})
+//	b.Add(householdID, 20000, "paper towels")
+//	b.AddPercent(groceriesID, 75, "")
+//	b.AddPercent(diningID, 25, "")
+//	txn, err := b.Build()
+//
+// Part amounts are given in the direction of the total, so the parts of an
+// outflow are positive; a negative part goes the other way, e.g. cash back on
+// a purchase.
+type SplitBuilder struct {
+	txn NewTransaction
+	// DecimalDigits is the precision percentage parts are rounded to, e.g.
+	// 2 for cents. NewSplitBuilder sets it to 2.
+	DecimalDigits int
+	parts         []splitPart
+}
+
+type splitPart struct {
+	categoryID string
+	memo       string
+	amount     int64
+	// weight is the part's percentage times percentScale, or 0 for a fixed
+	// amount.
+	weight int64
+}
+
+// NewSplitBuilder returns a SplitBuilder for a transaction like txn, which is
+// copied. txn.Amount is the total; its CategoryID and Subtransactions are
+// replaced by Build.
+func NewSplitBuilder(txn *NewTransaction) *SplitBuilder {
+	return &SplitBuilder{txn: *txn, DecimalDigits: 2}
+}
+
+// Add adds a part of amount in categoryID.
+func (b *SplitBuilder) Add(categoryID string, amount int64, memo string) *SplitBuilder {
+	b.parts = append(b.parts, splitPart{categoryID: categoryID, memo: memo, amount: amount})
+	return b
+}
+
+// AddPercent adds a part of percent percent in categoryID. Percentages are of
+// what the fixed amounts added with Add leave of the total, and must add up to
+// 100. Each percentage part is rounded toward zero to DecimalDigits, and the
+// last one gets what is left, so the parts always add up to the total.
+func (b *SplitBuilder) AddPercent(categoryID string, percent float64, memo string) *SplitBuilder {
+	// A weight of -1 makes Build report the bad percentage.
+	weight := int64(-1)
+	if percent > 0 && percent <= 100 {
+		weight = int64(math.Round(percent * percentScale))
+	}
+	b.parts = append(b.parts, splitPart{categoryID: categoryID, memo: memo, weight: weight})
+	return b
+}
+
+// Build returns the split transaction. It returns an error if there are fewer
+// than two parts, if the fixed amounts don't add up to the total and there are
+// no percentage parts, or if the percentages don't add up to 100.
+func (b *SplitBuilder) Build() (*NewTransaction, error) {
+	if len(b.parts) < 2 {
+		return nil, &Error{Message: fmt.Sprintf("ynab: a split needs at least two parts, got %d", len(b.parts))}
+	}
+	sign := int64(1)
+	if b.txn.Amount < 0 {
+		sign = -1
+	}
+	subs := make([]*NewSubTransaction, len(b.parts))
+	var fixed []Milliunits
+	var weights []int64
+	var percentParts []int
+	weightSum := int64(0)
+	for i, p := range b.parts {
+		if p.weight < 0 {
+			return nil, &Error{Message: fmt.Sprintf("ynab: split percentage for category %q must be more than 0 and at most 100", p.categoryID)}
+		}
+		subs[i] = &NewSubTransaction{
+			CategoryID: types.NullString{Valid: p.categoryID != "", String: p.categoryID},
+			Memo:       types.NullString{Valid: p.memo != "", String: p.memo},
+		}
+		if p.weight == 0 {
+			subs[i].Amount = sign * p.amount
+			fixed = append(fixed, Milliunits(subs[i].Amount))
+			continue
+		}
+		weights = append(weights, p.weight)
+		weightSum += p.weight
+		percentParts = append(percentParts, i)
+	}
+	fixedSum, err := Sum(fixed...)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := Milliunits(b.txn.Amount).Sub(fixedSum)
+	if err != nil {
+		return nil, err
+	}
+	if len(percentParts) == 0 {
+		if rest != 0 {
+			return nil, &Error{Message: fmt.Sprintf("ynab: split parts add up to %d, not the transaction amount %d", fixedSum, b.txn.Amount)}
+		}
+	} else {
+		if weightSum != 100*percentScale {
+			return nil, &Error{Message: fmt.Sprintf("ynab: split percentages add up to %g, not 100", float64(weightSum)/percentScale)}
+		}
+		if rest != 0 && (rest < 0) != (b.txn.Amount < 0) {
+			return nil, &Error{Message: fmt.Sprintf("ynab: fixed split parts add up to %d, more than the transaction amount %d", fixedSum, b.txn.Amount)}
+		}
+		amounts, err := rest.Allocate(weights, b.DecimalDigits)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range percentParts {
+			subs[i].Amount = int64(amounts[j])
+		}
+	}
+	txn := b.txn
+	txn.CategoryID = types.NullString{}
+	txn.Subtransactions = subs
+	return &txn, nil
+}
+
+// NewTransactionFrom returns a NewTransaction with the same account, date,
+// amount, payee, category, memo, status, flag and subtransactions as
+// existing, for recreating it with changes. The API can't change the
+// subtransactions of an existing split; see PlanService.RecreateTransaction.
+//
+// The import ID isn't copied: YNAB keeps the import IDs of deleted
+// transactions, so it would skip the new transaction as a duplicate.
+func NewTransactionFrom(existing *Transaction) *NewTransaction {
+	txn := &NewTransaction{
+		AccountID:  existing.AccountID,
+		Date:       existing.Date,
+		Amount:     existing.Amount,
+		PayeeID:    existing.PayeeID,
+		PayeeName:  types.NullString{Valid: existing.PayeeName != "", String: existing.PayeeName},
+		CategoryID: existing.CategoryID,
+		Memo:       types.NullString{Valid: existing.Memo != "", String: existing.Memo},
+		Cleared:    existing.Cleared,
+		Approved:   existing.Approved,
+		FlagColor:  existing.FlagColor,
+	}
+	for _, sub := range existing.Subtransactions {
+		if sub.Deleted {
+			continue
+		}
+		txn.Subtransactions = append(txn.Subtransactions, &NewSubTransaction{
+			Amount:     sub.Amount,
+			PayeeID:    sub.PayeeID,
+			PayeeName:  types.NullString{Valid: sub.PayeeName != "", String: sub.PayeeName},
+			CategoryID: sub.CategoryID,
+			Memo:       types.NullString{Valid: sub.Memo != "", String: sub.Memo},
+		})
+	}
+	if len(txn.Subtransactions) > 0 {
+		// The category of a split is the internal "Split" category.
+		txn.CategoryID = types.NullString{}
+	}
+	return txn
+}
+
+// RecreateTransaction replaces the transaction with ID id by txn, by deleting
+// it and creating txn; this is the only way to change the subtransactions of
+// a split. The new transaction has a new ID. Before anything is deleted, txn
+// is validated against the plan's categories, which are fetched for the
+// purpose. If creating txn fails, RecreateTransaction tries to create a copy
+// of the deleted transaction, and returns an error that says whether it
+// could.
+func (b *PlanService) RecreateTransaction(ctx context.Context, id string, txn *NewTransaction) (*CreateTransactionResponse, error) {
+	ctx = withOperation(ctx, "PlanService.RecreateTransaction")
+	categories, err := b.Categories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := txn.ValidateWith(&ValidateOptions{CategoryGroups: categories.Data.CategoryGroups}); err != nil {
+		return nil, err
+	}
+	deleted, err := b.DeleteTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.CreateTransaction(ctx, &CreateTransactionRequest{Transaction: txn})
+	if err == nil {
+		return resp, nil
+	}
+	restored, rerr := b.CreateTransaction(ctx, &CreateTransactionRequest{Transaction: NewTransactionFrom(deleted.Data.Transaction)})
+	if rerr != nil {
+		return nil, fmt.Errorf("ynab: transaction %s was deleted, but creating its replacement failed (%w) and so did restoring it: %w", id, err, rerr)
+	}
+	return nil, fmt.Errorf("ynab: creating the replacement for transaction %s failed, so it was restored as %s: %w", id, restored.Data.Transaction.ID, err)
+}
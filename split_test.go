@@ -0,0 +1,205 @@
+package ynab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/go-types"
+)
+
+func subAmounts(txn *NewTransaction) []int64 {
+	var out []int64
+	for _, sub := range txn.Subtransactions {
+		out = append(out, sub.Amount)
+	}
+	return out
+}
+
+func TestSplitBuilder(t *testing.T) {
+	base := &NewTransaction{AccountID: "checking", Date: Date(time.Now().AddDate(0, 0, -1)), Amount: -100000, CategoryID: types.NullString{Valid: true, String: "old"}}
+	tests := []struct {
+		name  string
+		build func(b *SplitBuilder)
+		total int64
+		want  []int64
+	}{
+		{"fixed", func(b *SplitBuilder) { b.Add("a", 60000, "").Add("b", 40000, "") }, -100000, []int64{-60000, -40000}},
+		{"cash back", func(b *SplitBuilder) { b.Add("a", 120000, "").Add("b", -20000, "") }, -100000, []int64{-120000, 20000}},
+		{"inflow", func(b *SplitBuilder) { b.Add("a", 60000, "").Add("b", 40000, "") }, 100000, []int64{60000, 40000}},
+		{"thirds", func(b *SplitBuilder) {
+			b.AddPercent("a", 33.33, "").AddPercent("b", 33.33, "").AddPercent("c", 33.34, "")
+		}, -10000, []int64{-3330, -3330, -3340}},
+		{"fixed then percent", func(b *SplitBuilder) {
+			b.AddPercent("a", 50, "").Add("mine", 20000, "").AddPercent("b", 50, "")
+		}, -120010, []int64{-50000, -20000, -50010}},
+		{"fixed covers the total", func(b *SplitBuilder) {
+			b.Add("a", 10000, "").AddPercent("b", 100, "")
+		}, -10000, []int64{-10000, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := *base
+			txn.Amount = tt.total
+			b := NewSplitBuilder(&txn)
+			tt.build(b)
+			got, err := b.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a := subAmounts(got); !slices.Equal(a, tt.want) {
+				t.Errorf("got amounts %v, want %v", a, tt.want)
+			}
+			if got.CategoryID.Valid || got.Amount != tt.total || got.AccountID != "checking" {
+				t.Errorf("got parent %+v", got)
+			}
+			if err := got.Validate(); err != nil {
+				t.Errorf("built split doesn't validate: %v", err)
+			}
+		})
+	}
+	// Build doesn't change the transaction passed to NewSplitBuilder.
+	if base.CategoryID.String != "old" || base.Subtransactions != nil {
+		t.Errorf("NewSplitBuilder changed its argument: %+v", base)
+	}
+}
+
+func TestSplitBuilderErrors(t *testing.T) {
+	tests := []struct {
+		build func(b *SplitBuilder)
+		want  string
+	}{
+		{func(b *SplitBuilder) { b.Add("a", 100000, "") }, "at least two parts"},
+		{func(b *SplitBuilder) { b.Add("a", 60000, "").Add("b", 30000, "") }, "split parts add up to -90000, not the transaction amount -100000"},
+		{func(b *SplitBuilder) { b.Add("a", -60000, "").Add("b", -40000, "") }, "add up to 100000"},
+		{func(b *SplitBuilder) { b.AddPercent("a", 50, "").AddPercent("b", 40, "") }, "percentages add up to 90, not 100"},
+		{func(b *SplitBuilder) { b.AddPercent("a", 150, "").AddPercent("b", -50, "") }, `percentage for category "a"`},
+		{func(b *SplitBuilder) { b.Add("a", 110000, "").AddPercent("b", 100, "") }, "more than the transaction amount"},
+	}
+	for _, tt := range tests {
+		b := NewSplitBuilder(&NewTransaction{AccountID: "checking", Amount: -100000})
+		tt.build(b)
+		if _, err := b.Build(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Build: got error %v, want %q", err, tt.want)
+		}
+	}
+}
+
+const existingSplit = `{
+	"id": "split-1", "account_id": "checking", "date": "2024-03-12", "amount": -75000,
+	"payee_id": "costco", "payee_name": "Costco", "category_id": "split-category", "category_name": "Split",
+	"memo": "weekly shop", "cleared": "cleared", "approved": true, "flag_color": "red", "import_id": "YNAB:-75000:2024-03-12:1",
+	"subtransactions": [
+		{"id": "sub-1", "amount": -50000, "category_id": "groceries", "memo": "food", "deleted": false},
+		{"id": "sub-2", "amount": -10000, "category_id": "old", "deleted": true},
+		{"id": "sub-3", "amount": -25000, "category_id": "household", "deleted": false}
+	]
+}`
+
+func TestNewTransactionFrom(t *testing.T) {
+	existing := new(Transaction)
+	if err := json.Unmarshal([]byte(existingSplit), existing); err != nil {
+		t.Fatal(err)
+	}
+	txn := NewTransactionFrom(existing)
+	if txn.AccountID != "checking" || txn.Amount != -75000 || txn.PayeeID.String != "costco" || txn.Memo.String != "weekly shop" ||
+		txn.Cleared != ClearedStatusCleared || !txn.Approved || txn.FlagColor != FlagColorRed || txn.CategoryID.Valid {
+		t.Errorf("got %+v", txn)
+	}
+	if txn.ImportID.Valid {
+		t.Errorf("expected the import ID not to be copied, got %q", txn.ImportID.String)
+	}
+	if len(txn.Subtransactions) != 2 || txn.Subtransactions[0].Memo.String != "food" || txn.Subtransactions[1].CategoryID.String != "household" {
+		t.Errorf("got subtransactions %v", subAmounts(txn))
+	}
+	if err := txn.ValidateWith(&ValidateOptions{Now: time.Date(2024, 3, 12, 0, 0, 0, 0, time.Local)}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecreateTransaction(t *testing.T) {
+	var calls []string
+	failCreate := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"data": {"category_groups": [{"id": "ccp-group", "name": "Credit Card Payments", "categories": [{"id": "visa", "category_group_id": "ccp-group", "name": "Visa"}]}], "server_knowledge": 1}}`))
+		case "DELETE":
+			w.Write([]byte(`{"data": {"transaction": ` + existingSplit + `}}`))
+		case "POST":
+			req := new(CreateTransactionRequest)
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				t.Fatal(err)
+			}
+			if req.Transaction.ImportID.Valid {
+				t.Errorf("created a transaction with the import ID %q of the deleted one", req.Transaction.ImportID.String)
+			}
+			if failCreate && req.Transaction.Memo.String != "weekly shop" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": {"id": "400", "name": "bad_request", "detail": "nope"}}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"transaction_ids": ["new-1"], "transaction": {"id": "new-1", "subtransactions": []}, "server_knowledge": 2}}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient("test-token", WithBaseURL(server.URL))
+	svc := client.Plans("plan")
+	ctx := context.Background()
+
+	existing := new(Transaction)
+	if err := json.Unmarshal([]byte(existingSplit), existing); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := NewSplitBuilder(NewTransactionFrom(existing)).
+		AddPercent("groceries", 60, "").
+		AddPercent("household", 40, "").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited.Memo = types.NullString{Valid: true, String: "edited"}
+	resp, err := svc.RecreateTransaction(ctx, "split-1", edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Transaction.ID != "new-1" {
+		t.Errorf("got %+v", resp.Data)
+	}
+	if got := strings.Join(calls, ", "); got != "GET /plans/plan/categories, DELETE /plans/plan/transactions/split-1, POST /plans/plan/transactions" {
+		t.Errorf("got calls %s", got)
+	}
+
+	calls, failCreate = nil, true
+	_, err = svc.RecreateTransaction(ctx, "split-1", edited)
+	if err == nil || !strings.Contains(err.Error(), "so it was restored as new-1") || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("got error %v", err)
+	}
+	if len(calls) != 4 {
+		t.Errorf("got calls %v", calls)
+	}
+
+	// An invalid transaction is rejected before anything is deleted, including
+	// one that only the plan's categories show is invalid.
+	calls = nil
+	edited.Subtransactions[0].CategoryID = types.NullString{Valid: true, String: "visa"}
+	var verr *ValidationError
+	if _, err := svc.RecreateTransaction(ctx, "split-1", edited); !errors.As(err, &verr) || !strings.Contains(err.Error(), "Credit Card Payment") || len(calls) != 1 {
+		t.Errorf("got error %v after calls %v", err, calls)
+	}
+	calls = nil
+	edited.Subtransactions[0].CategoryID = types.NullString{Valid: true, String: "groceries"}
+	edited.Subtransactions[0].Amount = 0
+	if _, err := svc.RecreateTransaction(ctx, "split-1", edited); !errors.As(err, &verr) || len(calls) != 1 {
+		t.Errorf("got error %v after calls %v", err, calls)
+	}
+}
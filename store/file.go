@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// FileStore saves each plan as a JSON file named <plan id>.json in a
+// directory.
+type FileStore struct {
+	dir string
+
+	mu sync.Mutex
+}
+
+// NewFileStore returns a FileStore that keeps its files in dir. The directory
+// is created on the first Save if it does not exist.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (f *FileStore) path(planID string) (string, error) {
+	if planID == "" || strings.ContainsAny(planID, `/\`) || planID == "." || planID == ".." {
+		return "", fmt.Errorf("store: invalid plan id %q", planID)
+	}
+	return filepath.Join(f.dir, planID+".json"), nil
+}
+
+// Load implements Store.
+func (f *FileStore) Load(ctx context.Context, planID string) (*ynab.PlanSnapshot, error) {
+	path, err := f.path(planID)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap := new(ynab.PlanSnapshot)
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("store: decoding %s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// Save implements Store. The file is replaced atomically, so a crash during
+// Save leaves the previous snapshot intact.
+func (f *FileStore) Save(ctx context.Context, snap *ynab.PlanSnapshot) error {
+	path, err := f.path(snap.PlanID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(f.dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.dir, "."+snap.PlanID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Plans implements Store.
+func (f *FileStore) Plans(ctx context.Context) ([]*ynab.Plan, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	matches, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]*ynab.Plan, 0, len(matches))
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		var header struct {
+			PlanID         string               `json:"plan_id"`
+			PlanName       string               `json:"plan_name"`
+			CurrencyFormat *ynab.CurrencyFormat `json:"currency_format"`
+			DateFormat     *ynab.DateFormat     `json:"date_format"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			return nil, fmt.Errorf("store: decoding %s: %w", match, err)
+		}
+		plans = append(plans, &ynab.Plan{
+			ID:             header.PlanID,
+			Name:           header.PlanName,
+			CurrencyFormat: header.CurrencyFormat,
+			DateFormat:     header.DateFormat,
+		})
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].Name < plans[j].Name
+	})
+	return plans, nil
+}
+
+var _ Store = (*FileStore)(nil)
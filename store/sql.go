@@ -0,0 +1,431 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/kevinburke/ynab-go"
+	_ "modernc.org/sqlite"
+)
+
+// SQLStore saves plans in an embedded SQLite database. OpenSQLite opens a
+// database file with the pure Go modernc.org/sqlite driver, so programs that
+// use it don't need cgo:
+//
+//	st, err := store.OpenSQLite(ctx, "ynab.db")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer st.Close()
+//	snap, err := store.Sync(ctx, st, client, plan)
+//
+// NewSQLStore uses a database the caller has already opened.
+//
+// Each entity is stored as a JSON document in the ynab_entities table, keyed
+// by plan, kind and ID. Subtransactions are stored as their own rows, with the
+// parent transaction ID in parent_id and their index in the parent in
+// position.
+//
+// SQLStore implements ChangeSaver, so Sync only writes the rows that a delta
+// request changed.
+type SQLStore struct {
+	db *sql.DB
+}
+
+const sqlSchema = `
+CREATE TABLE IF NOT EXISTS ynab_plans (
+	plan_id TEXT PRIMARY KEY,
+	plan_name TEXT NOT NULL,
+	currency_format TEXT,
+	date_format TEXT,
+	accounts_knowledge INTEGER NOT NULL,
+	categories_knowledge INTEGER NOT NULL,
+	payees_knowledge INTEGER NOT NULL,
+	months_knowledge INTEGER NOT NULL,
+	transactions_knowledge INTEGER NOT NULL,
+	scheduled_transactions_knowledge INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ynab_entities (
+	plan_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	id TEXT NOT NULL,
+	parent_id TEXT,
+	position INTEGER NOT NULL DEFAULT 0,
+	data TEXT NOT NULL,
+	PRIMARY KEY (plan_id, kind, id)
+);
+CREATE INDEX IF NOT EXISTS ynab_entities_parent ON ynab_entities (plan_id, parent_id);
+`
+
+// Entity kinds stored in the ynab_entities table.
+const (
+	kindAccount              = "account"
+	kindCategoryGroup        = "category_group"
+	kindCategory             = "category"
+	kindPayee                = "payee"
+	kindMonth                = "month"
+	kindTransaction          = "transaction"
+	kindSubtransaction       = "subtransaction"
+	kindScheduledTransaction = "scheduled_transaction"
+)
+
+// OpenSQLite opens the SQLite database at path, creating it if it does not
+// exist, and returns a SQLStore that uses it. Call Close when done.
+func OpenSQLite(ctx context.Context, path string) (*SQLStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time. With a single connection, a second
+	// Save waits for the first instead of failing with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	st, err := NewSQLStore(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// NewSQLStore creates the tables used by the store, if they do not already
+// exist, and returns a SQLStore that uses db, which must be a SQLite database.
+func NewSQLStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	if _, err := db.ExecContext(ctx, sqlSchema); err != nil {
+		return nil, fmt.Errorf("store: creating tables: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Load implements Store.
+func (s *SQLStore) Load(ctx context.Context, planID string) (*ynab.PlanSnapshot, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snap := ynab.NewPlanSnapshot(planID)
+	k := &snap.Knowledge
+	var currencyFormat, dateFormat sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT plan_name, currency_format,
+		date_format, accounts_knowledge, categories_knowledge, payees_knowledge,
+		months_knowledge, transactions_knowledge, scheduled_transactions_knowledge
+		FROM ynab_plans WHERE plan_id = ?`, planID).Scan(&snap.PlanName,
+		&currencyFormat, &dateFormat, &k.Accounts, &k.Categories, &k.Payees,
+		&k.Months, &k.Transactions, &k.ScheduledTransactions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snap.CurrencyFormat, err = decodeNullable[ynab.CurrencyFormat](currencyFormat); err != nil {
+		return nil, fmt.Errorf("store: decoding currency format: %w", err)
+	}
+	if snap.DateFormat, err = decodeNullable[ynab.DateFormat](dateFormat); err != nil {
+		return nil, fmt.Errorf("store: decoding date format: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT kind, id, parent_id, data
+		FROM ynab_entities WHERE plan_id = ? ORDER BY kind, parent_id, position, id`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subtransactions := make(map[string][]ynab.Transaction)
+	for rows.Next() {
+		var kind, id string
+		var parentID sql.NullString
+		var data []byte
+		if err := rows.Scan(&kind, &id, &parentID, &data); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case kindAccount:
+			err = decodeInto(snap.Accounts, id, data)
+		case kindCategoryGroup:
+			err = decodeInto(snap.CategoryGroups, id, data)
+		case kindCategory:
+			err = decodeInto(snap.Categories, id, data)
+		case kindPayee:
+			err = decodeInto(snap.Payees, id, data)
+		case kindMonth:
+			err = decodeInto(snap.Months, id, data)
+		case kindTransaction:
+			err = decodeInto(snap.Transactions, id, data)
+		case kindSubtransaction:
+			var sub ynab.Transaction
+			err = json.Unmarshal(data, &sub)
+			subtransactions[parentID.String] = append(subtransactions[parentID.String], sub)
+		case kindScheduledTransaction:
+			err = decodeInto(snap.ScheduledTransactions, id, data)
+		default:
+			err = fmt.Errorf("unknown entity kind %q", kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("store: decoding %s %s: %w", kind, id, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for parentID, subs := range subtransactions {
+		if txn, ok := snap.Transactions[parentID]; ok {
+			txn.Subtransactions = subs
+		}
+	}
+	return snap, nil
+}
+
+func decodeInto[T any](m map[string]*T, id string, data []byte) error {
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	m[id] = v
+	return nil
+}
+
+// encodeNullable encodes v as JSON for a nullable TEXT column.
+func encodeNullable[T any](v *T) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decodeNullable decodes a column written by encodeNullable.
+func decodeNullable[T any](data sql.NullString) (*T, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	v := new(T)
+	if err := json.Unmarshal([]byte(data.String), v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Save implements Store. The snapshot replaces every row saved for the plan,
+// in a single database transaction.
+func (s *SQLStore) Save(ctx context.Context, snap *ynab.PlanSnapshot) error {
+	return s.write(ctx, snap, func(w *entityWriter) error {
+		if _, err := w.tx.ExecContext(ctx, `DELETE FROM ynab_entities WHERE plan_id = ?`, snap.PlanID); err != nil {
+			return err
+		}
+		putAll(w, kindAccount, snap.Accounts)
+		putAll(w, kindCategoryGroup, snap.CategoryGroups)
+		putAll(w, kindCategory, snap.Categories)
+		putAll(w, kindPayee, snap.Payees)
+		putAll(w, kindMonth, snap.Months)
+		putAll(w, kindTransaction, snap.Transactions)
+		putAll(w, kindScheduledTransaction, snap.ScheduledTransactions)
+		return w.err
+	})
+}
+
+// SaveChanges implements ChangeSaver. Only the plan row and the entities in
+// changes are written, in a single database transaction.
+func (s *SQLStore) SaveChanges(ctx context.Context, snap *ynab.PlanSnapshot, changes *ynab.SnapshotChanges) error {
+	return s.write(ctx, snap, func(w *entityWriter) error {
+		putChanged(w, kindAccount, snap.Accounts, changes.Accounts)
+		putChanged(w, kindCategoryGroup, snap.CategoryGroups, changes.CategoryGroups)
+		putChanged(w, kindCategory, snap.Categories, changes.Categories)
+		putChanged(w, kindPayee, snap.Payees, changes.Payees)
+		putChanged(w, kindMonth, snap.Months, changes.Months)
+		putChanged(w, kindTransaction, snap.Transactions, changes.Transactions)
+		putChanged(w, kindScheduledTransaction, snap.ScheduledTransactions, changes.ScheduledTransactions)
+		return w.err
+	})
+}
+
+// write saves the plan row for snap and calls writeEntities, in a single
+// database transaction.
+func (s *SQLStore) write(ctx context.Context, snap *ynab.PlanSnapshot, writeEntities func(*entityWriter) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	currencyFormat, err := encodeNullable(snap.CurrencyFormat)
+	if err != nil {
+		return err
+	}
+	dateFormat, err := encodeNullable(snap.DateFormat)
+	if err != nil {
+		return err
+	}
+	k := snap.Knowledge
+	if _, err := tx.ExecContext(ctx, `INSERT INTO ynab_plans (plan_id, plan_name,
+		currency_format, date_format, accounts_knowledge, categories_knowledge,
+		payees_knowledge, months_knowledge, transactions_knowledge,
+		scheduled_transactions_knowledge)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (plan_id) DO UPDATE SET plan_name = excluded.plan_name,
+		currency_format = excluded.currency_format,
+		date_format = excluded.date_format,
+		accounts_knowledge = excluded.accounts_knowledge,
+		categories_knowledge = excluded.categories_knowledge,
+		payees_knowledge = excluded.payees_knowledge,
+		months_knowledge = excluded.months_knowledge,
+		transactions_knowledge = excluded.transactions_knowledge,
+		scheduled_transactions_knowledge = excluded.scheduled_transactions_knowledge`,
+		snap.PlanID, snap.PlanName, currencyFormat, dateFormat, k.Accounts, k.Categories, k.Payees,
+		k.Months, k.Transactions, k.ScheduledTransactions); err != nil {
+		return err
+	}
+	w := &entityWriter{ctx: ctx, tx: tx, planID: snap.PlanID}
+	if w.upsert, err = tx.PrepareContext(ctx, `INSERT INTO ynab_entities
+		(plan_id, kind, id, parent_id, position, data) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (plan_id, kind, id) DO UPDATE SET parent_id = excluded.parent_id,
+		position = excluded.position, data = excluded.data`); err != nil {
+		return err
+	}
+	defer w.upsert.Close()
+	if w.remove, err = tx.PrepareContext(ctx, `DELETE FROM ynab_entities
+		WHERE plan_id = ? AND kind = ? AND id = ?`); err != nil {
+		return err
+	}
+	defer w.remove.Close()
+	if w.removeChildren, err = tx.PrepareContext(ctx, `DELETE FROM ynab_entities
+		WHERE plan_id = ? AND parent_id = ?`); err != nil {
+		return err
+	}
+	defer w.removeChildren.Close()
+	if err := writeEntities(w); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// An entityWriter writes rows of the ynab_entities table for one plan. After
+// the first error, writes do nothing and err holds the error.
+type entityWriter struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	planID string
+	err    error
+
+	upsert, remove, removeChildren *sql.Stmt
+}
+
+// put inserts or replaces the entity id. A transaction's subtransactions
+// replace the ones saved for it.
+func (w *entityWriter) put(kind, id string, v any) {
+	if w.err == nil {
+		w.err = w.putEntity(kind, id, v)
+	}
+}
+
+func (w *entityWriter) putEntity(kind, id string, v any) error {
+	txn, ok := v.(*ynab.Transaction)
+	if !ok {
+		return w.putRow(kind, id, sql.NullString{}, 0, v)
+	}
+	parent := *txn
+	parent.Subtransactions = nil
+	if err := w.putRow(kind, id, sql.NullString{}, 0, &parent); err != nil {
+		return err
+	}
+	if _, err := w.removeChildren.ExecContext(w.ctx, w.planID, id); err != nil {
+		return err
+	}
+	for i := range txn.Subtransactions {
+		sub := &txn.Subtransactions[i]
+		if err := w.putRow(kindSubtransaction, sub.ID, sql.NullString{String: id, Valid: true}, i, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *entityWriter) putRow(kind, id string, parentID sql.NullString, position int, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.upsert.ExecContext(w.ctx, w.planID, kind, id, parentID, position, data)
+	return err
+}
+
+// delete deletes the entity id and any rows it is the parent of.
+func (w *entityWriter) delete(kind, id string) {
+	if w.err == nil {
+		w.err = w.deleteEntity(kind, id)
+	}
+}
+
+func (w *entityWriter) deleteEntity(kind, id string) error {
+	if _, err := w.remove.ExecContext(w.ctx, w.planID, kind, id); err != nil {
+		return err
+	}
+	if kind != kindTransaction {
+		return nil
+	}
+	_, err := w.removeChildren.ExecContext(w.ctx, w.planID, id)
+	return err
+}
+
+// putAll writes every entity in m.
+func putAll[T any](w *entityWriter, kind string, m map[string]*T) {
+	for id, v := range m {
+		w.put(kind, id, v)
+	}
+}
+
+// putChanged writes the entities of m that changed and deletes the ones that
+// were deleted.
+func putChanged[T any](w *entityWriter, kind string, m map[string]*T, changes map[string]bool) {
+	for id, deleted := range changes {
+		if v, ok := m[id]; ok && !deleted {
+			w.put(kind, id, v)
+		} else {
+			w.delete(kind, id)
+		}
+	}
+}
+
+// Plans implements Store.
+func (s *SQLStore) Plans(ctx context.Context) ([]*ynab.Plan, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT plan_id, plan_name, currency_format,
+		date_format FROM ynab_plans ORDER BY plan_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var plans []*ynab.Plan
+	for rows.Next() {
+		plan := new(ynab.Plan)
+		var currencyFormat, dateFormat sql.NullString
+		if err := rows.Scan(&plan.ID, &plan.Name, &currencyFormat, &dateFormat); err != nil {
+			return nil, err
+		}
+		var err error
+		if plan.CurrencyFormat, err = decodeNullable[ynab.CurrencyFormat](currencyFormat); err != nil {
+			return nil, fmt.Errorf("store: decoding currency format: %w", err)
+		}
+		if plan.DateFormat, err = decodeNullable[ynab.DateFormat](dateFormat); err != nil {
+			return nil, fmt.Errorf("store: decoding date format: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+var (
+	_ Store       = (*SQLStore)(nil)
+	_ ChangeSaver = (*SQLStore)(nil)
+)
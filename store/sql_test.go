@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+func newTestSQLStore(t *testing.T) (*SQLStore, *sql.DB) {
+	t.Helper()
+	st, err := OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "ynab.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st, st.db
+}
+
+func TestSQLStoreRoundTrip(t *testing.T) {
+	st, _ := newTestSQLStore(t)
+	testRoundTrip(t, st)
+}
+
+func TestSQLStoreSyncResumesWithDeltaRequest(t *testing.T) {
+	st, _ := newTestSQLStore(t)
+	testSyncResumes(t, st)
+}
+
+func TestSQLStoreSyncWritesOnlyChanges(t *testing.T) {
+	st, db := newTestSQLStore(t)
+	var delta bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/plans/plan-id/")
+		switch {
+		case key == "accounts" && !delta:
+			w.Write([]byte(`{"data": {"server_knowledge": 1, "accounts": [{"id": "acct-1", "name": "Checking"}]}}`))
+		case key == "transactions" && !delta:
+			w.Write([]byte(`{"data": {"server_knowledge": 1, "transactions": [
+				{"id": "txn-1", "amount": -1000, "subtransactions": [{"id": "sub-1", "amount": -400}, {"id": "sub-2", "amount": -600}]},
+				{"id": "txn-2", "amount": -2000},
+				{"id": "txn-3", "amount": -3000}
+			]}}`))
+		case key == "transactions":
+			w.Write([]byte(`{"data": {"server_knowledge": 2, "transactions": [
+				{"id": "txn-1", "amount": -1000, "deleted": true},
+				{"id": "txn-2", "amount": -2500}
+			]}}`))
+		default:
+			w.Write([]byte(`{"data": {"server_knowledge": 1, "` + key + `": []}}`))
+		}
+	}))
+	defer server.Close()
+	client := ynab.NewClient("test-token")
+	client.Base = server.URL
+	plan := &ynab.Plan{ID: "plan-id", Name: "Personal"}
+	ctx := context.Background()
+	if _, err := Sync(ctx, st, client, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows the delta doesn't mention must not be rewritten.
+	if _, err := db.ExecContext(ctx, `UPDATE ynab_entities SET data = '{"id": "acct-1", "name": "Untouched"}' WHERE id = 'acct-1'`); err != nil {
+		t.Fatal(err)
+	}
+	delta = true
+	if _, err := Sync(ctx, st, client, plan); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := st.Load(ctx, "plan-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := snap.Accounts["acct-1"]; a == nil || a.Name != "Untouched" {
+		t.Errorf("expected the account row to be left alone, got %+v", a)
+	}
+	if snap.Transactions["txn-1"] != nil || snap.Transactions["txn-2"].Amount != -2500 || snap.Transactions["txn-3"] == nil {
+		t.Errorf("expected txn-1 deleted, txn-2 updated and txn-3 kept, got %v", snap.TransactionList())
+	}
+	if snap.Knowledge.Transactions != 2 {
+		t.Errorf("expected transaction knowledge 2, got %d", snap.Knowledge.Transactions)
+	}
+	var subs int
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM ynab_entities WHERE kind = 'subtransaction'`).Scan(&subs); err != nil {
+		t.Fatal(err)
+	}
+	if subs != 0 {
+		t.Errorf("expected the deleted transaction's subtransactions to be deleted, got %d rows", subs)
+	}
+}
+
+func TestNewSQLStoreIsIdempotent(t *testing.T) {
+	st, db := newTestSQLStore(t)
+	testRoundTrip(t, st)
+	// Opening the same database again must not fail or lose data.
+	st, err := NewSQLStore(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans, err := st.Plans(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Errorf("expected the saved plan, got %+v", plans)
+	}
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	testRoundTrip(t, NewFileStore(t.TempDir()))
+}
+
+// testRoundTrip saves a snapshot to st, which must be empty, and checks that
+// it loads back unchanged.
+func testRoundTrip(t *testing.T, st Store) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := st.Load(ctx, "plan-id"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	snap := ynab.NewPlanSnapshot("plan-id")
+	snap.PlanName = "Personal"
+	snap.CurrencyFormat = &ynab.CurrencyFormat{ISOCode: "EUR", DecimalDigits: 2}
+	snap.Knowledge.Transactions = 42
+	snap.Accounts["acct-1"] = &ynab.Account{ID: "acct-1", Name: "Checking", Balance: 100000}
+	snap.Transactions["txn-1"] = &ynab.Transaction{
+		ID:     "txn-1",
+		Amount: -5000,
+		// not in ID order, which must be kept
+		Subtransactions: []ynab.Transaction{
+			{ID: "sub-b", Amount: -2000},
+			{ID: "sub-a", Amount: -3000},
+		},
+	}
+	if err := st.Save(ctx, snap); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.Load(ctx, "plan-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PlanName != "Personal" || got.Knowledge.Transactions != 42 {
+		t.Errorf("expected name and knowledge to round trip, got %q %d", got.PlanName, got.Knowledge.Transactions)
+	}
+	if a := got.Accounts["acct-1"]; a == nil || a.Balance != 100000 {
+		t.Errorf("expected account to round trip, got %+v", a)
+	}
+	if txn := got.Transactions["txn-1"]; txn == nil || len(txn.Subtransactions) != 2 {
+		t.Errorf("expected transaction with 2 subtransactions, got %+v", txn)
+	} else if txn.Subtransactions[0].ID != "sub-b" || txn.Subtransactions[1].ID != "sub-a" {
+		t.Errorf("expected subtransactions in their saved order, got %s, %s", txn.Subtransactions[0].ID, txn.Subtransactions[1].ID)
+	}
+
+	plans, err := st.Plans(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || plans[0].ID != "plan-id" || plans[0].Name != "Personal" {
+		t.Fatalf("expected one plan, got %+v", plans)
+	}
+	if cf := plans[0].CurrencyFormat; cf == nil || cf.ISOCode != "EUR" {
+		t.Errorf("expected EUR currency format, got %+v", cf)
+	}
+	if plans[0].DateFormat != nil {
+		t.Errorf("expected no date format, got %+v", plans[0].DateFormat)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := Open(ctx, filepath.Join(dir, "ynab.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlStore, ok := st.(*SQLStore)
+	if !ok {
+		t.Fatalf("expected a *SQLStore for a .db path, got %T", st)
+	}
+	sqlStore.Close()
+	if st, err = Open(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.(*FileStore); !ok {
+		t.Errorf("expected a *FileStore for a directory, got %T", st)
+	}
+}
+
+func TestFileStoreRejectsPathInPlanID(t *testing.T) {
+	st := NewFileStore(t.TempDir())
+	if err := st.Save(context.Background(), ynab.NewPlanSnapshot("../escape")); err == nil {
+		t.Fatal("expected an error for a plan id containing a path separator")
+	}
+}
+
+func TestSyncResumesWithDeltaRequest(t *testing.T) {
+	testSyncResumes(t, NewFileStore(t.TempDir()))
+}
+
+// testSyncResumes syncs a plan into st, which must be empty, and checks that a
+// second sync only makes delta requests.
+func testSyncResumes(t *testing.T, st Store) {
+	t.Helper()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		key := strings.TrimPrefix(r.URL.Path, "/plans/plan-id/")
+		w.Write([]byte(`{"data": {"server_knowledge": 7, "` + key + `": []}}`))
+	}))
+	defer server.Close()
+
+	client := ynab.NewClient("test-token")
+	client.Base = server.URL
+	plan := &ynab.Plan{ID: "plan-id", Name: "Personal"}
+	ctx := context.Background()
+
+	if _, err := Sync(ctx, st, client, plan); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range requests {
+		if strings.Contains(req, "last_knowledge_of_server") {
+			t.Errorf("expected first sync to fetch everything, got %s", req)
+		}
+	}
+
+	// A new process: nothing in memory, only what's on disk.
+	requests = nil
+	snap, err := Sync(ctx, st, client, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 6 {
+		t.Fatalf("expected 6 requests, got %d: %q", len(requests), requests)
+	}
+	for _, req := range requests {
+		if !strings.Contains(req, "last_knowledge_of_server=7") {
+			t.Errorf("expected delta request, got %s", req)
+		}
+	}
+	if snap.PlanName != "Personal" {
+		t.Errorf("expected plan name to be saved, got %q", snap.PlanName)
+	}
+}
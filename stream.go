@@ -0,0 +1,196 @@
+package ynab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// The Stream methods return the same transactions as the List methods, but
+// decode the response one transaction at a time as it arrives instead of
+// holding the entire list in memory. This matters for plans with tens of
+// thousands of transactions.
+//
+// The request is sent when iteration starts. If it fails, the iterator yields
+// a single nil transaction and the error. If the response is cut off or
+// malformed part way through, the transactions decoded so far are yielded
+// and then the error. Retries only apply before the first transaction is
+// yielded.
+//
+//	for txn, err := range client.Plans(planID).StreamTransactions(ctx, nil) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(txn.Date, txn.Amount)
+//	}
+
+// StreamTransactions returns an iterator over the transactions in this plan.
+// opts may be nil.
+func (b *PlanService) StreamTransactions(ctx context.Context, opts *TransactionListOptions) iter.Seq2[*Transaction, error] {
+	return stream[Transaction](ctx, b.client, "PlanService.StreamTransactions", "/plans/"+b.id+"/transactions", opts.Values())
+}
+
+// StreamAccountTransactions returns an iterator over the transactions for a
+// specific account. opts may be nil.
+func (b *PlanService) StreamAccountTransactions(ctx context.Context, accountID string, opts *TransactionListOptions) iter.Seq2[*Transaction, error] {
+	return stream[Transaction](ctx, b.client, "PlanService.StreamAccountTransactions", "/plans/"+b.id+"/accounts/"+accountID+"/transactions", opts.Values())
+}
+
+// StreamCategoryTransactions returns an iterator over the transactions for a
+// specific category. opts may be nil.
+func (b *PlanService) StreamCategoryTransactions(ctx context.Context, categoryID string, opts *TransactionListOptions) iter.Seq2[*HybridTransaction, error] {
+	return stream[HybridTransaction](ctx, b.client, "PlanService.StreamCategoryTransactions", "/plans/"+b.id+"/categories/"+categoryID+"/transactions", opts.Values())
+}
+
+// StreamPayeeTransactions returns an iterator over the transactions for a
+// specific payee. opts may be nil.
+func (b *PlanService) StreamPayeeTransactions(ctx context.Context, payeeID string, opts *TransactionListOptions) iter.Seq2[*HybridTransaction, error] {
+	return stream[HybridTransaction](ctx, b.client, "PlanService.StreamPayeeTransactions", "/plans/"+b.id+"/payees/"+payeeID+"/transactions", opts.Values())
+}
+
+// StreamMonthTransactions returns an iterator over the transactions for a
+// specific month, in ISO format (e.g. "2024-01-01") or "current". opts may be
+// nil.
+func (b *PlanService) StreamMonthTransactions(ctx context.Context, month string, opts *TransactionListOptions) iter.Seq2[*HybridTransaction, error] {
+	return stream[HybridTransaction](ctx, b.client, "PlanService.StreamMonthTransactions", "/plans/"+b.id+"/months/"+month+"/transactions", opts.Values())
+}
+
+// errStopped is returned by decodeTransactions when the caller stops
+// iterating.
+var errStopped = errors.New("ynab: iteration stopped")
+
+// stream sends a GET request for path and yields each element of the
+// data.transactions array in the response. op names the call for
+// instrumentation.
+func stream[T any](ctx context.Context, c *Client, op, path string, data url.Values) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		if len(data) > 0 {
+			path += "?" + data.Encode()
+		}
+		req, err := c.NewRequestWithContext(withOperation(ctx, op), "GET", path, nil)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		body, err := c.open(req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+		err = decodeTransactions(json.NewDecoder(body), func(v *T) bool {
+			return yield(v, nil)
+		})
+		if err != nil && err != errStopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// open sends r and returns the body of a successful response, which the
+// caller must close.
+func (c *Client) open(r *http.Request) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := c.send(r, func(req *http.Request) error {
+		hc := c.Client.Client
+		if hc == nil {
+			hc = http.DefaultClient
+		}
+		resp, err := hc.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return parseError(resp)
+		}
+		body = resp.Body
+		return nil
+	})
+	return body, err
+}
+
+// decodeTransactions calls yield with each element of the data.transactions
+// array in a response. Other fields are skipped. It returns errStopped if
+// yield returns false.
+func decodeTransactions[T any](dec *json.Decoder, yield func(*T) bool) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "data" {
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if key != "transactions" {
+				if err := skipValue(dec); err != nil {
+					return err
+				}
+				continue
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				continue
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("ynab: expected transactions array, got %v", tok)
+			}
+			for dec.More() {
+				v := new(T)
+				if err := dec.Decode(v); err != nil {
+					return err
+				}
+				if !yield(v) {
+					return errStopped
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if tok != d {
+		return fmt.Errorf("ynab: invalid response: expected %v, got %v", d, tok)
+	}
+	return nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
@@ -0,0 +1,141 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func transactionsBody(n int) string {
+	var b strings.Builder
+	b.WriteString(`{"data": {"server_knowledge": 7, "transactions": [`)
+	for i := range n {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id": "txn-%d", "date": "2024-01-02", "amount": %d, "subtransactions": []}`, i, -1000*(i+1))
+	}
+	b.WriteString(`]}}`)
+	return b.String()
+}
+
+func TestStreamTransactions(t *testing.T) {
+	body := transactionsBody(1000)
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	client := NewClient("test-token")
+	client.Base = server.URL
+
+	ctx := context.Background()
+	n := 0
+	for txn, err := range client.Plans("plan-id").StreamTransactions(ctx, &TransactionListOptions{Type: TransactionFilterUnapproved}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("txn-%d", n); txn.ID != want {
+			t.Fatalf("expected %s, got %s", want, txn.ID)
+		}
+		if want := int64(-1000 * (n + 1)); txn.Amount != want {
+			t.Errorf("%s: expected amount %d, got %d", txn.ID, want, txn.Amount)
+		}
+		n++
+	}
+	if n != 1000 {
+		t.Errorf("expected 1000 transactions, got %d", n)
+	}
+	if query != "type=unapproved" {
+		t.Errorf("unexpected query %q", query)
+	}
+
+	n = 0
+	for _, err := range client.Plans("plan-id").StreamAccountTransactions(ctx, "account-id", nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("expected to stop after 3 transactions, got %d", n)
+	}
+}
+
+func TestStreamHybridTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plans/plan-id/categories/cat-id/transactions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data": {"transactions": [{"id": "sub-1", "type": "subtransaction", "parent_transaction_id": "txn-1", "amount": -500}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient("test-token")
+	client.Base = server.URL
+
+	var got []*HybridTransaction
+	for txn, err := range client.Plans("plan-id").StreamCategoryTransactions(context.Background(), "cat-id", nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, txn)
+	}
+	if len(got) != 1 || got[0].ParentTransactionID.String != "txn-1" || got[0].Amount != -500 {
+		t.Errorf("unexpected transactions: %+v", got)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	full := transactionsBody(5)
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   int // transactions yielded before the error
+		err    error
+	}{
+		{"NotFound", 404, `{"error": {"id": "404.2", "name": "resource_not_found", "detail": "Not found"}}`, 0, ErrNotFound},
+		{"Truncated", 200, full[:len(full)-40], 4, nil},
+		{"NotAnArray", 200, `{"data": {"transactions": {}}}`, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			client := NewClient("test-token")
+			client.Base = server.URL
+
+			n := 0
+			var err error
+			for txn, ierr := range client.Plans("plan-id").StreamTransactions(context.Background(), nil) {
+				if ierr != nil {
+					err = ierr
+					break
+				}
+				if txn == nil {
+					t.Fatal("nil transaction without an error")
+				}
+				n++
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+			if n != tt.want {
+				t.Errorf("expected %d transactions before the error, got %d", tt.want, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package ynab
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ServerKnowledge records the last server_knowledge value seen for each
+// resource that supports delta requests. Zero means the resource has never
+// been synced.
+type ServerKnowledge struct {
+	Accounts              int64 `json:"accounts"`
+	Categories            int64 `json:"categories"`
+	Payees                int64 `json:"payees"`
+	Months                int64 `json:"months"`
+	Transactions          int64 `json:"transactions"`
+	ScheduledTransactions int64 `json:"scheduled_transactions"`
+}
+
+// A PlanSnapshot is an in-memory copy of the entities in a plan, keyed by ID.
+// Months are keyed by their ISO date, e.g. "2024-01-01". Deleted entities are
+// removed rather than kept as tombstones.
+//
+// PlanName, CurrencyFormat and DateFormat are not set by a Syncer; the store
+// package copies them from the Plan so a saved snapshot can be formatted
+// offline.
+//
+// CategoryGroups do not carry their Categories; look categories up by
+// CategoryGroupID instead.
+type PlanSnapshot struct {
+	PlanID                string                           `json:"plan_id"`
+	PlanName              string                           `json:"plan_name,omitempty"`
+	CurrencyFormat        *CurrencyFormat                  `json:"currency_format,omitempty"`
+	DateFormat            *DateFormat                      `json:"date_format,omitempty"`
+	Knowledge             ServerKnowledge                  `json:"server_knowledge"`
+	Accounts              map[string]*Account              `json:"accounts"`
+	CategoryGroups        map[string]*CategoryGroup        `json:"category_groups"`
+	Categories            map[string]*Category             `json:"categories"`
+	Payees                map[string]*Payee                `json:"payees"`
+	Months                map[string]*MonthSummary         `json:"months"`
+	Transactions          map[string]*Transaction          `json:"transactions"`
+	ScheduledTransactions map[string]*ScheduledTransaction `json:"scheduled_transactions"`
+}
+
+// NewPlanSnapshot returns an empty snapshot for the given plan.
+func NewPlanSnapshot(planID string) *PlanSnapshot {
+	return &PlanSnapshot{
+		PlanID:                planID,
+		Accounts:              make(map[string]*Account),
+		CategoryGroups:        make(map[string]*CategoryGroup),
+		Categories:            make(map[string]*Category),
+		Payees:                make(map[string]*Payee),
+		Months:                make(map[string]*MonthSummary),
+		Transactions:          make(map[string]*Transaction),
+		ScheduledTransactions: make(map[string]*ScheduledTransaction),
+	}
+}
+
+// init allocates any nil maps, e.g. in a snapshot decoded from JSON.
+func (s *PlanSnapshot) init() {
+	if s.Accounts == nil {
+		s.Accounts = make(map[string]*Account)
+	}
+	if s.CategoryGroups == nil {
+		s.CategoryGroups = make(map[string]*CategoryGroup)
+	}
+	if s.Categories == nil {
+		s.Categories = make(map[string]*Category)
+	}
+	if s.Payees == nil {
+		s.Payees = make(map[string]*Payee)
+	}
+	if s.Months == nil {
+		s.Months = make(map[string]*MonthSummary)
+	}
+	if s.Transactions == nil {
+		s.Transactions = make(map[string]*Transaction)
+	}
+	if s.ScheduledTransactions == nil {
+		s.ScheduledTransactions = make(map[string]*ScheduledTransaction)
+	}
+}
+
+// clone returns a copy of s. The maps are copied, the entities they point to
+// are shared.
+func (s *PlanSnapshot) clone() *PlanSnapshot {
+	return &PlanSnapshot{
+		PlanID:                s.PlanID,
+		PlanName:              s.PlanName,
+		CurrencyFormat:        s.CurrencyFormat,
+		DateFormat:            s.DateFormat,
+		Knowledge:             s.Knowledge,
+		Accounts:              maps.Clone(s.Accounts),
+		CategoryGroups:        maps.Clone(s.CategoryGroups),
+		Categories:            maps.Clone(s.Categories),
+		Payees:                maps.Clone(s.Payees),
+		Months:                maps.Clone(s.Months),
+		Transactions:          maps.Clone(s.Transactions),
+		ScheduledTransactions: maps.Clone(s.ScheduledTransactions),
+	}
+}
+
+// AccountList returns the accounts in the snapshot, sorted by name.
+func (s *PlanSnapshot) AccountList() []*Account {
+	accounts := slices.Collect(maps.Values(s.Accounts))
+	slices.SortFunc(accounts, func(a, b *Account) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return accounts
+}
+
+// CategoryGroupList returns the category groups in the snapshot, sorted by
+// name, with their Categories filled in from the snapshot. The groups are
+// copies; the categories are shared.
+func (s *PlanSnapshot) CategoryGroupList() []*CategoryGroup {
+	groups := make([]*CategoryGroup, 0, len(s.CategoryGroups))
+	byGroup := make(map[string]*CategoryGroup, len(s.CategoryGroups))
+	for _, g := range s.CategoryGroups {
+		group := *g
+		group.Categories = nil
+		groups = append(groups, &group)
+		byGroup[g.ID] = &group
+	}
+	for _, c := range s.Categories {
+		if g, ok := byGroup[c.CategoryGroupID]; ok {
+			g.Categories = append(g.Categories, c)
+		}
+	}
+	for _, g := range groups {
+		slices.SortFunc(g.Categories, func(a, b *Category) int {
+			return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+		})
+	}
+	slices.SortFunc(groups, func(a, b *CategoryGroup) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return groups
+}
+
+// TransactionList returns the transactions in the snapshot, sorted by date
+// and then by ID.
+func (s *PlanSnapshot) TransactionList() []*Transaction {
+	txns := slices.Collect(maps.Values(s.Transactions))
+	slices.SortFunc(txns, func(a, b *Transaction) int {
+		return cmp.Or(time.Time(a.Date).Compare(time.Time(b.Date)), cmp.Compare(a.ID, b.ID))
+	})
+	return txns
+}
+
+// ScheduledTransactionList returns the scheduled transactions in the
+// snapshot, sorted by next date and then by ID.
+func (s *PlanSnapshot) ScheduledTransactionList() []*ScheduledTransaction {
+	txns := slices.Collect(maps.Values(s.ScheduledTransactions))
+	slices.SortFunc(txns, func(a, b *ScheduledTransaction) int {
+		return cmp.Or(time.Time(a.DateNext).Compare(time.Time(b.DateNext)), cmp.Compare(a.ID, b.ID))
+	})
+	return txns
+}
+
+// A Syncer keeps a PlanSnapshot up to date using delta requests. The first
+// sync of each resource fetches everything; later syncs pass
+// last_knowledge_of_server and only receive rows that changed, including
+// deleted ones, which are removed from the snapshot.
+//
+// A Syncer is safe for concurrent use.
+type Syncer struct {
+	plan *PlanService
+
+	// syncMu serializes syncs so two callers don't request the same delta.
+	syncMu  sync.Mutex
+	mu      sync.Mutex
+	snap    *PlanSnapshot
+	changes *SnapshotChanges
+}
+
+// SnapshotChanges lists the entities a Syncer has changed since it was
+// created, so a store can write only those. Each map holds the ID of every
+// entity that was added or updated, mapped to false, and of every entity that
+// was deleted, mapped to true. Months are keyed by their ISO date.
+type SnapshotChanges struct {
+	Accounts              map[string]bool
+	CategoryGroups        map[string]bool
+	Categories            map[string]bool
+	Payees                map[string]bool
+	Months                map[string]bool
+	Transactions          map[string]bool
+	ScheduledTransactions map[string]bool
+}
+
+func newSnapshotChanges() *SnapshotChanges {
+	return &SnapshotChanges{
+		Accounts:              make(map[string]bool),
+		CategoryGroups:        make(map[string]bool),
+		Categories:            make(map[string]bool),
+		Payees:                make(map[string]bool),
+		Months:                make(map[string]bool),
+		Transactions:          make(map[string]bool),
+		ScheduledTransactions: make(map[string]bool),
+	}
+}
+
+// NewSyncer returns a Syncer for this plan. If snapshot is not nil, syncing
+// resumes from its server knowledge, e.g. after loading it from disk.
+// Otherwise the Syncer starts from an empty snapshot.
+func (b *PlanService) NewSyncer(snapshot *PlanSnapshot) *Syncer {
+	if snapshot == nil {
+		snapshot = NewPlanSnapshot(b.id)
+	} else {
+		snapshot = snapshot.clone()
+		snapshot.init()
+	}
+	return &Syncer{plan: b, snap: snapshot, changes: newSnapshotChanges()}
+}
+
+// Snapshot returns a copy of the current state of the plan. The entities in
+// the returned maps are shared with the Syncer and must not be modified.
+func (s *Syncer) Snapshot() *PlanSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snap.clone()
+}
+
+// Changes returns the entities that syncing has added, updated or deleted
+// since the Syncer was created.
+func (s *Syncer) Changes() *SnapshotChanges {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.changes
+	return &SnapshotChanges{
+		Accounts:              maps.Clone(c.Accounts),
+		CategoryGroups:        maps.Clone(c.CategoryGroups),
+		Categories:            maps.Clone(c.Categories),
+		Payees:                maps.Clone(c.Payees),
+		Months:                maps.Clone(c.Months),
+		Transactions:          maps.Clone(c.Transactions),
+		ScheduledTransactions: maps.Clone(c.ScheduledTransactions),
+	}
+}
+
+// Knowledge returns the current server knowledge for each resource.
+func (s *Syncer) Knowledge() ServerKnowledge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snap.Knowledge
+}
+
+// Sync brings every resource in the snapshot up to date. It stops at the
+// first error; resources synced before the error keep their new state.
+func (s *Syncer) Sync(ctx context.Context) error {
+	for _, syncResource := range []func(context.Context) error{
+		s.SyncAccounts,
+		s.SyncCategories,
+		s.SyncPayees,
+		s.SyncMonths,
+		s.SyncTransactions,
+		s.SyncScheduledTransactions,
+	} {
+		if err := syncResource(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncAccounts brings the accounts in the snapshot up to date.
+func (s *Syncer) SyncAccounts(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	resp, err := s.plan.ListAccounts(ctx, &ListOptions{LastKnowledgeOfServer: s.Knowledge().Accounts})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range resp.Data.Accounts {
+		apply(s.snap.Accounts, s.changes.Accounts, a.ID, a, a.Deleted)
+	}
+	s.snap.Knowledge.Accounts = resp.Data.ServerKnowledge
+	return nil
+}
+
+// SyncCategories brings the category groups and categories in the snapshot
+// up to date.
+func (s *Syncer) SyncCategories(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	resp, err := s.plan.ListCategories(ctx, &ListOptions{LastKnowledgeOfServer: s.Knowledge().Categories})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range resp.Data.CategoryGroups {
+		for _, c := range g.Categories {
+			apply(s.snap.Categories, s.changes.Categories, c.ID, c, c.Deleted)
+		}
+		group := *g
+		group.Categories = nil
+		apply(s.snap.CategoryGroups, s.changes.CategoryGroups, g.ID, &group, g.Deleted)
+	}
+	s.snap.Knowledge.Categories = resp.Data.ServerKnowledge
+	return nil
+}
+
+// SyncPayees brings the payees in the snapshot up to date.
+func (s *Syncer) SyncPayees(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	resp, err := s.plan.ListPayees(ctx, &ListOptions{LastKnowledgeOfServer: s.Knowledge().Payees})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range resp.Data.Payees {
+		apply(s.snap.Payees, s.changes.Payees, p.ID, p, p.Deleted)
+	}
+	s.snap.Knowledge.Payees = resp.Data.ServerKnowledge
+	return nil
+}
+
+// SyncMonths brings the month summaries in the snapshot up to date.
+func (s *Syncer) SyncMonths(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	resp, err := s.plan.ListMonths(ctx, &ListOptions{LastKnowledgeOfServer: s.Knowledge().Months})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range resp.Data.Months {
+		apply(s.snap.Months, s.changes.Months, m.Month, m, m.Deleted)
+	}
+	s.snap.Knowledge.Months = resp.Data.ServerKnowledge
+	return nil
+}
+
+// SyncTransactions brings the transactions in the snapshot up to date.
+func (s *Syncer) SyncTransactions(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	resp, err := s.plan.ListTransactions(ctx, &TransactionListOptions{LastKnowledgeOfServer: s.Knowledge().Transactions})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range resp.Data.Transactions {
+		apply(s.snap.Transactions, s.changes.Transactions, t.ID, t, t.Deleted)
+	}
+	s.snap.Knowledge.Transactions = resp.Data.ServerKnowledge
+	return nil
+}
+
+// SyncScheduledTransactions brings the scheduled transactions in the
+// snapshot up to date.
+func (s *Syncer) SyncScheduledTransactions(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	resp, err := s.plan.ListScheduledTransactions(ctx, &ListOptions{LastKnowledgeOfServer: s.Knowledge().ScheduledTransactions})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range resp.Data.ScheduledTransactions {
+		apply(s.snap.ScheduledTransactions, s.changes.ScheduledTransactions, t.ID, t, t.Deleted)
+	}
+	s.snap.Knowledge.ScheduledTransactions = resp.Data.ServerKnowledge
+	return nil
+}
+
+// apply adds, replaces or deletes the entity id in m and records the change.
+func apply[T any](m map[string]*T, changes map[string]bool, id string, v *T, deleted bool) {
+	changes[id] = deleted
+	if deleted {
+		delete(m, id)
+		return
+	}
+	m[id] = v
+}
@@ -0,0 +1,130 @@
+package ynab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSyncerAppliesDeltas(t *testing.T) {
+	var knowledgeSeen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		knowledge := r.URL.Query().Get("last_knowledge_of_server")
+		switch r.URL.Path {
+		case "/plans/plan-id/transactions":
+			knowledgeSeen = append(knowledgeSeen, knowledge)
+			if knowledge == "" {
+				w.Write([]byte(`{"data": {"server_knowledge": 10, "transactions": [
+					{"id": "txn-1", "date": "2024-01-02", "amount": -1000, "account_id": "acct-1"},
+					{"id": "txn-2", "date": "2024-01-01", "amount": 5000, "account_id": "acct-1"}
+				]}}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"server_knowledge": 12, "transactions": [
+				{"id": "txn-1", "date": "2024-01-02", "amount": -1000, "account_id": "acct-1", "deleted": true},
+				{"id": "txn-2", "date": "2024-01-01", "amount": 6000, "account_id": "acct-1"},
+				{"id": "txn-3", "date": "2024-01-03", "amount": -250, "account_id": "acct-1"}
+			]}}`))
+		case "/plans/plan-id/categories":
+			w.Write([]byte(`{"data": {"server_knowledge": 4, "category_groups": [
+				{"id": "group-1", "name": "Bills", "categories": [
+					{"id": "cat-1", "name": "Rent", "category_group_id": "group-1"},
+					{"id": "cat-2", "name": "Old", "category_group_id": "group-1", "deleted": true}
+				]}
+			]}}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+	syncer := client.Plans("plan-id").NewSyncer(nil)
+	ctx := context.Background()
+
+	if err := syncer.SyncTransactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	snap := syncer.Snapshot()
+	if len(snap.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(snap.Transactions))
+	}
+	if k := syncer.Knowledge().Transactions; k != 10 {
+		t.Errorf("expected knowledge 10, got %d", k)
+	}
+
+	if err := syncer.SyncTransactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(knowledgeSeen) != 2 || knowledgeSeen[0] != "" || knowledgeSeen[1] != "10" {
+		t.Errorf("expected requests with knowledge [\"\" \"10\"], got %q", knowledgeSeen)
+	}
+	snap2 := syncer.Snapshot()
+	txns := snap2.TransactionList()
+	if len(txns) != 2 {
+		t.Fatalf("expected 2 transactions after delta, got %d", len(txns))
+	}
+	if txns[0].ID != "txn-2" || txns[0].Amount != 6000 {
+		t.Errorf("expected updated txn-2 first, got %s %d", txns[0].ID, txns[0].Amount)
+	}
+	if txns[1].ID != "txn-3" {
+		t.Errorf("expected txn-3 second, got %s", txns[1].ID)
+	}
+	// earlier snapshots are not affected by later syncs
+	if len(snap.Transactions) != 2 || snap.Transactions["txn-1"] == nil {
+		t.Errorf("expected first snapshot to be unchanged")
+	}
+
+	if err := syncer.SyncCategories(ctx); err != nil {
+		t.Fatal(err)
+	}
+	snap3 := syncer.Snapshot()
+	if len(snap3.Categories) != 1 || snap3.Categories["cat-1"] == nil {
+		t.Errorf("expected only cat-1, got %v", snap3.Categories)
+	}
+	if g := snap3.CategoryGroups["group-1"]; g == nil || g.Categories != nil {
+		t.Errorf("expected group-1 without nested categories, got %+v", g)
+	}
+
+	changes := syncer.Changes()
+	if got := fmt.Sprint(changes.Transactions); got != "map[txn-1:true txn-2:false txn-3:false]" {
+		t.Errorf("expected txn-1 deleted and txn-2 and txn-3 updated, got %s", got)
+	}
+	if got := fmt.Sprint(changes.Categories); got != "map[cat-1:false cat-2:true]" {
+		t.Errorf("expected cat-1 updated and cat-2 deleted, got %s", got)
+	}
+	if len(changes.Accounts) != 0 || len(changes.CategoryGroups) != 1 {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
+
+func TestSyncerResumesFromSnapshot(t *testing.T) {
+	var knowledge string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		knowledge = r.URL.Query().Get("last_knowledge_of_server")
+		w.Write([]byte(`{"data": {"server_knowledge": 21, "accounts": []}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.Base = server.URL
+	snap := NewPlanSnapshot("plan-id")
+	snap.Knowledge.Accounts = 20
+	snap.Accounts["acct-1"] = &Account{ID: "acct-1", Name: "Checking"}
+
+	syncer := client.Plans("plan-id").NewSyncer(snap)
+	if err := syncer.SyncAccounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if knowledge != "20" {
+		t.Errorf("expected last_knowledge_of_server=20, got %q", knowledge)
+	}
+	if got := syncer.Snapshot(); got.Accounts["acct-1"] == nil || got.Knowledge.Accounts != 21 {
+		t.Errorf("expected account to be kept and knowledge to advance, got %+v", got)
+	}
+}
@@ -0,0 +1,274 @@
+package ynab
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kevinburke/go-types"
+)
+
+// Length limits from the API spec, in characters.
+const (
+	maxPayeeNameLength = 200
+	maxMemoLength      = 500
+	maxImportIDLength  = 36
+)
+
+// The names YNAB gives the category group holding the Credit Card Payment
+// categories, and the internal category of split transactions.
+const (
+	creditCardPaymentsGroup = "Credit Card Payments"
+	splitCategory           = "Split (Multiple Categories)..."
+)
+
+// maxScheduledYears is how far in the future a scheduled transaction can be.
+const maxScheduledYears = 5
+
+// A FieldError is a problem with one field of a request, e.g. "memo" or
+// "subtransactions[1].amount".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// A ValidationError lists every problem Validate found with a request.
+type ValidationError struct {
+	// Request is the kind of request, e.g. "transaction".
+	Request string
+	Errors  []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("ynab: invalid %s: %v", e.Request, e.Errors[0])
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "ynab: invalid %s: %d problems:", e.Request, len(e.Errors))
+	for _, fe := range e.Errors {
+		b.WriteString("\n\t")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the FieldErrors, for errors.As.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// ValidateOptions supply what can't be told from a request alone. A nil
+// *ValidateOptions uses the defaults.
+type ValidateOptions struct {
+	// Now is the time dates are checked against. If zero, time.Now() is used.
+	Now time.Time
+	// CategoryGroups are the plan's categories. A category ID doesn't say
+	// what kind of category it is, so Credit Card Payment categories, and
+	// split scheduled transactions, are only rejected if this is set.
+	CategoryGroups []*CategoryGroup
+}
+
+// validator collects the problems with a request.
+type validator struct {
+	opts  ValidateOptions
+	today string // YYYY-MM-DD
+	// kinds maps category IDs to creditCardPaymentsGroup or splitCategory.
+	kinds map[string]string
+	errs  []*FieldError
+}
+
+func newValidator(opts *ValidateOptions) *validator {
+	v := new(validator)
+	if opts != nil {
+		v.opts = *opts
+	}
+	if v.opts.Now.IsZero() {
+		v.opts.Now = time.Now()
+	}
+	v.today = v.opts.Now.Format("2006-01-02")
+	v.kinds = make(map[string]string)
+	for _, g := range v.opts.CategoryGroups {
+		for _, c := range g.Categories {
+			switch {
+			case g.Name == creditCardPaymentsGroup:
+				v.kinds[c.ID] = creditCardPaymentsGroup
+			case c.Name == splitCategory:
+				v.kinds[c.ID] = splitCategory
+			}
+		}
+	}
+	return v
+}
+
+func (v *validator) addf(field, format string, args ...any) {
+	v.errs = append(v.errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err(request string) error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Request: request, Errors: v.errs}
+}
+
+func (v *validator) maxLength(field string, s types.NullString, max int) {
+	if n := utf8.RuneCountInString(s.String); n > max {
+		v.addf(field, "is %d characters long, the limit is %d", n, max)
+	}
+}
+
+// notFuture checks that d is not after today.
+func (v *validator) notFuture(field string, d Date) {
+	if s := d.String(); s > v.today {
+		v.addf(field, "%s is in the future; use a scheduled transaction instead", s)
+	}
+}
+
+func (v *validator) category(field string, id types.NullString) {
+	if v.kinds[id.String] == creditCardPaymentsGroup {
+		v.addf(field, "Credit Card Payment categories are not permitted")
+	}
+}
+
+func (v *validator) cleared(field string, s ClearedStatus) {
+	switch s {
+	case "", ClearedStatusCleared, ClearedStatusUncleared, ClearedStatusReconciled:
+	default:
+		v.addf(field, "unknown status %q, use cleared, uncleared or reconciled", s)
+	}
+}
+
+func (v *validator) flagColor(field string, c FlagColor) {
+	switch c {
+	case FlagColorEmpty, FlagColorRed, FlagColorOrange, FlagColorYellow, FlagColorGreen, FlagColorBlue, FlagColorPurple:
+	default:
+		v.addf(field, "unknown color %q", c)
+	}
+}
+
+func (v *validator) frequency(field string, f Frequency) {
+	if f != "" && !f.Valid() {
+		v.addf(field, "unknown frequency %q", f)
+	}
+}
+
+// subtransaction checks the fields shared by NewSubTransaction and
+// SubTransaction.
+func (v *validator) subtransaction(i int, payeeName, categoryID, memo types.NullString) {
+	prefix := fmt.Sprintf("subtransactions[%d].", i)
+	v.maxLength(prefix+"payee_name", payeeName, maxPayeeNameLength)
+	v.category(prefix+"category_id", categoryID)
+	v.maxLength(prefix+"memo", memo, maxMemoLength)
+}
+
+// Validate checks t against the rules the API enforces, so mistakes are
+// caught before the request is sent. It returns nil, or a *ValidationError
+// listing every problem.
+func (t *NewTransaction) Validate() error {
+	return t.ValidateWith(nil)
+}
+
+// ValidateWith is like Validate, with the given options.
+func (t *NewTransaction) ValidateWith(opts *ValidateOptions) error {
+	v := newValidator(opts)
+	if t.AccountID == "" {
+		v.addf("account_id", "is required")
+	}
+	if time.Time(t.Date).IsZero() {
+		v.addf("date", "is required")
+	} else {
+		v.notFuture("date", t.Date)
+	}
+	v.maxLength("payee_name", t.PayeeName, maxPayeeNameLength)
+	v.category("category_id", t.CategoryID)
+	v.maxLength("memo", t.Memo, maxMemoLength)
+	v.cleared("cleared", t.Cleared)
+	v.flagColor("flag_color", t.FlagColor)
+	v.maxLength("import_id", t.ImportID, maxImportIDLength)
+	if len(t.Subtransactions) > 0 {
+		sum := int64(0)
+		for i, sub := range t.Subtransactions {
+			sum += sub.Amount
+			v.subtransaction(i, sub.PayeeName, sub.CategoryID, sub.Memo)
+		}
+		if sum != t.Amount {
+			v.addf("subtransactions", "amounts add up to %d, not the transaction amount %d", sum, t.Amount)
+		}
+	}
+	return v.err("transaction")
+}
+
+// Validate checks t against the rules the API enforces, so mistakes are
+// caught before the request is sent. It returns nil, or a *ValidationError
+// listing every problem.
+func (t *UpdateTransaction) Validate() error {
+	return t.ValidateWith(nil)
+}
+
+// ValidateWith is like Validate, with the given options.
+func (t *UpdateTransaction) ValidateWith(opts *ValidateOptions) error {
+	v := newValidator(opts)
+	if !time.Time(t.Date).IsZero() {
+		v.notFuture("date", t.Date)
+	}
+	v.maxLength("payee_name", t.PayeeName, maxPayeeNameLength)
+	v.category("category_id", t.CategoryID)
+	v.maxLength("memo", t.Memo, maxMemoLength)
+	v.cleared("cleared", ClearedStatus(t.Cleared.String))
+	v.flagColor("flag_color", FlagColor(t.FlagColor.String))
+	if len(t.Subtransactions) > 0 {
+		sum := int64(0)
+		for i, sub := range t.Subtransactions {
+			sum += sub.Amount
+			v.subtransaction(i, sub.PayeeName, sub.CategoryID, sub.Memo)
+		}
+		if t.Amount != nil && sum != *t.Amount {
+			v.addf("subtransactions", "amounts add up to %d, not the transaction amount %d", sum, *t.Amount)
+		}
+	}
+	return v.err("transaction update")
+}
+
+// Validate checks t against the rules the API enforces, so mistakes are
+// caught before the request is sent. It returns nil, or a *ValidationError
+// listing every problem.
+func (t *SaveScheduledTransaction) Validate() error {
+	return t.ValidateWith(nil)
+}
+
+// ValidateWith is like Validate, with the given options.
+func (t *SaveScheduledTransaction) ValidateWith(opts *ValidateOptions) error {
+	v := newValidator(opts)
+	if t.AccountID == "" {
+		v.addf("account_id", "is required")
+	}
+	if time.Time(t.Date).IsZero() {
+		v.addf("date", "is required")
+	} else {
+		latest := v.opts.Now.AddDate(maxScheduledYears, 0, 0).Format("2006-01-02")
+		switch s := t.Date.String(); {
+		case s < v.today:
+			v.addf("date", "%s is in the past", s)
+		case s > latest:
+			v.addf("date", "%s is more than %d years in the future", s, maxScheduledYears)
+		}
+	}
+	v.maxLength("payee_name", t.PayeeName, maxPayeeNameLength)
+	v.category("category_id", t.CategoryID)
+	if v.kinds[t.CategoryID.String] == splitCategory {
+		v.addf("category_id", "split scheduled transactions are not supported")
+	}
+	v.maxLength("memo", t.Memo, maxMemoLength)
+	v.flagColor("flag_color", t.FlagColor)
+	v.frequency("frequency", t.Frequency)
+	return v.err("scheduled transaction")
+}
@@ -0,0 +1,150 @@
+package ynab
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/go-types"
+)
+
+var validateNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+func validateDate(year int, month time.Month, day int) Date {
+	return Date(time.Date(year, month, day, 0, 0, 0, 0, time.Local))
+}
+
+func validateGroups() []*CategoryGroup {
+	return []*CategoryGroup{
+		{Name: "Internal Master Category", Internal: true, Categories: []*Category{
+			{ID: "split", Name: "Split (Multiple Categories)..."},
+		}},
+		{Name: "Credit Card Payments", Categories: []*Category{{ID: "visa-payment", Name: "Visa"}}},
+		{Name: "Bills", Categories: []*Category{{ID: "rent", Name: "Rent"}}},
+	}
+}
+
+// fields returns the fields err complains about.
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %T, want *ValidationError", err)
+	}
+	var out []string
+	for _, fe := range verr.Errors {
+		out = append(out, fe.Field)
+	}
+	return out
+}
+
+func TestNewTransactionValidate(t *testing.T) {
+	opts := &ValidateOptions{Now: validateNow, CategoryGroups: validateGroups()}
+	valid := func() *NewTransaction {
+		return &NewTransaction{
+			AccountID: "checking",
+			Date:      validateDate(2026, 10, 16),
+			Amount:    -30000,
+			Memo:      types.NullString{Valid: true, String: strings.Repeat("é", 500)},
+			Subtransactions: []*NewSubTransaction{
+				{Amount: -10000, CategoryID: types.NullString{Valid: true, String: "rent"}},
+				{Amount: -20000},
+			},
+		}
+	}
+	if err := valid().ValidateWith(opts); err != nil {
+		t.Fatalf("valid transaction: %v", err)
+	}
+
+	txn := valid()
+	txn.AccountID = ""
+	txn.Date = validateDate(2026, 10, 17)
+	txn.PayeeName = types.NullString{Valid: true, String: strings.Repeat("x", 201)}
+	txn.CategoryID = types.NullString{Valid: true, String: "visa-payment"}
+	txn.Memo.String += "x"
+	txn.Cleared = "pending"
+	txn.FlagColor = "pink"
+	txn.ImportID = types.NullString{Valid: true, String: strings.Repeat("1", 37)}
+	txn.Subtransactions[1].Amount = -10000
+	txn.Subtransactions[1].CategoryID = types.NullString{Valid: true, String: "visa-payment"}
+	err := txn.ValidateWith(opts)
+	want := []string{"account_id", "date", "payee_name", "category_id", "memo", "cleared", "flag_color", "import_id", "subtransactions[1].category_id", "subtransactions"}
+	if got := fields(t, err); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got problems with %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(err.Error(), "ynab: invalid transaction: 10 problems:\n\taccount_id: is required\n") {
+		t.Errorf("Error() = %q", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "account_id" {
+		t.Errorf("errors.As(*FieldError) = %v", fe)
+	}
+
+	// Without the category groups, category IDs aren't checked.
+	txn = valid()
+	txn.CategoryID = types.NullString{Valid: true, String: "visa-payment"}
+	if err := txn.ValidateWith(&ValidateOptions{Now: validateNow}); err != nil {
+		t.Errorf("without category groups: %v", err)
+	}
+	if err := (&NewTransaction{AccountID: "checking", Date: validateDate(2026, 10, 17)}).ValidateWith(&ValidateOptions{Now: validateNow}); err == nil || err.Error() != "ynab: invalid transaction: date: 2026-10-17 is in the future; use a scheduled transaction instead" {
+		t.Errorf("future date: got %v", err)
+	}
+}
+
+func TestUpdateTransactionValidate(t *testing.T) {
+	opts := &ValidateOptions{Now: validateNow, CategoryGroups: validateGroups()}
+	if err := new(UpdateTransaction).ValidateWith(opts); err != nil {
+		t.Errorf("empty update: %v", err)
+	}
+	amount := int64(-5000)
+	u := &UpdateTransaction{
+		Date:            validateDate(2027, 1, 1),
+		Amount:          &amount,
+		CategoryID:      types.NullString{Valid: true, String: "visa-payment"},
+		Cleared:         types.NullString{Valid: true, String: "nope"},
+		FlagColor:       types.NullString{Valid: true, String: "red"},
+		Subtransactions: []*SubTransaction{{Amount: -1000}, {Amount: -1000, Memo: types.NullString{Valid: true, String: strings.Repeat("m", 501)}}},
+	}
+	want := []string{"date", "category_id", "cleared", "subtransactions[1].memo", "subtransactions"}
+	if got := fields(t, u.ValidateWith(opts)); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got problems with %q, want %q", got, want)
+	}
+}
+
+func TestSaveScheduledTransactionValidate(t *testing.T) {
+	opts := &ValidateOptions{Now: validateNow, CategoryGroups: validateGroups()}
+	tests := []struct {
+		date     Date
+		category string
+		want     string
+	}{
+		{validateDate(2026, 10, 16), "rent", ""},
+		{validateDate(2031, 10, 16), "", ""},
+		{validateDate(2026, 10, 15), "", "date"},
+		{validateDate(2031, 10, 17), "", "date"},
+		{validateDate(2026, 11, 1), "visa-payment", "category_id"},
+		{validateDate(2026, 11, 1), "split", "category_id"},
+		{Date{}, "", "date"},
+	}
+	for _, tt := range tests {
+		st := &SaveScheduledTransaction{AccountID: "checking", Date: tt.date, CategoryID: types.NullString{Valid: tt.category != "", String: tt.category}}
+		if got := strings.Join(fields(t, st.ValidateWith(opts)), ","); got != tt.want {
+			t.Errorf("Validate(%s, %q): got problems with %q, want %q", tt.date, tt.category, got, tt.want)
+		}
+	}
+	if err := new(SaveScheduledTransaction).Validate(); err == nil || !strings.Contains(err.Error(), "2 problems") {
+		t.Errorf("empty scheduled transaction: got %v", err)
+	}
+	st := &SaveScheduledTransaction{AccountID: "checking", Date: validateDate(2026, 11, 1), Frequency: FrequencyEveryOtherWeek}
+	if err := st.ValidateWith(opts); err != nil {
+		t.Error(err)
+	}
+	st.Frequency = "fortnightly"
+	if got := strings.Join(fields(t, st.ValidateWith(opts)), ","); got != "frequency" {
+		t.Errorf("got problems with %q, want frequency", got)
+	}
+}
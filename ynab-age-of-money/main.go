@@ -8,16 +8,18 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"math"
 	"net/url"
 	"os"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/ageofmoney"
+	"github.com/kevinburke/ynab-go/internal/cli"
 )
 
 func getAccounts(client *ynab.Client, budgetID string) ([]*ynab.Account, error) {
@@ -28,20 +30,20 @@ func getAccounts(client *ynab.Client, budgetID string) ([]*ynab.Account, error)
 	return accountResp.Data.Accounts, nil
 }
 
-func getBudgets(client *ynab.Client) ([]*ynab.Budget, error) {
-	budgetResp, err := client.GetBudgets(context.TODO(), url.Values{})
+func getTransactions(client *ynab.Client, budgetID string) ([]*ynab.Transaction, error) {
+	transactionResp, err := client.Budgets(budgetID).Transactions(context.TODO(), url.Values{})
 	if err != nil {
 		return nil, err
 	}
-	return budgetResp.Data.Budgets, nil
+	return transactionResp.Data.Transactions, nil
 }
 
-func getTransactions(client *ynab.Client, budgetID string) ([]*ynab.Transaction, error) {
-	transactionResp, err := client.Budgets(budgetID).Transactions(context.TODO(), url.Values{})
+func getMonths(client *ynab.Client, budgetID string) ([]*ynab.MonthSummary, error) {
+	monthResp, err := client.Budgets(budgetID).Months(context.TODO(), url.Values{})
 	if err != nil {
 		return nil, err
 	}
-	return transactionResp.Data.Transactions, nil
+	return monthResp.Data.Months, nil
 }
 
 func getScheduledTransactions(client *ynab.Client, budgetID string) ([]*ynab.ScheduledTransaction, error) {
@@ -52,114 +54,62 @@ func getScheduledTransactions(client *ynab.Client, budgetID string) ([]*ynab.Sch
 	return transactionResp.Data.ScheduledTransactions, nil
 }
 
-func isOutflow(accountMap map[string]*ynab.Account, tx *ynab.Transaction, scheduled bool) bool {
-	txnAccount, ok := accountMap[tx.AccountID]
-	if !ok {
-		panic("unknown account: " + txnAccount.ID + " " + txnAccount.Name)
-	}
-	if !txnAccount.OnBudget {
-		return false
-	}
-	var transferAccount *ynab.Account
-	if tx.TransferAccountID.Valid {
-		var ok bool
-		transferAccount, ok = accountMap[tx.TransferAccountID.String]
-		if !ok {
-			panic("could not find id: " + tx.TransferAccountID.String)
-		}
-	}
-	if txnAccount.CashBacked() {
-		if transferAccount == nil || !transferAccount.OnBudget ||
-			// For scheduled transfers we only see one side of the transaction
-			// - cash => credit transfers in the past get caught below but we need
-			// to catch scheduled ones here also.
-			(scheduled && !transferAccount.CashBacked()) {
-			// cash direct spending, or transfer to off budget account
-			return tx.Amount < 0
-		}
-		// cash <> cash transfer is just moving money around, not an outflow
-		// the bank account side of a credit card transfer is ignored, we count
-		// the credit card transfer inflow instead.
-		return false
-	}
-	// transaction account is not cash backed:
-	if transferAccount == nil {
-		// credit card spending or similar outflow.
-		return false
-	}
-	if !transferAccount.CashBacked() {
-		// not cash backed and transfer account is not cash backed, e.g.
-		// transfer from mortgage account to escrow account.
-		return false
-	}
-	if tx.Amount >= 0 {
-		// transfer from cash backed account to credit account, reverse the
-		// amount and count it as an outflow.
-		tx.Amount = -1 * tx.Amount
-		return true
-	}
-	return false
-}
-
 func main() {
 	debug := flag.Bool("debug", false, "Enable debug")
 	file := flag.String("file", "", "Filename to read txns from")
-	budgetName := flag.String("budget-name", "", "Name of the budget to compute AOM for")
+	budgetName := flag.String("budget-name", "", "Name, name prefix or ID of the budget to compute AOM for")
 	includeScheduledIncome := flag.Bool("include-scheduled-income", false, "Include scheduled income")
+	storeDir := flag.String("store", "", "Directory, or SQLite database ending in .db, to save plan data in. Later runs only download changes")
+	offline := flag.Bool("offline", false, "Read plan data from --store without contacting YNAB")
+	cacheDir := flag.String("cache", "", "Directory to cache responses that rarely change in, like the plan list")
+	weighted := flag.Bool("weighted", false, "Age each spend by the weighted average of the buckets it spends from, like YNAB")
+	simulate := flag.String("simulate", "", "Project the age of money week by week with the what-if scenario in this YAML file")
+	verify := flag.Bool("verify", false, "Compare the age of money at the end of each month to the one YNAB reports")
+	profile := flag.String("profile", "", "Profile in ~/.config/ynab/config.toml to read the access token from (default $YNAB_PROFILE)")
 	flag.Parse()
-	token, ok := os.LookupEnv("YNAB_TOKEN")
-	if !ok {
-		log.Fatal("please set YNAB_TOKEN in the environment: https://app.youneedabudget.com/settings")
+	if *offline && *storeDir == "" {
+		log.Fatal("--offline requires --store")
 	}
-	client := ynab.NewClient(token)
-	budgets, err := getBudgets(client)
+	client, thisBudget, err := cli.OpenPlan(context.TODO(), *profile, *budgetName, *cacheDir, *storeDir, *offline)
 	if err != nil {
 		log.Fatal(err)
 	}
-	var thisBudget *ynab.Budget
-	if len(budgets) == 1 {
-		thisBudget = budgets[0]
-	} else {
-		if *budgetName == "" {
-			log.Fatal("please use --budget-name to tell us which budget to calculate!")
-		}
-
-		for _, budget := range budgets {
-			if budget.Name == *budgetName {
-				thisBudget = budget
-				break
-			}
-		}
-		if thisBudget == nil {
-			log.Fatalf("could not find budget with name %q, please double check!", *budgetName)
+	formatter = thisBudget.Formatter()
+	var snap *ynab.PlanSnapshot
+	if *storeDir != "" {
+		snap, err = cli.Snapshot(context.TODO(), client, *storeDir, thisBudget, *offline)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
-	accounts, err := getAccounts(client, thisBudget.ID)
-	if err != nil {
-		log.Fatal(err)
+	var accounts []*ynab.Account
+	if snap != nil {
+		accounts = snap.AccountList()
+	} else {
+		accounts, err = getAccounts(client, thisBudget.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-	accountMap := make(map[string]*ynab.Account, len(accounts))
-	for _, account := range accounts {
-		if *debug {
+	if *debug {
+		for _, account := range accounts {
 			fmt.Println("account", account.ID, account.Name, account.Type, account.Note, "on budget:", account.OnBudget)
 		}
-		accountMap[account.ID] = account
-	}
-	scheduledTxns, err := getScheduledTransactions(client, thisBudget.ID)
-	if err != nil {
-		log.Fatal(err)
 	}
-	sort.Slice(scheduledTxns, func(i, j int) bool {
-		it := time.Time(scheduledTxns[i].DateNext)
-		jt := time.Time(scheduledTxns[j].DateNext)
-		if it.Equal(jt) {
-			return scheduledTxns[i].Amount > scheduledTxns[j].Amount
+	var scheduledTxns []*ynab.ScheduledTransaction
+	if snap != nil {
+		scheduledTxns = snap.ScheduledTransactionList()
+	} else {
+		scheduledTxns, err = getScheduledTransactions(client, thisBudget.ID)
+		if err != nil {
+			log.Fatal(err)
 		}
-		return it.Before(jt)
-	})
+	}
 	var txns []*ynab.Transaction
 
-	if *file != "" {
+	if snap != nil {
+		txns = snap.TransactionList()
+	} else if *file != "" {
 		data, err := os.ReadFile(*file)
 		switch {
 		case os.IsNotExist(err):
@@ -183,120 +133,75 @@ func main() {
 		}
 	}
 
-	buckets := make([]*ynab.Transaction, 0)
-	for i := range txns {
-		tx := txns[i]
-		txnAccount, ok := accountMap[tx.AccountID]
-		if !ok {
-			panic("unknown account: " + tx.AccountID)
-		}
-		if !txnAccount.OnBudget {
-			continue
-		}
-		if !txnAccount.CashBacked() {
-			continue
-		}
-		if tx.Amount <= 0 {
-			// should be counted in spending.
-			continue
+	if *simulate != "" {
+		scenario, err := ageofmoney.LoadScenario(*simulate)
+		if err != nil {
+			log.Fatal(err)
 		}
-		if tx.TransferAccountID.Valid {
-			transferAccount, ok := accountMap[tx.TransferAccountID.String]
-			if !ok {
-				panic("could not find transfer acct id: " + tx.TransferAccountID.String)
-			}
-			// transfers from off budget accounts are income, on budget, they
-			// are just moving money around
-			if transferAccount.OnBudget {
-				continue
-			}
+		sim, err := ageofmoney.Simulate(accounts, txns, scheduledTxns, scenario)
+		if err != nil {
+			log.Fatal(err)
 		}
-		buckets = append(buckets, tx)
-		continue
+		printSimulation(sim, scenario)
+		return
 	}
-	cumEarned := int64(0)
-	for i := range buckets {
-		cumEarned += buckets[i].Amount
-		if *debug {
-			fmt.Println("income:", buckets[i].Date.String(), amt(cumEarned), amt(buckets[i].Amount), buckets[i].AccountName, buckets[i].PayeeName)
-		}
+	res, err := ageofmoney.Compute(accounts, txns, scheduledTxns, &ageofmoney.Options{
+		IncludeScheduledIncome: *includeScheduledIncome,
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-	spending := make([]*ynab.Transaction, 0)
-	for i := range txns {
-		tx := txns[i]
-		if isOutflow(accountMap, tx, false) {
-			spending = append(spending, tx)
+	if *verify {
+		var months []*ynab.MonthSummary
+		if snap != nil {
+			months = slices.Collect(maps.Values(snap.Months))
+		} else {
+			months, err = getMonths(client, thisBudget.ID)
+			if err != nil {
+				log.Fatal(err)
+			}
 		}
-	}
-	sort.Slice(spending, func(i, j int) bool {
-		it := time.Time(spending[i].Date)
-		jt := time.Time(spending[j].Date)
-		if it.Equal(jt) {
-			return spending[i].Amount > spending[j].Amount
+		ages, err := res.Verify(months)
+		if err != nil {
+			log.Fatal(err)
 		}
-		return it.Before(jt)
-	})
-	cumSpent := int64(0)
-	for i := range spending {
-		cumSpent += spending[i].Amount
+		printVerify(ages)
+		return
 	}
 	if *debug {
-		fmt.Println("budget difference", amt(cumEarned+cumSpent))
-	}
-	if len(buckets) == 0 {
-		log.Fatal("Can't generate age of money without any money!")
+		cumEarned, cumSpent := int64(0), int64(0)
+		for _, b := range res.Buckets {
+			cumEarned += b.Amount
+			fmt.Println("income:", formatter.Date(b.Date), amt(cumEarned), amt(b.Amount), b.AccountName, b.PayeeName)
+		}
+		for _, s := range res.Spending {
+			cumSpent += s.Amount
+		}
+		fmt.Println("budget difference", amt(cumEarned-cumSpent))
 	}
-	// Now that we have inflow buckets and outflows, match up spending to the
-	// bucket we spent it in.
-	currentBucketIdx := 0
-	// Amount of money that's been spent from the current bucket.
-	bucketSpend := int64(0)
 	buf := new(bytes.Buffer)
 	tw := tabwriter.NewWriter(buf, 0, 0, 1, ' ', 0)
-	for i := range spending {
-		amount := -1 * spending[i].Amount
-		if amount == 0 {
+	for _, s := range res.Spending {
+		if s.Unfunded {
+			io.WriteString(tw, fmt.Sprintf("N/A Not earned yet.\tSpent: %s\t%s\t%s\t%s\n",
+				formatter.Date(s.Date), amt(s.Amount), s.AccountName, clean(s.PayeeName)))
 			continue
 		}
-		if amount < 0 {
-			panic("spending less than zero")
-		}
-		for amount > 0 {
-			if amount < buckets[currentBucketIdx].Amount-bucketSpend {
-				bucketSpend += amount
-				amount = 0
-				continue
-			} else {
-				// exhaust this bucket
-				amount -= buckets[currentBucketIdx].Amount - bucketSpend
-				currentBucketIdx++
-				bucketSpend = 0
-			}
-		}
-		ageHours := time.Time(spending[i].Date).Sub(time.Time(buckets[currentBucketIdx].Date)).Hours()
-		ageOfMoney := int(math.Round(float64(ageHours) / 24))
-		preamble := fmt.Sprintf("%3d", ageOfMoney)
-		io.WriteString(tw, fmt.Sprintf("%s\tEarned: %s\tSpent: %s\t%s\t%s\t%s\n",
-			preamble, buckets[currentBucketIdx].Date.String(),
-			spending[i].Date.String(), "$"+amt(-1*spending[i].Amount),
-			spending[i].AccountName, clean(spending[i].PayeeName)))
+		io.WriteString(tw, fmt.Sprintf("%3d\tEarned: %s\tSpent: %s\t%s\t%s\t%s\n",
+			age(s, *weighted), formatter.Date(s.Earned), formatter.Date(s.Date), amt(s.Amount),
+			s.AccountName, clean(s.PayeeName)))
 	}
 	io.WriteString(tw, "\n")
 	tw.Flush()
 	io.Copy(os.Stdout, buf)
 	buf.Reset()
+	if *weighted && len(res.Rolling) > 0 {
+		fmt.Printf("Age of Money (average of the last %d spends): %.0f days\n\n", ageofmoney.DefaultRollingWindow, res.Rolling[len(res.Rolling)-1].Age)
+	}
 	fmt.Println("Upcoming spending thresholds (and age if you spent today):")
 	fmt.Println("==========================================================")
-	threshold := int64(0)
-	for i := currentBucketIdx; (i-currentBucketIdx < 25 && threshold <= 20000*1000) && i < len(buckets); i++ {
-		ageHours := time.Since(time.Time(buckets[i].Date)).Hours()
-		ageDays := int(math.Round(float64(ageHours)/24)) - 1
-		if i == currentBucketIdx {
-			threshold += buckets[i].Amount - bucketSpend
-		} else {
-			threshold += buckets[i].Amount
-		}
-		io.WriteString(tw, fmt.Sprintf("%d\t%s\t%s\t%s\t%s\n", ageDays, buckets[i].Date.String(), "$"+amt(threshold), buckets[i].AccountName, clean(buckets[i].PayeeName)))
+	for _, th := range res.Thresholds {
+		io.WriteString(tw, fmt.Sprintf("%d\t%s\t%s\t%s\t%s\n", th.Age, formatter.Date(th.Bucket.Date), amt(th.Cumulative), th.Bucket.AccountName, clean(th.Bucket.PayeeName)))
 	}
 	tw.Flush()
 	io.Copy(os.Stdout, buf)
@@ -307,94 +212,71 @@ func main() {
 	fmt.Println("Projected age of scheduled transactions:")
 	fmt.Println("========================================")
 	buf.Reset()
-	for i := range scheduledTxns {
-		// TODO the duplication is not great here.
-
-		// lazy type hack
-		txnIsh := &ynab.Transaction{
-			AccountID:         scheduledTxns[i].AccountID,
-			Amount:            scheduledTxns[i].Amount,
-			TransferAccountID: scheduledTxns[i].TransferAccountID,
-			Date:              scheduledTxns[i].DateNext,
-			PayeeName:         scheduledTxns[i].PayeeName,
-			Memo:              scheduledTxns[i].Memo,
-		}
-
-		if !isOutflow(accountMap, txnIsh, true) {
-			if !*includeScheduledIncome {
-				continue
-			}
-			txnAccount, ok := accountMap[txnIsh.AccountID]
-			if !ok {
-				panic("unknown account: " + txnIsh.AccountID)
-			}
-			if txnIsh.TransferAccountID.Valid {
-				transferAccount, ok := accountMap[txnIsh.TransferAccountID.String]
-				if !ok {
-					panic("could not find transfer acct id: " + txnIsh.TransferAccountID.String)
-				}
-				if !txnAccount.CashBacked() && txnIsh.Amount < 0 && transferAccount.OnBudget {
-					// transfer from off budget to on budget, this is valid
-					txnIsh.Amount = txnIsh.Amount * -1
-					buckets = append(buckets, txnIsh)
-					continue
-				}
-				// transfers from off budget accounts are income, on budget, they
-				// are just moving money around
-				if txnAccount.CashBacked() && transferAccount.OnBudget {
-					continue
-				}
-			}
-			if !txnAccount.CashBacked() {
-				continue
-			}
-			buckets = append(buckets, txnIsh)
-			continue
-		}
-		amount := -1 * txnIsh.Amount
-		if amount == 0 {
-			continue
-		}
-		if amount < 0 {
-			panic("spending less than zero")
-		}
-		for amount > 0 {
-			if currentBucketIdx >= len(buckets) {
-				break
-			}
-			if amount < buckets[currentBucketIdx].Amount-bucketSpend {
-				bucketSpend += amount
-				amount = 0
-				continue
-			} else {
-				// exhaust this bucket
-				amount -= buckets[currentBucketIdx].Amount - bucketSpend
-				currentBucketIdx++
-				bucketSpend = 0
-			}
-		}
-		if currentBucketIdx >= len(buckets) {
+	for _, s := range res.Scheduled {
+		if s.Unfunded {
 			//          113 Earned: 2019-07-25 Spend on: 2019-11-15
 			io.WriteString(tw, fmt.Sprintf("N/A Not earned yet.\tSpend on: %s\t%s\t%s\t%s\n",
-				scheduledTxns[i].DateNext.String(), "$"+amt(-1*txnIsh.Amount),
-				scheduledTxns[i].AccountName, clean(scheduledTxns[i].PayeeName)))
-			break
+				formatter.Date(s.Date), amt(s.Amount), s.AccountName, clean(s.PayeeName)))
+			continue
 		}
-		ageHours := time.Time(scheduledTxns[i].DateNext).Sub(time.Time(buckets[currentBucketIdx].Date)).Hours()
-		ageOfMoney := int(math.Round(float64(ageHours) / 24))
 		io.WriteString(tw, fmt.Sprintf("%d\tEarned: %s\tSpend on: %s\t%s\t%s\t%s\n",
-			ageOfMoney, buckets[currentBucketIdx].Date.String(),
-			scheduledTxns[i].DateNext.String(), "$"+amt(-1*txnIsh.Amount),
-			scheduledTxns[i].AccountName, clean(scheduledTxns[i].PayeeName)))
+			age(s, *weighted), formatter.Date(s.Earned), formatter.Date(s.Date), amt(s.Amount),
+			s.AccountName, clean(s.PayeeName)))
 	}
 	tw.Flush()
 	io.Copy(os.Stdout, buf)
 }
 
+// age returns the age of s in days: the weighted average of the buckets it
+// spent from if weighted is true, or else the age of the last one.
+func age(s *ageofmoney.Spend, weighted bool) int {
+	if weighted {
+		return int(math.Round(s.WeightedAge))
+	}
+	return s.Age
+}
+
+func printVerify(ages []*ageofmoney.MonthAge) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Month\tComputed\tYNAB\tDrift")
+	matched := 0
+	for _, a := range ages {
+		if a.Drift == 0 {
+			matched++
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%d\t%+d\n", time.Time(a.Month).Format("2006-01"), a.Computed, a.Reported, a.Drift)
+	}
+	tw.Flush()
+	fmt.Printf("\nThe computed age of money matches YNAB in %d of %d months.\n", matched, len(ages))
+}
+
+func printSimulation(sim *ageofmoney.Simulation, scenario *ageofmoney.Scenario) {
+	fmt.Println("Projected age of money:")
+	fmt.Println("=======================")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	for _, w := range sim.Weeks {
+		io.WriteString(tw, fmt.Sprintf("%s\t%3d days", formatter.Date(w.Date), int(math.Round(w.Age))))
+		if w.Unfunded > 0 {
+			io.WriteString(tw, fmt.Sprintf("\t%s spent without the money to spend", amt(w.Unfunded)))
+		}
+		io.WriteString(tw, "\n")
+	}
+	tw.Flush()
+	switch {
+	case sim.Reached != nil:
+		fmt.Printf("\nAge of money reaches %g days the week of %s.\n", scenario.Target, formatter.Date(sim.Reached.Date))
+	case scenario.Target > 0 && len(sim.Weeks) > 0:
+		fmt.Printf("\nAge of money doesn't reach %g days by %s.\n", scenario.Target, formatter.Date(sim.Weeks[len(sim.Weeks)-1].Date))
+	}
+}
+
 func clean(payee string) string {
 	return strings.Replace(payee, "Transfer :", "Transfer:", -1)
 }
 
+// formatter formats amounts and dates for the selected plan.
+var formatter = ynab.NewFormatter(ynab.DefaultPlanSettings)
+
 func amt(amount int64) string {
-	return strconv.FormatFloat(float64(amount)/1000, 'f', 2, 64)
+	return formatter.Amount(ynab.Milliunits(amount))
 }
@@ -3,8 +3,8 @@
 // the list of transactions returned by the program. Use --budget <budget_name>
 // to specify a budget.
 //
-// Set YNAB_TOKEN in your environment with your API token to configure the
-// client.
+// Set YNAB_TOKEN in your environment with your API token, or use --profile to
+// pick a profile from ~/.config/ynab/config.toml, to configure the client.
 package main
 
 import (
@@ -14,28 +14,12 @@ import (
 	"log"
 	"net/url"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/internal/cli"
 )
 
-func getBudgets(ctx context.Context, client *ynab.Client) ([]*ynab.Budget, error) {
-	budgetResp, err := client.GetBudgets(ctx, url.Values{})
-	if err != nil {
-		return nil, err
-	}
-	return budgetResp.Data.Budgets, nil
-}
-
-func getTransactions(client *ynab.Client, budgetID string, data url.Values) ([]*ynab.Transaction, error) {
-	transactionResp, err := client.Budgets(budgetID).Transactions(context.TODO(), data)
-	if err != nil {
-		return nil, err
-	}
-	return transactionResp.Data.Transactions, nil
-}
-
 func getCategories(client *ynab.Client, budgetID string, data url.Values) ([]*ynab.CategoryGroup, error) {
 	categoryResp, err := client.Budgets(budgetID).Categories(context.TODO(), data)
 	if err != nil {
@@ -44,43 +28,42 @@ func getCategories(client *ynab.Client, budgetID string, data url.Values) ([]*yn
 	return categoryResp.Data.CategoryGroups, nil
 }
 
+// amountFormat formats amounts like the YNAB CSV export, e.g. "1234.56".
+var amountFormat = ynab.CurrencyFormat{DecimalDigits: 2, DecimalSeparator: "."}
+
 func main() {
-	budgetName := flag.String("budget-name", "", "Name of the budget to export transactions for")
+	budgetName := flag.String("budget-name", "", "Name, name prefix or ID of the budget to export transactions for")
 	category := flag.String("category", "", "Category to filter for")
 	start := flag.String("start", "", "Start time (parsed as "+time.RFC3339+")")
+	storeDir := flag.String("store", "", "Directory, or SQLite database ending in .db, to save plan data in. Later runs only download changes")
+	offline := flag.Bool("offline", false, "Read plan data from --store without contacting YNAB")
+	cacheDir := flag.String("cache", "", "Directory to cache responses that rarely change in, like the plan list")
+	profile := flag.String("profile", "", "Profile in ~/.config/ynab/config.toml to read the access token from (default $YNAB_PROFILE)")
 	flag.Parse()
-	token, ok := os.LookupEnv("YNAB_TOKEN")
-	if !ok {
-		log.Fatal("please set YNAB_TOKEN in the environment: https://app.youneedabudget.com/settings")
+	if *offline && *storeDir == "" {
+		log.Fatal("--offline requires --store")
 	}
-	client := ynab.NewClient(token)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
-	budgets, err := getBudgets(ctx, client)
+	client, thisBudget, err := cli.OpenPlan(ctx, *profile, *budgetName, *cacheDir, *storeDir, *offline)
 	if err != nil {
 		log.Fatal(err)
 	}
-	var thisBudget *ynab.Budget
-	if len(budgets) == 1 {
-		thisBudget = budgets[0]
-	} else {
-		if *budgetName == "" {
-			log.Fatal("please use --budget-name to tell us which budget to calculate!")
-		}
-
-		for _, budget := range budgets {
-			if budget.Name == *budgetName {
-				thisBudget = budget
-				break
-			}
-		}
-		if thisBudget == nil {
-			log.Fatalf("could not find budget with name %q, please double check!", *budgetName)
+	var snap *ynab.PlanSnapshot
+	if *storeDir != "" {
+		snap, err = cli.Snapshot(ctx, client, *storeDir, thisBudget, *offline)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
-	categories, err := getCategories(client, thisBudget.ID, url.Values{})
-	if err != nil {
-		log.Fatal(err)
+	var categories []*ynab.CategoryGroup
+	if snap != nil {
+		categories = snap.CategoryGroupList()
+	} else {
+		categories, err = getCategories(client, thisBudget.ID, url.Values{})
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 	groupMap := make(map[string][]string)
 	categoryMap := make(map[string]string)
@@ -93,35 +76,50 @@ func main() {
 			}
 		}
 	}
-	data := url.Values{}
+	opts := new(ynab.TransactionListOptions)
+	var startTime time.Time
 	if *start != "" {
-		startTime, err := time.Parse(time.RFC3339, *start)
+		startTime, err = time.Parse(time.RFC3339, *start)
 		if err != nil {
 			log.Fatal(err)
 		}
-		data.Set("since_date", startTime.Format("2006-01-02"))
-	}
-	txns, err := getTransactions(client, thisBudget.ID, data)
-	if err != nil {
-		log.Fatal(err)
+		opts.SinceDate = ynab.Date(startTime)
 	}
 	w := csv.NewWriter(os.Stdout)
 	werr := w.Write([]string{"Account", "Flag", "Date", "Payee", "Category Group/Category", "Category Group", "Category", "Memo", "Outflow", "Inflow", "Cleared"})
 	if werr != nil {
 		log.Fatal(werr)
 	}
-	for _, txn := range txns {
+	writeRow := func(txn *ynab.Transaction) {
 		var outflow, inflow string
 		if txn.Amount < 0 {
-			outflow = strconv.FormatFloat(-1*float64(txn.Amount)/1000, 'f', 2, 64)
+			outflow = txn.Outflow().Format(amountFormat)
 		} else {
-			inflow = strconv.FormatFloat(float64(txn.Amount)/1000, 'f', 2, 64)
+			inflow = txn.Inflow().Format(amountFormat)
 		}
 		cgroup := categoryMap[txn.CategoryName.String]
 		if *category == "" || txn.CategoryName.String == *category || cgroup == *category {
 			w.Write([]string{txn.AccountName, "", txn.Date.String(), txn.PayeeName, "", cgroup, txn.CategoryName.String, txn.Memo, outflow, inflow, string(txn.Cleared)})
 		}
 	}
+	if snap != nil {
+		sinceDate := startTime.Format("2006-01-02")
+		for _, txn := range snap.TransactionList() {
+			if txn.Date.String() >= sinceDate {
+				writeRow(txn)
+			}
+		}
+	} else {
+		// Write rows as the transactions arrive instead of waiting for the
+		// whole list.
+		for txn, err := range client.Budgets(thisBudget.ID).StreamTransactions(ctx, opts) {
+			if err != nil {
+				w.Flush()
+				log.Fatal(err)
+			}
+			writeRow(txn)
+		}
+	}
 	w.Flush()
 	if err := w.Error(); err != nil {
 		log.Fatal(err)
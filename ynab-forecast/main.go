@@ -0,0 +1,137 @@
+// The ynab-forecast command projects the daily balance of each on-budget cash
+// account, and their total, from the current balances and the scheduled
+// transactions, and flags the first date an account would go below a floor.
+//
+// Use --days to pick how many days to forecast, starting today, and --floor to
+// flag balances below an amount other than zero. --format prints a table
+// (the default), csv or json. Use --budget-name <budget_name> to specify a
+// budget.
+//
+// Set YNAB_TOKEN in your environment with your API token, or use --profile to
+// pick a profile from ~/.config/ynab/config.toml, to configure the client.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/forecast"
+	"github.com/kevinburke/ynab-go/internal/cli"
+)
+
+func main() {
+	budgetName := flag.String("budget-name", "", "Name, name prefix or ID of the budget to forecast")
+	days := flag.Int("days", forecast.DefaultDays, "Number of days to forecast, starting today")
+	floorStr := flag.String("floor", "", "Flag accounts whose balance goes below this amount (default 0)")
+	format := flag.String("format", "table", "Output format: table, csv or json")
+	storeDir := flag.String("store", "", "Directory, or SQLite database ending in .db, to save plan data in. Later runs only download changes")
+	offline := flag.Bool("offline", false, "Read plan data from --store without contacting YNAB")
+	cacheDir := flag.String("cache", "", "Directory to cache responses that rarely change in, like the plan list")
+	profile := flag.String("profile", "", "Profile in ~/.config/ynab/config.toml to read the access token from (default $YNAB_PROFILE)")
+	flag.Parse()
+	if *offline && *storeDir == "" {
+		log.Fatal("--offline requires --store")
+	}
+	if *days <= 0 {
+		log.Fatal("--days must be positive")
+	}
+	switch *format {
+	case "table", "csv", "json":
+	default:
+		log.Fatalf("unknown format %q, use table, csv or json", *format)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	client, thisBudget, err := cli.OpenPlan(ctx, *profile, *budgetName, *cacheDir, *storeDir, *offline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	formatter = thisBudget.Formatter()
+	var floor int64
+	if *floorStr != "" {
+		m, err := formatter.ParseAmount(*floorStr)
+		if err != nil {
+			log.Fatalf("invalid --floor: %v", err)
+		}
+		floor = int64(m)
+	}
+
+	var accounts []*ynab.Account
+	var scheduled []*ynab.ScheduledTransaction
+	if *storeDir != "" {
+		snap, err := cli.Snapshot(ctx, client, *storeDir, thisBudget, *offline)
+		if err != nil {
+			log.Fatal(err)
+		}
+		accounts = snap.AccountList()
+		scheduled = snap.ScheduledTransactionList()
+	} else {
+		plan := client.Plans(thisBudget.ID)
+		accountResp, err := plan.ListAccounts(ctx, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		scheduledResp, err := plan.ListScheduledTransactions(ctx, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		accounts, scheduled = accountResp.Data.Accounts, scheduledResp.Data.ScheduledTransactions
+	}
+	f, err := forecast.Compute(accounts, scheduled, &forecast.Options{Days: *days, Floor: floor})
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f); err != nil {
+			log.Fatal(err)
+		}
+	case "csv":
+		printCSV(f)
+	default:
+		printTable(f)
+	}
+}
+
+func printCSV(f *forecast.Forecast) {
+	amount := func(m int64) string { return cli.PlainAmount(m, formatter.CurrencyFormat.DecimalDigits) }
+	header, rows := cli.ForecastRows(f, amount, ynab.Date.String)
+	w := csv.NewWriter(os.Stdout)
+	w.Write(header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printTable(f *forecast.Forecast) {
+	header, rows := cli.ForecastRows(f, amt, formatter.Date)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+	fmt.Println()
+	for _, line := range cli.ForecastSummary(f, amt, formatter.Date) {
+		fmt.Println(line)
+	}
+}
+
+// formatter formats amounts and dates for the selected plan.
+var formatter = ynab.NewFormatter(ynab.DefaultPlanSettings)
+
+func amt(amount int64) string {
+	return formatter.Amount(ynab.Milliunits(amount))
+}
@@ -14,24 +14,14 @@ import (
 	"fmt"
 	"log"
 	"net/url"
-	"os"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/kevinburke/ynab-go"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
+	"github.com/kevinburke/ynab-go/internal/cli"
 )
 
-func getBudgets(client *ynab.Client) ([]*ynab.Budget, error) {
-	budgetResp, err := client.GetBudgets(context.TODO(), url.Values{})
-	if err != nil {
-		return nil, err
-	}
-	return budgetResp.Data.Budgets, nil
-}
-
 func getAccounts(client *ynab.Client, budgetID string) ([]*ynab.Account, error) {
 	accountResp, err := client.Budgets(budgetID).Accounts(context.TODO(), url.Values{})
 	if err != nil {
@@ -82,52 +72,47 @@ func isBlackBox(accountMap map[string]*ynab.Account, tx *ynab.Transaction) bool
 }
 
 func main() {
-	budgetName := flag.String("budget-name", "", "Name of the budget to compute inputs and outputs for")
+	budgetName := flag.String("budget-name", "", "Name, name prefix or ID of the budget to compute inputs and outputs for")
 	exclude := flag.String("exclude", "", "Comma separated list of accounts to exclude")
 	monthStr := flag.String("month", "", "Month to print inputs and outputs for")
 	yearStr := flag.String("year", "", "Year to print inputs and outputs for")
+	storeDir := flag.String("store", "", "Directory, or SQLite database ending in .db, to save plan data in. Later runs only download changes")
+	offline := flag.Bool("offline", false, "Read plan data from --store without contacting YNAB")
+	cacheDir := flag.String("cache", "", "Directory to cache responses that rarely change in, like the plan list")
+	profile := flag.String("profile", "", "Profile in ~/.config/ynab/config.toml to read the access token from (default $YNAB_PROFILE)")
 	flag.Parse()
-	token, ok := os.LookupEnv("YNAB_TOKEN")
-	if !ok {
-		log.Fatal("please set YNAB_TOKEN in the environment: https://app.youneedabudget.com/settings")
+	if *offline && *storeDir == "" {
+		log.Fatal("--offline requires --store")
 	}
-	client := ynab.NewClient(token)
-	budgets, err := getBudgets(client)
+	client, thisBudget, err := cli.OpenPlan(context.TODO(), *profile, *budgetName, *cacheDir, *storeDir, *offline)
 	if err != nil {
 		log.Fatal(err)
 	}
+	formatter = thisBudget.Formatter()
 
-	var thisBudget *ynab.Budget
-	if len(budgets) == 1 {
-		thisBudget = budgets[0]
-	} else {
-		if *budgetName == "" {
-			log.Fatal("please use --budget-name to tell us which budget to calculate!")
+	var accounts []*ynab.Account
+	var txns []*ynab.Transaction
+	if *storeDir != "" {
+		snap, err := cli.Snapshot(context.TODO(), client, *storeDir, thisBudget, *offline)
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		for _, budget := range budgets {
-			if budget.Name == *budgetName {
-				thisBudget = budget
-				break
-			}
+		accounts = snap.AccountList()
+		txns = snap.TransactionList()
+	} else {
+		accounts, err = getAccounts(client, thisBudget.ID)
+		if err != nil {
+			log.Fatal(err)
 		}
-		if thisBudget == nil {
-			log.Fatalf("could not find budget with name %q, please double check!", *budgetName)
+		txns, err = getTransactions(client, thisBudget.ID)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
-
-	accounts, err := getAccounts(client, thisBudget.ID)
-	if err != nil {
-		log.Fatal(err)
-	}
 	accountMap := make(map[string]*ynab.Account, len(accounts))
 	for _, account := range accounts {
 		accountMap[account.ID] = account
 	}
-	txns, err := getTransactions(client, thisBudget.ID)
-	if err != nil {
-		log.Fatal(err)
-	}
 	var month, endOfMonth time.Time
 	if *monthStr != "" && *yearStr != "" {
 		log.Fatalf("can't specify both --month and --year")
@@ -200,11 +185,11 @@ func main() {
 		return outflows[i].Amount < outflows[j].Amount
 	})
 	if *monthStr != "" {
-		fmt.Println("Month Balance: $" + amt(runningTotal))
+		fmt.Println("Month Balance: " + amt(runningTotal))
 	} else if *yearStr != "" {
-		fmt.Println("Year Balance: $" + amt(runningTotal))
+		fmt.Println("Year Balance: " + amt(runningTotal))
 	}
-	fmt.Printf("\nInflows: $%s\n================================\n", amt(inflowSum))
+	fmt.Printf("\nInflows: %s\n================================\n", amt(inflowSum))
 	count := 0
 	runningInflow := int64(0)
 	for i := range inflows {
@@ -217,7 +202,7 @@ func main() {
 			memo = fmt.Sprintf("%q", tx.Memo)
 			payeeFmt = "%q"
 		}
-		fmt.Printf("%s %10s %10s %-22s "+payeeFmt+" %s\n", tx.Date.String(), "$"+amt(tx.Amount), "$"+amt(runningInflow), tx.AccountName, payee, memo)
+		fmt.Printf("%s %10s %10s %-22s "+payeeFmt+" %s\n", formatter.Date(tx.Date), amt(tx.Amount), amt(runningInflow), tx.AccountName, payee, memo)
 		count++
 		if count > 10 && tx.Amount < 100*1000 {
 			break
@@ -225,7 +210,7 @@ func main() {
 	}
 	count = 0
 	runningOutflow := int64(0)
-	fmt.Printf("\nOutflows: $%s\n================================\n", amt(-1*outflowSum))
+	fmt.Printf("\nOutflows: %s\n================================\n", amt(-1*outflowSum))
 	for i := range outflows {
 		tx := outflows[i]
 		runningOutflow += tx.Amount
@@ -236,7 +221,7 @@ func main() {
 			memo = fmt.Sprintf("%q", tx.Memo)
 			payeeFmt = "%q"
 		}
-		fmt.Printf("%s %10s %10s %-22s "+payeeFmt+" %s\n", tx.Date.String(), "$"+amt(-1*tx.Amount), "$"+amt(-1*runningOutflow), tx.AccountName, payee, memo)
+		fmt.Printf("%s %10s %10s %-22s "+payeeFmt+" %s\n", formatter.Date(tx.Date), amt(-1*tx.Amount), amt(-1*runningOutflow), tx.AccountName, payee, memo)
 		count++
 		if count > 10 && (-1*tx.Amount) < 100*1000 {
 			break
@@ -244,8 +229,9 @@ func main() {
 	}
 }
 
-var p = message.NewPrinter(language.English)
+// formatter formats amounts and dates for the selected plan.
+var formatter = ynab.NewFormatter(ynab.DefaultPlanSettings)
 
 func amt(amount int64) string {
-	return p.Sprintf("%.2f", float64(amount)/1000)
+	return formatter.Amount(ynab.Milliunits(amount))
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/ageofmoney"
+)
+
+func runAgeOfMoney(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	includeScheduledIncome := fs.Bool("include-scheduled-income", false, "Include scheduled income in the projection")
+	weighted := fs.Bool("weighted", false, "Age each spend by the weighted average of the buckets it spends from, like YNAB")
+	simulate := fs.String("simulate", "", "Project the age of money week by week with the what-if scenario in this YAML file")
+	verify := fs.Bool("verify", false, "Compare the age of money at the end of each month to the one YNAB reports")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	accounts, err := a.accounts(ctx)
+	if err != nil {
+		return err
+	}
+	txns, err := a.transactions(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	scheduled, err := a.scheduledTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if *simulate != "" {
+		return a.simulateAgeOfMoney(accounts, txns, scheduled, *simulate)
+	}
+	res, err := ageofmoney.Compute(accounts, txns, scheduled, &ageofmoney.Options{
+		IncludeScheduledIncome: *includeScheduledIncome,
+	})
+	if err != nil {
+		return err
+	}
+	if *verify {
+		return a.verifyAgeOfMoney(ctx, res)
+	}
+	t := &table{header: []string{"Age", "Earned", "Spent", "Amount", "Account", "Payee"}}
+	spends := func(section string, spending []*ageofmoney.Spend) {
+		for _, s := range spending {
+			days, earned := "N/A", ""
+			if !s.Unfunded {
+				days, earned = strconv.Itoa(s.Age), a.date(s.Earned)
+				if *weighted {
+					days = strconv.Itoa(int(math.Round(s.WeightedAge)))
+				}
+			}
+			t.addTo(section, days, earned, a.date(s.Date), a.amount(s.Amount), s.AccountName, clean(s.PayeeName))
+		}
+	}
+	spends("Spending", res.Spending)
+	for _, th := range res.Thresholds {
+		b := th.Bucket
+		t.addTo("Upcoming spending thresholds (and age if you spent today)", strconv.Itoa(th.Age), a.date(b.Date), "", a.amount(th.Cumulative), b.AccountName, clean(b.PayeeName))
+	}
+	spends("Projected age of scheduled transactions", res.Scheduled)
+	return a.print(t, res)
+}
+
+// verifyAgeOfMoney prints the age of money in res at the end of each month
+// next to the one YNAB reports.
+func (a *app) verifyAgeOfMoney(ctx context.Context, res *ageofmoney.Result) error {
+	months, err := a.months(ctx)
+	if err != nil {
+		return err
+	}
+	ages, err := res.Verify(months)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"Month", "Computed", "YNAB", "Drift"}}
+	for _, m := range ages {
+		t.add(m.Month.String()[:7], strconv.FormatFloat(m.Computed, 'f', 1, 64), strconv.Itoa(m.Reported), fmt.Sprintf("%+d", m.Drift))
+	}
+	return a.print(t, ages)
+}
+
+// simulateAgeOfMoney prints the age of money week by week in the scenario in
+// the file at path.
+func (a *app) simulateAgeOfMoney(accounts []*ynab.Account, txns []*ynab.Transaction, scheduled []*ynab.ScheduledTransaction, path string) error {
+	scenario, err := ageofmoney.LoadScenario(path)
+	if err != nil {
+		return err
+	}
+	sim, err := ageofmoney.Simulate(accounts, txns, scheduled, scenario)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"Week", "Age", "Unfunded"}}
+	for _, w := range sim.Weeks {
+		t.add(a.date(w.Date), strconv.Itoa(int(math.Round(w.Age))), a.amount(w.Unfunded))
+	}
+	if err := a.print(t, sim); err != nil {
+		return err
+	}
+	if a.format != "table" || scenario.Target == 0 {
+		return nil
+	}
+	if sim.Reached != nil {
+		_, err = fmt.Fprintf(a.stdout, "\nAge of money reaches %g days the week of %s.\n", scenario.Target, a.date(sim.Reached.Date))
+	} else if len(sim.Weeks) > 0 {
+		_, err = fmt.Fprintf(a.stdout, "\nAge of money doesn't reach %g days by %s.\n", scenario.Target, a.date(sim.Weeks[len(sim.Weeks)-1].Date))
+	}
+	return err
+}
+
+func clean(payee string) string {
+	return strings.ReplaceAll(payee, "Transfer :", "Transfer:")
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/credentials"
+	"github.com/kevinburke/ynab-go/internal/cli"
+)
+
+// app holds the global flags and the state shared by every command.
+type app struct {
+	plan      string
+	format    string
+	profile   string
+	tokenEnv  string
+	tokenFile string
+	storeDir  string
+	offline   bool
+	cacheDir  string
+	baseURL   string
+
+	stdout io.Writer
+	cmd    *command
+	client *ynab.Client // nil with --offline
+	// The selected plan and its formatter, set by start if the command
+	// needs a plan.
+	selected  *ynab.Plan
+	formatter *ynab.Formatter
+	snap      *ynab.PlanSnapshot
+}
+
+func newApp(stdout io.Writer) *app {
+	return &app{
+		plan:      os.Getenv("YNAB_PLAN"),
+		tokenEnv:  credentials.TokenEnv,
+		baseURL:   ynab.DefaultBaseURL,
+		stdout:    stdout,
+		formatter: ynab.NewFormatter(ynab.DefaultPlanSettings),
+	}
+}
+
+// register adds the global flags to fs. Each flag defaults to its current
+// value, so flags given before the command name survive the command's own
+// FlagSet.
+func (a *app) register(fs *flag.FlagSet) {
+	fs.StringVar(&a.plan, "plan", a.plan, `Name, name prefix or ID of the plan, or "default" or "last-used"`)
+	fs.StringVar(&a.format, "format", a.format, "Output format: table, json or csv (default table, or csv for export)")
+	fs.StringVar(&a.profile, "profile", a.profile, "Profile in ~/.config/ynab/config.toml to read the access token from (default $YNAB_PROFILE)")
+	fs.StringVar(&a.tokenEnv, "token-env", a.tokenEnv, "Environment variable to read the access token from")
+	fs.StringVar(&a.tokenFile, "token-file", a.tokenFile, "File to read the access token from")
+	fs.StringVar(&a.storeDir, "store", a.storeDir, "Directory, or SQLite database ending in .db, to save plan data in. Later runs only download changes")
+	fs.BoolVar(&a.offline, "offline", a.offline, "Read plan data from --store without contacting YNAB")
+	fs.StringVar(&a.cacheDir, "cache", a.cacheDir, "Directory to cache responses that rarely change in, like the plan list")
+	fs.StringVar(&a.baseURL, "base-url", a.baseURL, "URL of the YNAB API")
+}
+
+func (a *app) run(ctx context.Context, cmd *command, args []string) error {
+	a.cmd = cmd
+	return cmd.run(ctx, a, args)
+}
+
+// start parses the command's flags, connects to YNAB and, if the command
+// needs one, selects the plan. Commands call it once they have added their
+// own flags to fs.
+func (a *app) start(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if a.format == "" {
+		a.format = cmp.Or(a.cmd.format, "table")
+	}
+	switch a.format {
+	case "table", "json", "csv":
+	default:
+		return usageError(fs, "unknown format %q, use table, json or csv", a.format)
+	}
+	if a.offline && a.storeDir == "" {
+		return usageError(fs, "--offline requires --store")
+	}
+	if !a.offline {
+		creds, err := a.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		opts := []ynab.ClientOption{ynab.WithBaseURL(a.baseURL)}
+		if a.cacheDir != "" {
+			opts = append(opts, ynab.WithCache(ynab.NewCache(ynab.NewDiskCache(a.cacheDir))))
+		}
+		a.client = ynab.NewClient(creds.Token, opts...)
+		if err := creds.Check(ctx, a.client); err != nil {
+			return err
+		}
+		a.plan = cmp.Or(a.plan, creds.Plan)
+	}
+	if !a.cmd.plan {
+		return nil
+	}
+	var err error
+	a.selected, err = cli.ResolvePlan(ctx, a.client, a.storeDir, a.plan)
+	var planErr *ynab.PlanError
+	if errors.As(err, &planErr) && planErr.Ambiguous {
+		return fmt.Errorf("%w\nuse --plan or $YNAB_PLAN to choose one", err)
+	}
+	if err != nil {
+		return err
+	}
+	a.formatter = a.selected.Formatter()
+	if a.storeDir != "" {
+		a.snap, err = cli.Snapshot(ctx, a.client, a.storeDir, a.selected, a.offline)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// credentials returns the access token from --token-file, or else from the
+// profile or environment variable as described in the credentials package.
+func (a *app) credentials(ctx context.Context) (*credentials.Credentials, error) {
+	if a.tokenFile != "" {
+		data, err := os.ReadFile(a.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		return &credentials.Credentials{Token: strings.TrimSpace(string(data)), Source: a.tokenFile}, nil
+	}
+	l := &credentials.Loader{Profile: a.profile, Env: a.tokenEnv}
+	return l.Load(ctx)
+}
+
+// online returns an error if the command can't run with --offline.
+func (a *app) online() error {
+	if a.client == nil {
+		return fmt.Errorf("can't %s with --offline", a.cmd.short)
+	}
+	return nil
+}
+
+func (a *app) service() *ynab.PlanService {
+	return a.client.Plans(a.selected.ID)
+}
+
+// The following methods return data for the selected plan, from the store if
+// --store is set and from the API otherwise. Deleted entities are left out.
+
+func (a *app) accounts(ctx context.Context) ([]*ynab.Account, error) {
+	var accounts []*ynab.Account
+	if a.snap != nil {
+		accounts = a.snap.AccountList()
+	} else {
+		resp, err := a.service().ListAccounts(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		accounts = resp.Data.Accounts
+	}
+	return without(accounts, func(acct *ynab.Account) bool { return acct.Deleted }), nil
+}
+
+func (a *app) categoryGroups(ctx context.Context) ([]*ynab.CategoryGroup, error) {
+	var groups []*ynab.CategoryGroup
+	if a.snap != nil {
+		groups = a.snap.CategoryGroupList()
+	} else {
+		resp, err := a.service().ListCategories(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		groups = resp.Data.CategoryGroups
+	}
+	groups = without(groups, func(g *ynab.CategoryGroup) bool { return g.Deleted })
+	for _, g := range groups {
+		g.Categories = without(g.Categories, func(c *ynab.Category) bool { return c.Deleted })
+	}
+	return groups, nil
+}
+
+func (a *app) payees(ctx context.Context) ([]*ynab.Payee, error) {
+	var payees []*ynab.Payee
+	if a.snap != nil {
+		for _, p := range a.snap.Payees {
+			payees = append(payees, p)
+		}
+	} else {
+		resp, err := a.service().ListPayees(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		payees = resp.Data.Payees
+	}
+	slices.SortFunc(payees, func(a, b *ynab.Payee) int { return strings.Compare(a.Name, b.Name) })
+	return without(payees, func(p *ynab.Payee) bool { return p.Deleted }), nil
+}
+
+func (a *app) months(ctx context.Context) ([]*ynab.MonthSummary, error) {
+	var months []*ynab.MonthSummary
+	if a.snap != nil {
+		for _, m := range a.snap.Months {
+			months = append(months, m)
+		}
+	} else {
+		resp, err := a.service().ListMonths(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		months = resp.Data.Months
+	}
+	slices.SortFunc(months, func(a, b *ynab.MonthSummary) int { return strings.Compare(a.Month, b.Month) })
+	return without(months, func(m *ynab.MonthSummary) bool { return m.Deleted }), nil
+}
+
+func (a *app) scheduledTransactions(ctx context.Context) ([]*ynab.ScheduledTransaction, error) {
+	var txns []*ynab.ScheduledTransaction
+	if a.snap != nil {
+		txns = a.snap.ScheduledTransactionList()
+	} else {
+		resp, err := a.service().ListScheduledTransactions(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		txns = resp.Data.ScheduledTransactions
+	}
+	return without(txns, func(t *ynab.ScheduledTransaction) bool { return t.Deleted }), nil
+}
+
+// transactions returns the transactions on or after since, which may be zero.
+func (a *app) transactions(ctx context.Context, since time.Time) ([]*ynab.Transaction, error) {
+	var txns []*ynab.Transaction
+	if a.snap != nil {
+		for _, txn := range a.snap.TransactionList() {
+			if !time.Time(txn.Date).Before(since) {
+				txns = append(txns, txn)
+			}
+		}
+	} else {
+		resp, err := a.service().ListTransactions(ctx, &ynab.TransactionListOptions{SinceDate: ynab.Date(since)})
+		if err != nil {
+			return nil, err
+		}
+		txns = resp.Data.Transactions
+	}
+	return without(txns, func(t *ynab.Transaction) bool { return t.Deleted }), nil
+}
+
+// without returns the elements of s for which drop returns false.
+func without[T any](s []*T, drop func(*T) bool) []*T {
+	out := s[:0:0]
+	for _, v := range s {
+		if !drop(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// findAccount returns the account whose ID or name, ignoring case, is
+// nameOrID.
+func findAccount(accounts []*ynab.Account, nameOrID string) (*ynab.Account, error) {
+	var found []*ynab.Account
+	for _, acct := range accounts {
+		switch {
+		case acct.ID == nameOrID:
+			return acct, nil
+		case strings.EqualFold(acct.Name, nameOrID):
+			found = append(found, acct)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no account named %q", nameOrID)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("more than one account is named %q, use its ID", nameOrID)
+}
+
+// findCategory returns the category whose ID or name, ignoring case, is
+// nameOrID. "Group: Category" picks a category in a specific group.
+func findCategory(groups []*ynab.CategoryGroup, nameOrID string) (*ynab.Category, error) {
+	group, name, ok := strings.Cut(nameOrID, ":")
+	var found []*ynab.Category
+	for _, g := range groups {
+		for _, c := range g.Categories {
+			switch {
+			case c.ID == nameOrID:
+				return c, nil
+			case strings.EqualFold(c.Name, nameOrID),
+				ok && strings.EqualFold(g.Name, strings.TrimSpace(group)) && strings.EqualFold(c.Name, strings.TrimSpace(name)):
+				found = append(found, c)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no category named %q", nameOrID)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("more than one category is named %q, use \"Group: Category\"", nameOrID)
+}
+
+// parseDate parses a date given as YYYY-MM-DD, in local time like the dates
+// in API responses.
+func parseDate(s string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// parseMonth parses a month given as YYYY-MM.
+func parseMonth(s string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, use YYYY-MM", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+func TestStartSelectsPlan(t *testing.T) {
+	s, personal := newTestServer(t)
+
+	// With one plan, it's used without --plan.
+	a, _, err := runYNAB(s, "accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.selected.ID != personal.ID {
+		t.Errorf("got plan %q, want Personal", a.selected.Name)
+	}
+
+	business := s.AddPlan("Business")
+	if _, _, err := runYNAB(s, "accounts"); err == nil || !strings.Contains(err.Error(), "use --plan or $YNAB_PLAN") {
+		t.Errorf("expected an ambiguous plan error, got %v", err)
+	}
+	for _, plan := range []string{"Business", "busi", business.ID} {
+		a, _, err := runYNAB(s, "accounts", "--plan="+plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.selected.ID != business.ID {
+			t.Errorf("--plan=%s: got plan %q, want Business", plan, a.selected.Name)
+		}
+	}
+	t.Setenv("YNAB_PLAN", "Business")
+	if a, _, err := runYNAB(s, "accounts"); err != nil || a.selected.ID != business.ID {
+		t.Errorf("expected $YNAB_PLAN to select Business, got %v", err)
+	}
+	if _, _, err := runYNAB(s, "accounts", "--plan=Savings"); err == nil {
+		t.Error("expected an error for a plan that doesn't exist")
+	}
+}
+
+func TestStartFormat(t *testing.T) {
+	s, _ := newTestServer(t)
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"accounts"}, "table"},
+		{[]string{"export"}, "csv"},
+		{[]string{"export", "--format=json"}, "json"},
+		{[]string{"--format=csv", "accounts"}, "csv"},
+	} {
+		a, _, err := runYNAB(s, tt.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.format != tt.want {
+			t.Errorf("%q: got format %q, want %q", tt.args, a.format, tt.want)
+		}
+	}
+	if _, _, err := runYNAB(s, "accounts", "--format=xml"); err != errUsage {
+		t.Errorf("expected a usage error for --format=xml, got %v", err)
+	}
+	if _, _, err := runYNAB(s, "accounts", "--offline"); err != errUsage {
+		t.Errorf("expected a usage error for --offline without --store, got %v", err)
+	}
+}
+
+func TestFindAccount(t *testing.T) {
+	accounts := []*ynab.Account{
+		{ID: "acct-1", Name: "Checking"},
+		{ID: "acct-2", Name: "Savings"},
+		{ID: "acct-3", Name: "savings"},
+	}
+	for _, tt := range []struct {
+		nameOrID, wantID, wantErr string
+	}{
+		{"checking", "acct-1", ""},
+		{"acct-3", "acct-3", ""},
+		{"Savings", "", `more than one account is named "Savings"`},
+		{"Brokerage", "", `no account named "Brokerage"`},
+	} {
+		acct, err := findAccount(accounts, tt.nameOrID)
+		switch {
+		case tt.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("findAccount(%q): got error %v, want %q", tt.nameOrID, err, tt.wantErr)
+			}
+		case err != nil:
+			t.Errorf("findAccount(%q): %v", tt.nameOrID, err)
+		case acct.ID != tt.wantID:
+			t.Errorf("findAccount(%q): got %s, want %s", tt.nameOrID, acct.ID, tt.wantID)
+		}
+	}
+}
+
+func TestFindCategory(t *testing.T) {
+	groups := []*ynab.CategoryGroup{
+		{Name: "Everyday", Categories: []*ynab.Category{{ID: "cat-1", Name: "Groceries"}, {ID: "cat-2", Name: "Gifts"}}},
+		{Name: "Holidays", Categories: []*ynab.Category{{ID: "cat-3", Name: "Gifts"}}},
+	}
+	for _, tt := range []struct {
+		nameOrID, wantID, wantErr string
+	}{
+		{"groceries", "cat-1", ""},
+		{"cat-3", "cat-3", ""},
+		{"Holidays: Gifts", "cat-3", ""},
+		{"everyday:gifts", "cat-2", ""},
+		{"Gifts", "", `more than one category is named "Gifts", use "Group: Category"`},
+		{"Rent", "", `no category named "Rent"`},
+		{"Bills: Groceries", "", `no category named "Bills: Groceries"`},
+	} {
+		c, err := findCategory(groups, tt.nameOrID)
+		switch {
+		case tt.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("findCategory(%q): got error %v, want %q", tt.nameOrID, err, tt.wantErr)
+			}
+		case err != nil:
+			t.Errorf("findCategory(%q): %v", tt.nameOrID, err)
+		case c.ID != tt.wantID:
+			t.Errorf("findCategory(%q): got %s, want %s", tt.nameOrID, c.ID, tt.wantID)
+		}
+	}
+}
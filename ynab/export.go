@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// exportHeader is the header of the CSV file YNAB's "Export plan data"
+// produces.
+var exportHeader = []string{"Account", "Flag", "Date", "Payee", "Category Group/Category", "Category Group", "Category", "Memo", "Outflow", "Inflow", "Cleared"}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	since := fs.String("since", "", "Only export transactions on or after this date (YYYY-MM-DD)")
+	category := fs.String("category", "", "Only export transactions in this category or category group")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = parseDate(*since); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+	groups, err := a.categoryGroups(ctx)
+	if err != nil {
+		return err
+	}
+	groupOf := make(map[string]string)
+	for _, g := range groups {
+		if g.Hidden {
+			continue
+		}
+		for _, c := range g.Categories {
+			groupOf[c.ID] = g.Name
+		}
+	}
+	row := func(txn *ynab.Transaction) []string {
+		var outflow, inflow string
+		if txn.Amount < 0 {
+			outflow = a.amount(int64(txn.Outflow()))
+		} else {
+			inflow = a.amount(int64(txn.Inflow()))
+		}
+		group := groupOf[txn.CategoryID.String]
+		var groupCategory string
+		if group != "" {
+			groupCategory = group + ": " + txn.CategoryName.String
+		}
+		return []string{txn.AccountName, string(txn.FlagColor), a.date(txn.Date), txn.PayeeName, groupCategory, group, txn.CategoryName.String, txn.Memo, outflow, inflow, string(txn.Cleared)}
+	}
+	keep := func(txn *ynab.Transaction) bool {
+		return *category == "" || txn.CategoryName.String == *category || groupOf[txn.CategoryID.String] == *category
+	}
+
+	if a.format != "csv" || a.snap != nil {
+		txns, err := a.transactions(ctx, from)
+		if err != nil {
+			return err
+		}
+		txns = without(txns, func(txn *ynab.Transaction) bool { return !keep(txn) })
+		t := &table{header: exportHeader}
+		for _, txn := range txns {
+			t.add(row(txn)...)
+		}
+		return a.print(t, txns)
+	}
+	// Write rows as the transactions arrive instead of waiting for the whole
+	// list.
+	w := csv.NewWriter(a.stdout)
+	w.Write(exportHeader)
+	opts := &ynab.TransactionListOptions{SinceDate: ynab.Date(from)}
+	for txn, err := range a.service().StreamTransactions(ctx, opts) {
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		if !txn.Deleted && keep(txn) {
+			w.Write(row(txn))
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// A flow is a transaction that changes net worth.
+type flow struct {
+	Date    ynab.Date `json:"date"`
+	Amount  int64     `json:"amount"`
+	Running int64     `json:"running_total"`
+	Account string    `json:"account"`
+	Payee   string    `json:"payee"`
+	Memo    string    `json:"memo,omitempty"`
+}
+
+type flows struct {
+	Balance  int64  `json:"balance"`
+	Inflow   int64  `json:"inflow"`
+	Outflow  int64  `json:"outflow"`
+	Inflows  []flow `json:"inflows"`
+	Outflows []flow `json:"outflows"`
+}
+
+func runFlows(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	monthStr := fs.String("month", "", "Only count transactions in this month (YYYY-MM)")
+	yearStr := fs.String("year", "", "Only count transactions in this year (YYYY)")
+	exclude := fs.String("exclude", "", "Comma separated list of accounts to exclude")
+	limit := fs.Int("limit", 10, "Print at least this many inflows and outflows, and then any over 100 in the plan's currency")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	var from, to time.Time
+	switch {
+	case *monthStr != "" && *yearStr != "":
+		return usageError(fs, "can't specify both --month and --year")
+	case *monthStr != "":
+		month, err := parseMonth(*monthStr)
+		if err != nil {
+			return usageError(fs, "%v", err)
+		}
+		from, to = month, month.AddDate(0, 1, 0)
+	case *yearStr != "":
+		year, err := time.ParseInLocation("2006", *yearStr, time.Local)
+		if err != nil {
+			return usageError(fs, "invalid year %q, use YYYY", *yearStr)
+		}
+		from, to = year, year.AddDate(1, 0, 0)
+	}
+	accounts, err := a.accounts(ctx)
+	if err != nil {
+		return err
+	}
+	txns, err := a.transactions(ctx, from)
+	if err != nil {
+		return err
+	}
+	excluded := make(map[string]bool)
+	for name := range strings.SplitSeq(*exclude, ",") {
+		if name != "" {
+			excluded[name] = true
+		}
+	}
+	f, err := computeFlows(accounts, txns, excluded, to)
+	if err != nil {
+		return err
+	}
+	// Like ynab-largest-inputs-outputs, print the largest flows, then any
+	// that are still large.
+	trim := func(fl []flow) []flow {
+		for i, x := range fl {
+			if i >= *limit && abs(x.Amount) < 100*1000 {
+				return fl[:i]
+			}
+		}
+		return fl
+	}
+	f.Inflows, f.Outflows = trim(f.Inflows), trim(f.Outflows)
+
+	t := &table{header: []string{"Date", "Amount", "Running total", "Account", "Payee", "Memo"}}
+	inflowSection := fmt.Sprintf("Inflows: %s", a.amount(f.Inflow))
+	outflowSection := fmt.Sprintf("Outflows: %s", a.amount(f.Outflow))
+	if a.format == "csv" {
+		inflowSection, outflowSection = "Inflows", "Outflows"
+	}
+	for _, x := range f.Inflows {
+		t.addTo(inflowSection, a.date(x.Date), a.amount(x.Amount), a.amount(x.Running), x.Account, x.Payee, x.Memo)
+	}
+	for _, x := range f.Outflows {
+		t.addTo(outflowSection, a.date(x.Date), a.amount(x.Amount), a.amount(x.Running), x.Account, x.Payee, x.Memo)
+	}
+	if a.format == "table" && !from.IsZero() {
+		fmt.Fprintf(a.stdout, "Balance: %s\n\n", a.amount(f.Balance))
+	}
+	return a.print(t, f)
+}
+
+// computeFlows returns the transactions that change net worth, largest first,
+// from txns before until, which may be zero. Credit card spending counts when
+// the card is paid, not when the money is spent.
+func computeFlows(accounts []*ynab.Account, txns []*ynab.Transaction, excluded map[string]bool, until time.Time) (*flows, error) {
+	accountMap := make(map[string]*ynab.Account, len(accounts))
+	for _, acct := range accounts {
+		accountMap[acct.ID] = acct
+	}
+	f := new(flows)
+	for _, tx := range txns {
+		if excluded[tx.AccountName] || tx.Amount == 0 {
+			continue
+		}
+		if !until.IsZero() && !time.Time(tx.Date).Before(until) {
+			continue
+		}
+		ok, err := changesNetWorth(accountMap, tx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		f.Balance += tx.Amount
+		x := flow{Date: tx.Date, Amount: tx.Amount, Account: tx.AccountName, Payee: strings.ReplaceAll(tx.PayeeName, " : ", ": "), Memo: tx.Memo}
+		if tx.Amount > 0 {
+			f.Inflows = append(f.Inflows, x)
+			f.Inflow += tx.Amount
+		} else {
+			x.Amount = -x.Amount
+			f.Outflows = append(f.Outflows, x)
+			f.Outflow -= tx.Amount
+		}
+	}
+	for _, fl := range [][]flow{f.Inflows, f.Outflows} {
+		sort.SliceStable(fl, func(i, j int) bool { return fl[i].Amount > fl[j].Amount })
+		running := int64(0)
+		for i := range fl {
+			running += fl[i].Amount
+			fl[i].Running = running
+		}
+	}
+	return f, nil
+}
+
+// changesNetWorth reports whether tx moves money into or out of the plan, as
+// opposed to between its accounts.
+func changesNetWorth(accounts map[string]*ynab.Account, tx *ynab.Transaction) (bool, error) {
+	acct, ok := accounts[tx.AccountID]
+	if !ok {
+		return false, fmt.Errorf("unknown account %q", tx.AccountID)
+	}
+	var transfer *ynab.Account
+	if tx.TransferAccountID.Valid {
+		if transfer, ok = accounts[tx.TransferAccountID.String]; !ok {
+			return false, fmt.Errorf("unknown account %q", tx.TransferAccountID.String)
+		}
+	}
+	if acct.CashBacked() {
+		return transfer == nil || (transfer.Type == "creditCard" && tx.Amount < 0), nil
+	}
+	if transfer != nil {
+		return false, nil
+	}
+	// spending on a credit card is counted when the card is paid
+	return !acct.OnBudget || tx.Amount >= 0, nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kevinburke/ynab-go/forecast"
+	"github.com/kevinburke/ynab-go/internal/cli"
+)
+
+func runForecast(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	days := fs.Int("days", forecast.DefaultDays, "Number of days to forecast, starting today")
+	floorStr := fs.String("floor", "", "Flag accounts whose balance goes below this amount (default 0)")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	if *days <= 0 {
+		return usageError(fs, "--days must be positive")
+	}
+	var floor int64
+	if *floorStr != "" {
+		m, err := a.formatter.ParseAmount(*floorStr)
+		if err != nil {
+			return usageError(fs, "invalid --floor: %v", err)
+		}
+		floor = int64(m)
+	}
+	accounts, err := a.accounts(ctx)
+	if err != nil {
+		return err
+	}
+	scheduled, err := a.scheduledTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	f, err := forecast.Compute(accounts, scheduled, &forecast.Options{Days: *days, Floor: floor})
+	if err != nil {
+		return err
+	}
+	header, rows := cli.ForecastRows(f, a.amount, a.date)
+	t := &table{header: header}
+	for _, row := range rows {
+		t.add(row...)
+	}
+	if err := a.print(t, f); err != nil {
+		return err
+	}
+	if a.format != "table" {
+		return nil
+	}
+	fmt.Fprintln(a.stdout)
+	for _, line := range cli.ForecastSummary(f, a.amount, a.date) {
+		fmt.Fprintln(a.stdout, line)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/store"
+)
+
+func runPlans(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	var plans []*ynab.Plan
+	if a.client == nil {
+		st, err := store.Open(ctx, a.storeDir)
+		if err != nil {
+			return err
+		}
+		plans, err = st.Plans(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		resp, err := a.client.GetPlans(ctx, nil)
+		if err != nil {
+			return err
+		}
+		plans = resp.Data.Plans
+	}
+	t := &table{header: []string{"ID", "Name", "Last modified"}}
+	for _, p := range plans {
+		t.add(p.ID, p.Name, p.LastModifiedOn)
+	}
+	return a.print(t, plans)
+}
+
+func runAccounts(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	closed := fs.Bool("closed", false, "Include closed accounts")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	accounts, err := a.accounts(ctx)
+	if err != nil {
+		return err
+	}
+	if !*closed {
+		accounts = without(accounts, func(acct *ynab.Account) bool { return acct.Closed })
+	}
+	t := &table{header: []string{"Name", "Type", "On budget", "Balance", "Cleared", "Uncleared"}}
+	for _, acct := range accounts {
+		t.add(acct.Name, acct.Type, yesNo(acct.OnBudget), a.amount(acct.Balance), a.amount(acct.ClearedBalance), a.amount(acct.UnclearedBalance))
+	}
+	return a.print(t, accounts)
+}
+
+func runCategories(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	hidden := fs.Bool("hidden", false, "Include hidden categories")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	groups, err := a.categoryGroups(ctx)
+	if err != nil {
+		return err
+	}
+	var shown []*ynab.CategoryGroup
+	t := &table{header: []string{"Group", "Category", "Assigned", "Activity", "Available"}}
+	for _, g := range groups {
+		if g.Internal || (g.Hidden && !*hidden) {
+			continue
+		}
+		if !*hidden {
+			g.Categories = without(g.Categories, func(c *ynab.Category) bool { return c.Hidden })
+		}
+		shown = append(shown, g)
+		for _, c := range g.Categories {
+			t.add(g.Name, c.Name, a.amount(c.Budgeted), a.amount(c.Activity), a.amount(c.Balance))
+		}
+	}
+	return a.print(t, shown)
+}
+
+func runPayees(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	payees, err := a.payees(ctx)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"ID", "Name", "Transfer account"}}
+	for _, p := range payees {
+		t.add(p.ID, p.Name, p.TransferAccountID.String)
+	}
+	return a.print(t, payees)
+}
+
+func runScheduled(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	txns, err := a.scheduledTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"Next", "Frequency", "Account", "Payee", "Category", "Amount", "Memo"}}
+	for _, txn := range txns {
+		t.add(a.date(txn.DateNext), string(txn.Frequency), txn.AccountName, txn.PayeeName, txn.CategoryName.String, a.amount(txn.Amount), txn.Memo)
+	}
+	return a.print(t, txns)
+}
+
+func runMonths(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	since := fs.String("since", "", "Only list months from this month on (YYYY-MM)")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = parseMonth(*since); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+	months, err := a.months(ctx)
+	if err != nil {
+		return err
+	}
+	months = without(months, func(m *ynab.MonthSummary) bool {
+		return m.Month < from.Format("2006-01-02")
+	})
+	t := &table{header: []string{"Month", "Income", "Assigned", "Activity", "Ready to assign", "Age of money"}}
+	for _, m := range months {
+		age := ""
+		if m.AgeOfMoney != nil {
+			age = strconv.Itoa(*m.AgeOfMoney)
+		}
+		t.add(m.Month[:7], a.amount(m.Income), a.amount(m.Budgeted), a.amount(m.Activity), a.amount(m.ToBeBudgeted), age)
+	}
+	return a.print(t, months)
+}
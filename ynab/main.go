@@ -0,0 +1,156 @@
+// The ynab command reads and changes YNAB plans from the command line:
+//
+//	ynab [global flags] <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	plans          list plans
+//	accounts       list accounts and their balances
+//	categories     list categories and this month's amounts
+//	payees         list payees
+//	transactions   list, create, update or delete transactions
+//	scheduled      list scheduled transactions
+//	months         list plan months
+//	age-of-money   print the age of money of each transaction
+//	flows          print the largest inputs and outputs to net worth
+//	forecast       forecast the balances of cash accounts from scheduled transactions
+//	export         print transactions in YNAB's CSV export format
+//
+// Global flags can be given before or after the command name. --plan picks the
+// plan by ID, name, unique name prefix, "default" or "last-used"; it defaults
+// to $YNAB_PLAN, or the only plan if there is just one. --format picks table,
+// json or csv output. The access token is read from the file named by
+// --token-file, or else from the --profile in ~/.config/ynab/config.toml or
+// $YNAB_TOKEN (or the variable named by --token-env), as described in the
+// credentials package. A profile can also set the default plan.
+//
+// Dates are always given as YYYY-MM-DD and months as YYYY-MM. Amounts are
+// given in the plan's currency, e.g. -12.34 for an outflow.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+)
+
+// A command is a ynab subcommand.
+type command struct {
+	name string
+	// args is the synopsis of the arguments, e.g. "<transaction-id>".
+	args  string
+	short string
+	// plan is true if the command needs a plan. app.start resolves it.
+	plan bool
+	// format is the default output format, if not table.
+	format string
+	run    func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = []*command{
+	{name: "plans", short: "list plans", run: runPlans},
+	{name: "accounts", short: "list accounts and their balances", plan: true, run: runAccounts},
+	{name: "categories", short: "list categories and this month's amounts", plan: true, run: runCategories},
+	{name: "payees", short: "list payees", plan: true, run: runPayees},
+	{name: "transactions", args: "list|create|update|delete", short: "list, create, update or delete transactions", plan: true, run: runTransactions},
+	{name: "scheduled", short: "list scheduled transactions", plan: true, run: runScheduled},
+	{name: "months", short: "list plan months", plan: true, run: runMonths},
+	{name: "age-of-money", short: "print the age of money of each transaction", plan: true, run: runAgeOfMoney},
+	{name: "flows", short: "print the largest inputs and outputs to net worth", plan: true, run: runFlows},
+	{name: "forecast", short: "forecast the balances of cash accounts from scheduled transactions", plan: true, run: runForecast},
+	{name: "export", short: "print transactions in YNAB's CSV export format", plan: true, format: "csv", run: runExport},
+}
+
+// errUsage is returned by commands when their arguments are wrong. The usage
+// has already been printed.
+var errUsage = errors.New("usage error")
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, "Usage: ynab [global flags] <command> [flags] [arguments]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", c.name, c.args, c.short)
+	}
+	tw.Flush()
+	fmt.Fprint(w, "\nRun \"ynab <command> -h\" for the flags of a command.\n\nGlobal flags:\n")
+	fs := flag.NewFlagSet("ynab", flag.ContinueOnError)
+	fs.SetOutput(w)
+	newApp(w).register(fs)
+	fs.PrintDefaults()
+}
+
+// parseArgs parses the global flags before the command name in args, and
+// returns the command and its arguments. If args are wrong, it prints the
+// usage and returns errUsage.
+func (a *app) parseArgs(args []string) (*command, []string, error) {
+	fs := flag.NewFlagSet("ynab", flag.ContinueOnError)
+	fs.Usage = func() { usage(os.Stderr) }
+	a.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, nil, err
+		}
+		return nil, nil, errUsage
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		usage(os.Stderr)
+		return nil, nil, errUsage
+	}
+	for _, c := range commands {
+		if c.name == fs.Arg(0) {
+			return c, fs.Args()[1:], nil
+		}
+	}
+	fmt.Fprintf(os.Stderr, "ynab: unknown command %q\n\n", fs.Arg(0))
+	usage(os.Stderr)
+	return nil, nil, errUsage
+}
+
+func main() {
+	a := newApp(os.Stdout)
+	cmd, args, err := a.parseArgs(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = a.run(ctx, cmd, args)
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "ynab %s: %s\n", cmd.name, strings.TrimPrefix(err.Error(), "ynab: "))
+		os.Exit(1)
+	}
+}
+
+// flags returns a FlagSet for the current command that also accepts the
+// global flags. synopsis describes the arguments.
+func (a *app) flags(synopsis string) *flag.FlagSet {
+	cmd := a.cmd
+	fs := flag.NewFlagSet("ynab "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ynab %s [flags] %s\n\n%s.\n\nFlags:\n", cmd.name, synopsis, strings.ToUpper(cmd.short[:1])+cmd.short[1:])
+		fs.PrintDefaults()
+	}
+	a.register(fs)
+	return fs
+}
+
+// usageError prints msg and the usage of fs and returns errUsage.
+func usageError(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(fs.Output(), "ynab: "+format+"\n\n", args...)
+	fs.Usage()
+	return errUsage
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/ynabtest"
+)
+
+// newTestServer returns a fake YNAB server with a plan named Personal, and
+// points the environment the commands read at it.
+func newTestServer(t *testing.T) (*ynabtest.Server, *ynab.Plan) {
+	t.Helper()
+	s := ynabtest.NewServer()
+	t.Cleanup(s.Close)
+	t.Setenv("YNAB_TOKEN", ynabtest.DefaultToken)
+	t.Setenv("YNAB_PLAN", "")
+	t.Setenv("YNAB_PROFILE", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	return s, s.AddPlan("Personal")
+}
+
+// runYNAB runs the ynab command line args against s, like main does, and
+// returns the app and what the command printed.
+func runYNAB(s *ynabtest.Server, args ...string) (*app, string, error) {
+	buf := new(bytes.Buffer)
+	a := newApp(buf)
+	cmd, rest, err := a.parseArgs(append([]string{"--base-url=" + s.URL}, args...))
+	if err != nil {
+		return a, "", err
+	}
+	err = a.run(context.Background(), cmd, rest)
+	return a, buf.String(), err
+}
+
+func TestGlobalFlags(t *testing.T) {
+	s, _ := newTestServer(t)
+	business := s.AddPlan("Business")
+	if _, err := s.AddAccount(business.ID, &ynab.SaveAccount{Name: "Operating", Type: "checking", Balance: 1000000}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantFormat string
+		wantOutput string
+	}{
+		{"before the command", []string{"--plan=Business", "--format=csv", "accounts"}, "csv", "Operating,checking,yes,1000.00"},
+		{"after the command", []string{"accounts", "--plan=Business", "--format=csv"}, "csv", "Operating,checking,yes,1000.00"},
+		{"command flags win", []string{"--plan=Personal", "--format=json", "accounts", "--plan=Business", "--format=csv"}, "csv", "Operating"},
+		{"both", []string{"--plan=Business", "accounts", "--format=json"}, "json", `"Name": "Operating"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, out, err := runYNAB(s, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.format != tt.wantFormat || a.selected.ID != business.ID {
+				t.Errorf("got format %q and plan %q, want %q and Business", a.format, a.selected.Name, tt.wantFormat)
+			}
+			if !strings.Contains(out, tt.wantOutput) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.wantOutput, out)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	a := newApp(new(bytes.Buffer))
+	cmd, args, err := a.parseArgs([]string{"--plan", "Personal", "transactions", "delete", "txn-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.name != "transactions" || strings.Join(args, " ") != "delete txn-1" || a.plan != "Personal" {
+		t.Errorf("got command %q, args %q and plan %q", cmd.name, args, a.plan)
+	}
+	for _, args := range [][]string{nil, {"help"}, {"budgets"}, {"--no-such-flag", "plans"}} {
+		if _, _, err := newApp(new(bytes.Buffer)).parseArgs(args); err != errUsage {
+			t.Errorf("parseArgs(%q): got error %v, want errUsage", args, err)
+		}
+	}
+}
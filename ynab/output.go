@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/internal/cli"
+)
+
+// A table is the output of a command in the table and csv formats.
+type table struct {
+	header []string
+	rows   []row
+}
+
+// A row is a line of a table. Rows with a section are printed under a heading
+// in the table format, and with the section in the first column in csv.
+type row struct {
+	section string
+	cells   []string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, row{cells: cells})
+}
+
+func (t *table) addTo(section string, cells ...string) {
+	t.rows = append(t.rows, row{section: section, cells: cells})
+}
+
+// print writes t in the selected format. For json, it writes v instead.
+func (a *app) print(t *table, v any) error {
+	switch a.format {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "csv":
+		w := csv.NewWriter(a.stdout)
+		sections := len(t.rows) > 0 && t.rows[0].section != ""
+		header := t.header
+		if sections {
+			header = append([]string{"Section"}, header...)
+		}
+		w.Write(header)
+		for _, r := range t.rows {
+			cells := r.cells
+			if sections {
+				cells = append([]string{r.section}, cells...)
+			}
+			w.Write(cells)
+		}
+		w.Flush()
+		return w.Error()
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	section := ""
+	for i, r := range t.rows {
+		if i == 0 || r.section != section {
+			if r.section != "" {
+				if i > 0 {
+					tw.Flush()
+					fmt.Fprintln(a.stdout)
+				}
+				fmt.Fprintf(a.stdout, "%s\n%s\n", r.section, strings.Repeat("=", len(r.section)))
+			}
+			fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+			section = r.section
+		}
+		fmt.Fprintln(tw, strings.Join(r.cells, "\t"))
+	}
+	if len(t.rows) == 0 {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+	return tw.Flush()
+}
+
+// amount formats m for the selected output format.
+func (a *app) amount(m int64) string {
+	if a.format == "csv" {
+		return cli.PlainAmount(m, a.formatter.CurrencyFormat.DecimalDigits)
+	}
+	return a.formatter.Amount(ynab.Milliunits(m))
+}
+
+// date formats d for the selected output format: ISO 8601 in csv, the plan's
+// date format in a table.
+func (a *app) date(d ynab.Date) string {
+	if a.format == "csv" {
+		return d.String()
+	}
+	return a.formatter.Date(d)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+var transactionCommands = []*command{
+	{name: "transactions list", short: "list transactions", plan: true, run: runTransactionsList},
+	{name: "transactions create", short: "create a transaction", plan: true, run: runTransactionsCreate},
+	{name: "transactions update", args: "<transaction-id>", short: "update a transaction", plan: true, run: runTransactionsUpdate},
+	{name: "transactions delete", args: "<transaction-id>", short: "delete a transaction", plan: true, run: runTransactionsDelete},
+}
+
+func runTransactions(ctx context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		for _, c := range transactionCommands {
+			if c.name == "transactions "+args[0] {
+				return a.run(ctx, c, args[1:])
+			}
+		}
+	}
+	fmt.Fprint(os.Stderr, "Usage: ynab transactions <command> [flags] [arguments]\n\nCommands:\n")
+	for _, c := range transactionCommands {
+		fmt.Fprintf(os.Stderr, "  %-8s %-17s %s\n", strings.TrimPrefix(c.name, "transactions "), c.args, c.short)
+	}
+	return errUsage
+}
+
+// transactionTable returns a table of txns.
+func (a *app) transactionTable(txns []*ynab.Transaction) *table {
+	t := &table{header: []string{"ID", "Date", "Account", "Payee", "Category", "Amount", "Memo", "Cleared", "Approved", "Flag"}}
+	for _, txn := range txns {
+		t.add(txn.ID, a.date(txn.Date), txn.AccountName, txn.PayeeName, txn.CategoryName.String, a.amount(txn.Amount), txn.Memo, string(txn.Cleared), yesNo(txn.Approved), string(txn.FlagColor))
+	}
+	return t
+}
+
+func runTransactionsList(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	since := fs.String("since", "", "Only list transactions on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "Only list transactions on or before this date (YYYY-MM-DD)")
+	account := fs.String("account", "", "Only list transactions in this account")
+	category := fs.String("category", "", "Only list transactions in this category")
+	payee := fs.String("payee", "", "Only list transactions whose payee contains this text, ignoring case")
+	unapproved := fs.Bool("unapproved", false, "Only list unapproved transactions")
+	uncategorized := fs.Bool("uncategorized", false, "Only list uncategorized transactions")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	var from, to time.Time
+	var err error
+	if *since != "" {
+		if from, err = parseDate(*since); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+	if *until != "" {
+		if to, err = parseDate(*until); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+	txns, err := a.transactions(ctx, from)
+	if err != nil {
+		return err
+	}
+	var accountID, categoryID string
+	if *account != "" {
+		accounts, err := a.accounts(ctx)
+		if err != nil {
+			return err
+		}
+		acct, err := findAccount(accounts, *account)
+		if err != nil {
+			return err
+		}
+		accountID = acct.ID
+	}
+	if *category != "" {
+		groups, err := a.categoryGroups(ctx)
+		if err != nil {
+			return err
+		}
+		c, err := findCategory(groups, *category)
+		if err != nil {
+			return err
+		}
+		categoryID = c.ID
+	}
+	txns = without(txns, func(txn *ynab.Transaction) bool {
+		switch {
+		case !to.IsZero() && time.Time(txn.Date).After(to),
+			accountID != "" && txn.AccountID != accountID,
+			categoryID != "" && !inCategory(txn, categoryID),
+			*payee != "" && !strings.Contains(strings.ToLower(txn.PayeeName), strings.ToLower(*payee)),
+			*unapproved && txn.Approved,
+			*uncategorized && txn.CategoryID.Valid:
+			return true
+		}
+		return false
+	})
+	return a.print(a.transactionTable(txns), txns)
+}
+
+// inCategory reports whether txn, or one of its subtransactions, is in the
+// category.
+func inCategory(txn *ynab.Transaction, categoryID string) bool {
+	if txn.CategoryID.String == categoryID {
+		return true
+	}
+	for _, sub := range txn.Subtransactions {
+		if sub.CategoryID.String == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// transactionFlags are the flags of transactions create and update.
+type transactionFlags struct {
+	account, date, amount, payee, category, memo, cleared, flag string
+	approved                                                    bool
+}
+
+func (f *transactionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.account, "account", "", "Account name or ID")
+	fs.StringVar(&f.date, "date", "", "Date (YYYY-MM-DD), default today")
+	fs.StringVar(&f.amount, "amount", "", "Amount in the plan's currency, negative for an outflow, e.g. -12.34")
+	fs.StringVar(&f.payee, "payee", "", "Payee name")
+	fs.StringVar(&f.category, "category", "", `Category name or ID, or "Group: Category"`)
+	fs.StringVar(&f.memo, "memo", "", "Memo")
+	fs.StringVar(&f.cleared, "cleared", "", "Cleared status: cleared, uncleared or reconciled")
+	fs.BoolVar(&f.approved, "approved", true, "Mark the transaction approved")
+	fs.StringVar(&f.flag, "flag", "", "Flag color: red, orange, yellow, green, blue or purple, or none")
+}
+
+// resolved holds the values of transactionFlags after looking up names.
+type resolved struct {
+	accountID  string
+	date       ynab.Date
+	amount     int64
+	categoryID types.NullString
+	cleared    ynab.ClearedStatus
+	flag       ynab.FlagColor
+}
+
+// resolve checks the flags that were set and looks up the account and
+// category.
+func (f *transactionFlags) resolve(ctx context.Context, a *app, set map[string]bool) (*resolved, error) {
+	r := new(resolved)
+	if set["account"] {
+		accounts, err := a.accounts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		acct, err := findAccount(accounts, f.account)
+		if err != nil {
+			return nil, err
+		}
+		r.accountID = acct.ID
+	}
+	if set["date"] {
+		t, err := parseDate(f.date)
+		if err != nil {
+			return nil, err
+		}
+		r.date = ynab.Date(t)
+	}
+	if set["amount"] {
+		m, err := a.formatter.ParseAmount(f.amount)
+		if err != nil {
+			return nil, err
+		}
+		r.amount = int64(m)
+	}
+	if set["category"] {
+		groups, err := a.categoryGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c, err := findCategory(groups, f.category)
+		if err != nil {
+			return nil, err
+		}
+		r.categoryID = types.NullString{Valid: true, String: c.ID}
+	}
+	switch status := ynab.ClearedStatus(f.cleared); status {
+	case "", ynab.ClearedStatusCleared, ynab.ClearedStatusUncleared, ynab.ClearedStatusReconciled:
+		r.cleared = status
+	default:
+		return nil, fmt.Errorf("invalid cleared status %q", f.cleared)
+	}
+	switch color := ynab.FlagColor(f.flag); color {
+	case "none":
+		r.flag = ynab.FlagColorEmpty
+	case ynab.FlagColorEmpty, ynab.FlagColorRed, ynab.FlagColorOrange, ynab.FlagColorYellow, ynab.FlagColorGreen, ynab.FlagColorBlue, ynab.FlagColorPurple:
+		r.flag = color
+	default:
+		return nil, fmt.Errorf("invalid flag color %q", f.flag)
+	}
+	return r, nil
+}
+
+// setFlags returns the names of the flags set on the command line.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+func nullString(s string) types.NullString {
+	return types.NullString{Valid: s != "", String: s}
+}
+
+func runTransactionsCreate(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("")
+	var f transactionFlags
+	f.register(fs)
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	if err := a.online(); err != nil {
+		return err
+	}
+	set := setFlags(fs)
+	if !set["account"] || !set["amount"] {
+		return usageError(fs, "--account and --amount are required")
+	}
+	r, err := f.resolve(ctx, a, set)
+	if err != nil {
+		return err
+	}
+	if !set["date"] {
+		now := time.Now()
+		r.date = ynab.Date(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+	}
+	txn := &ynab.NewTransaction{
+		AccountID:  r.accountID,
+		Date:       r.date,
+		Amount:     r.amount,
+		PayeeName:  nullString(f.payee),
+		CategoryID: r.categoryID,
+		Memo:       nullString(f.memo),
+		Cleared:    r.cleared,
+		Approved:   f.approved,
+		FlagColor:  r.flag,
+	}
+	if err := txn.Validate(); err != nil {
+		return err
+	}
+	resp, err := a.service().CreateTransaction(ctx, &ynab.CreateTransactionRequest{Transaction: txn})
+	if err != nil {
+		return err
+	}
+	created := resp.Data.Transaction
+	return a.print(a.transactionTable([]*ynab.Transaction{created}), created)
+}
+
+func runTransactionsUpdate(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("<transaction-id>")
+	var f transactionFlags
+	f.register(fs)
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	if err := a.online(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected one transaction ID")
+	}
+	id := fs.Arg(0)
+	set := setFlags(fs)
+	r, err := f.resolve(ctx, a, set)
+	if err != nil {
+		return err
+	}
+	// The update replaces every field, so start from the current values.
+	resp, err := a.service().GetTransaction(ctx, id)
+	if err != nil {
+		return err
+	}
+	txn := resp.Data.Transaction
+	u := &ynab.UpdateTransaction{
+		AccountID:  &txn.AccountID,
+		Date:       txn.Date,
+		Amount:     &txn.Amount,
+		PayeeID:    txn.PayeeID,
+		CategoryID: txn.CategoryID,
+		Memo:       nullString(txn.Memo),
+		Cleared:    nullString(string(txn.Cleared)),
+		Approved:   &txn.Approved,
+		FlagColor:  nullString(string(txn.FlagColor)),
+	}
+	if len(txn.Subtransactions) > 0 && (set["date"] || set["amount"]) {
+		return fmt.Errorf("the date and amount of a split transaction can't be changed")
+	}
+	if set["account"] {
+		u.AccountID = &r.accountID
+	}
+	if set["date"] {
+		u.Date = r.date
+	}
+	if set["amount"] {
+		u.Amount = &r.amount
+	}
+	if set["payee"] {
+		u.PayeeID = types.NullString{}
+		u.PayeeName = nullString(f.payee)
+	}
+	if set["category"] {
+		u.CategoryID = r.categoryID
+	}
+	// Null leaves a field unchanged, so an empty memo or flag is sent as a
+	// string to clear it.
+	if set["memo"] {
+		u.Memo = types.NullString{Valid: true, String: f.memo}
+	}
+	if set["cleared"] {
+		u.Cleared = nullString(string(r.cleared))
+	}
+	if set["approved"] {
+		u.Approved = &f.approved
+	}
+	if set["flag"] {
+		u.FlagColor = types.NullString{Valid: true, String: string(r.flag)}
+	}
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	updated, err := a.service().UpdateTransaction(ctx, id, &ynab.UpdateTransactionRequest{Transaction: u})
+	if err != nil {
+		return err
+	}
+	txn = updated.Data.Transaction
+	return a.print(a.transactionTable([]*ynab.Transaction{txn}), txn)
+}
+
+func runTransactionsDelete(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("<transaction-id>")
+	if err := a.start(ctx, fs, args); err != nil {
+		return err
+	}
+	if err := a.online(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected one transaction ID")
+	}
+	resp, err := a.service().DeleteTransaction(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	txn := resp.Data.Transaction
+	return a.print(a.transactionTable([]*ynab.Transaction{txn}), txn)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+func TestTransactionsCreateUpdateDelete(t *testing.T) {
+	s, plan := newTestServer(t)
+	checking, err := s.AddAccount(plan.ID, &ynab.SaveAccount{Name: "Checking", Type: "checking", Balance: 500000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := s.AddCategoryGroup(plan.ID, "Everyday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceries, err := s.AddCategory(plan.ID, group.ID, "Groceries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().AddDate(0, 0, -3).Format("2006-01-02")
+
+	_, out, err := runYNAB(s, "transactions", "create", "--account=checking", "--amount=-12.34", "--payee=Trader Joe's",
+		"--category=groceries", "--date="+date, "--flag=red", "--format=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := new(ynab.Transaction)
+	if err := json.Unmarshal([]byte(out), created); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	if created.AccountID != checking.ID || created.Amount != -12340 || created.PayeeName != "Trader Joe's" ||
+		created.CategoryID.String != groceries.ID || created.Date.String() != date || created.FlagColor != ynab.FlagColorRed || !created.Approved {
+		t.Errorf("created %+v", created)
+	}
+
+	// Fields that aren't given keep their values.
+	_, out, err = runYNAB(s, "transactions", "update", "--amount=-20", "--memo=weekly shop", "--flag=none", "--format=json", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := new(ynab.Transaction)
+	if err := json.Unmarshal([]byte(out), updated); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	if updated.ID != created.ID || updated.Amount != -20000 || updated.Memo != "weekly shop" || updated.FlagColor != ynab.FlagColorEmpty ||
+		updated.PayeeName != "Trader Joe's" || updated.CategoryID.String != groceries.ID || updated.Date.String() != date {
+		t.Errorf("updated %+v", updated)
+	}
+
+	_, out, err = runYNAB(s, "transactions", "list", "--category=Everyday: Groceries", "--format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{created.ID, date, "Checking", "Trader Joe's", "Groceries", "-20.00", "weekly shop", "uncleared", "yes", ""}
+	if len(records) != 2 || strings.Join(records[0], ",") != "ID,Date,Account,Payee,Category,Amount,Memo,Cleared,Approved,Flag" ||
+		strings.Join(records[1], ",") != strings.Join(want, ",") {
+		t.Errorf("got csv:\n%s", out)
+	}
+
+	_, out, err = runYNAB(s, "transactions", "delete", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, created.ID) || !strings.Contains(out, "-$20.00") {
+		t.Errorf("expected the deleted transaction in the table, got:\n%s", out)
+	}
+	_, out, err = runYNAB(s, "transactions", "list", "--payee=trader", "--format=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var txns []*ynab.Transaction
+	if err := json.Unmarshal([]byte(out), &txns); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	if len(txns) != 0 {
+		t.Errorf("expected the transaction to be deleted, got %d transactions", len(txns))
+	}
+}
+
+func TestTransactionsErrors(t *testing.T) {
+	s, plan := newTestServer(t)
+	for _, name := range []string{"Checking", "checking", "Cash"} {
+		if _, err := s.AddAccount(plan.ID, &ynab.SaveAccount{Name: name, Type: "checking"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"create", "--account=Savings", "--amount=-1"}, `no account named "Savings"`},
+		{[]string{"create", "--account=Checking", "--amount=-1"}, `more than one account is named "Checking"`},
+		{[]string{"create", "--account=Cash", "--amount=lots"}, "lots"},
+		{[]string{"update", "--memo=x", "no-such-transaction"}, "not found"},
+		{[]string{"delete", "no-such-transaction"}, "not found"},
+	}
+	for _, tt := range tests {
+		if _, _, err := runYNAB(s, append([]string{"transactions"}, tt.args...)...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("transactions %q: got error %v, want %q", tt.args, err, tt.want)
+		}
+	}
+	for _, args := range [][]string{{"create", "--amount=-1"}, {"delete"}, {"update", "a", "b"}, {"rename"}} {
+		if _, _, err := runYNAB(s, append([]string{"transactions"}, args...)...); err != errUsage {
+			t.Errorf("transactions %q: got error %v, want errUsage", args, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package ynabotel reports the spans and metrics of a ynab.Client to
+// OpenTelemetry:
+//
+//	inst, err := ynabotel.New(otel.GetTracerProvider(), otel.GetMeterProvider())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := ynab.NewClient(token, ynab.WithInstrumentation(inst))
+//
+// See ynab.Instrumentation for the spans and attributes that are reported.
+package ynabotel
+
+import (
+	"context"
+
+	"github.com/kevinburke/ynab-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the Tracer and Meter New creates.
+const ScopeName = "github.com/kevinburke/ynab-go"
+
+// New returns Instrumentation that starts a client span from tp for each API
+// call, and records the ynab.client.requests counter and the
+// ynab.client.request.duration histogram with mp. Either may be nil to report
+// only spans or only metrics.
+func New(tp trace.TracerProvider, mp metric.MeterProvider) (*ynab.Instrumentation, error) {
+	inst := new(ynab.Instrumentation)
+	if tp != nil {
+		inst.Tracer = tracer{tp.Tracer(ScopeName, trace.WithInstrumentationVersion(ynab.Version))}
+	}
+	if mp == nil {
+		return inst, nil
+	}
+	meter := mp.Meter(ScopeName, metric.WithInstrumentationVersion(ynab.Version))
+	requests, err := meter.Int64Counter("ynab.client.requests",
+		metric.WithDescription("The number of YNAB API calls."),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("ynab.client.request.duration",
+		metric.WithDescription("The duration of YNAB API calls, including retries."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	inst.Requests = counter{requests}
+	inst.Duration = histogram{duration}
+	return inst, nil
+}
+
+type tracer struct{ trace.Tracer }
+
+func (t tracer) Start(ctx context.Context, name string, attrs ...ynab.Attribute) (context.Context, ynab.Span) {
+	ctx, s := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(convert(attrs)...))
+	return ctx, span{s}
+}
+
+type span struct{ trace.Span }
+
+func (s span) SetAttributes(attrs ...ynab.Attribute) {
+	s.Span.SetAttributes(convert(attrs)...)
+}
+
+func (s span) RecordError(err error) {
+	s.Span.RecordError(err)
+	s.Span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() { s.Span.End() }
+
+type counter struct{ metric.Int64Counter }
+
+func (c counter) Add(ctx context.Context, n int64, attrs ...ynab.Attribute) {
+	c.Int64Counter.Add(ctx, n, metric.WithAttributes(convert(attrs)...))
+}
+
+type histogram struct{ metric.Float64Histogram }
+
+func (h histogram) Record(ctx context.Context, v float64, attrs ...ynab.Attribute) {
+	h.Float64Histogram.Record(ctx, v, metric.WithAttributes(convert(attrs)...))
+}
+
+// convert returns attrs as OpenTelemetry attributes. Values of a type
+// ynab.Attribute doesn't document are dropped.
+func convert(attrs []ynab.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case []string:
+			kvs = append(kvs, attribute.StringSlice(a.Key, v))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,94 @@
+package ynabotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kevinburke/ynab-go"
+	"github.com/kevinburke/ynab-go/ynabtest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	s := ynabtest.NewServer()
+	defer s.Close()
+	plan := s.AddPlan("Personal")
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	inst, err := New(tp, mp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ynab.NewClient(ynabtest.DefaultToken, ynab.WithBaseURL(s.URL), ynab.WithInstrumentation(inst))
+	ctx := context.Background()
+	if _, err := client.Plans(plan.ID).GetAccount(ctx, "no-such-account"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "PlanService.GetAccount" || span.SpanKind() != trace.SpanKindClient || span.Status().Code != codes.Error {
+		t.Errorf("got span %q of kind %v with status %v", span.Name(), span.SpanKind(), span.Status())
+	}
+	attrs := attribute.NewSet(span.Attributes()...)
+	for k, want := range map[attribute.Key]attribute.Value{
+		"ynab.plan_id":              attribute.StringValue(plan.ID),
+		"http.response.status_code": attribute.Int64Value(404),
+		"ynab.attempts":             attribute.Int64Value(1),
+	} {
+		if got, ok := attrs.Value(k); !ok || got != want {
+			t.Errorf("expected %s = %v, got %v", k, want.Emit(), got.Emit())
+		}
+	}
+	if span.InstrumentationScope().Name != ScopeName {
+		t.Errorf("got scope %q, want %q", span.InstrumentationScope().Name, ScopeName)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	if len(rm.ScopeMetrics) != 1 {
+		t.Fatalf("expected 1 scope, got %d", len(rm.ScopeMetrics))
+	}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch data := m.Data.(type) {
+		case metricdata.Sum[int64]:
+			if m.Name != "ynab.client.requests" || len(data.DataPoints) != 1 || data.DataPoints[0].Value != 1 {
+				t.Errorf("unexpected counter %s: %+v", m.Name, data.DataPoints)
+				continue
+			}
+			if op, _ := data.DataPoints[0].Attributes.Value("ynab.operation"); op.AsString() != "PlanService.GetAccount" {
+				t.Errorf("got operation %q", op.AsString())
+			}
+		case metricdata.Histogram[float64]:
+			if m.Name != "ynab.client.request.duration" || m.Unit != "s" || len(data.DataPoints) != 1 || data.DataPoints[0].Count != 1 {
+				t.Errorf("unexpected histogram %s: %+v", m.Name, data.DataPoints)
+			}
+		default:
+			t.Errorf("unexpected metric %s", m.Name)
+		}
+	}
+}
+
+func TestNewWithoutMetrics(t *testing.T) {
+	inst, err := New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.Tracer != nil || inst.Requests != nil || inst.Duration != nil {
+		t.Errorf("expected empty Instrumentation, got %+v", inst)
+	}
+}
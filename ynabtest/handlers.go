@@ -0,0 +1,805 @@
+package ynabtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	ok, created := http.StatusOK, http.StatusCreated
+	s.handle(mux, "/", ok, func(*Server, *plan, *http.Request) (any, error) {
+		return nil, notFound("Resource")
+	})
+	s.handle(mux, "GET /user", ok, getUser)
+	s.handle(mux, "GET /plans", ok, listPlans)
+	s.handle(mux, "GET /plans/{plan_id}", ok, getPlan)
+	s.handle(mux, "GET /plans/{plan_id}/settings", ok, getSettings)
+
+	s.handle(mux, "GET /plans/{plan_id}/accounts", ok, listAccounts)
+	s.handle(mux, "POST /plans/{plan_id}/accounts", created, createAccount)
+	s.handle(mux, "GET /plans/{plan_id}/accounts/{account_id}", ok, getAccount)
+	s.handle(mux, "GET /plans/{plan_id}/accounts/{account_id}/transactions", ok, listAccountTransactions)
+
+	s.handle(mux, "GET /plans/{plan_id}/categories", ok, listCategories)
+	s.handle(mux, "POST /plans/{plan_id}/categories", created, createCategory)
+	s.handle(mux, "GET /plans/{plan_id}/categories/{category_id}", ok, getCategory)
+	s.handle(mux, "PATCH /plans/{plan_id}/categories/{category_id}", ok, updateCategory)
+	s.handle(mux, "GET /plans/{plan_id}/categories/{category_id}/transactions", ok, listCategoryTransactions)
+	s.handle(mux, "POST /plans/{plan_id}/category_groups", created, createCategoryGroup)
+	s.handle(mux, "PATCH /plans/{plan_id}/category_groups/{category_group_id}", ok, updateCategoryGroup)
+
+	s.handle(mux, "GET /plans/{plan_id}/payees", ok, listPayees)
+	s.handle(mux, "GET /plans/{plan_id}/payees/{payee_id}", ok, getPayee)
+	s.handle(mux, "PATCH /plans/{plan_id}/payees/{payee_id}", ok, updatePayee)
+	s.handle(mux, "GET /plans/{plan_id}/payees/{payee_id}/transactions", ok, listPayeeTransactions)
+	s.handle(mux, "GET /plans/{plan_id}/payees/{payee_id}/payee_locations", ok, listPayeeLocations)
+	s.handle(mux, "GET /plans/{plan_id}/payee_locations", ok, listPayeeLocations)
+	s.handle(mux, "GET /plans/{plan_id}/payee_locations/{payee_location_id}", ok, func(*Server, *plan, *http.Request) (any, error) {
+		return nil, notFound("Payee location")
+	})
+
+	s.handle(mux, "GET /plans/{plan_id}/months", ok, listMonths)
+	s.handle(mux, "GET /plans/{plan_id}/months/{month}", ok, getMonth)
+	s.handle(mux, "GET /plans/{plan_id}/months/{month}/categories/{category_id}", ok, getMonthCategory)
+	s.handle(mux, "PATCH /plans/{plan_id}/months/{month}/categories/{category_id}", ok, updateMonthCategory)
+	s.handle(mux, "GET /plans/{plan_id}/months/{month}/transactions", ok, listMonthTransactions)
+	s.handle(mux, "GET /plans/{plan_id}/months/{month}/money_movements", ok, listMoneyMovements)
+	s.handle(mux, "GET /plans/{plan_id}/months/{month}/money_movement_groups", ok, listMoneyMovementGroups)
+	s.handle(mux, "GET /plans/{plan_id}/money_movements", ok, listMoneyMovements)
+	s.handle(mux, "GET /plans/{plan_id}/money_movement_groups", ok, listMoneyMovementGroups)
+
+	s.handle(mux, "GET /plans/{plan_id}/transactions", ok, listTransactions)
+	s.handle(mux, "POST /plans/{plan_id}/transactions", created, createTransactions)
+	s.handle(mux, "PATCH /plans/{plan_id}/transactions", ok, updateTransactions)
+	s.handle(mux, "POST /plans/{plan_id}/transactions/import", ok, importTransactions)
+	s.handle(mux, "GET /plans/{plan_id}/transactions/{transaction_id}", ok, getTransaction)
+	s.handle(mux, "PUT /plans/{plan_id}/transactions/{transaction_id}", ok, updateTransaction)
+	s.handle(mux, "DELETE /plans/{plan_id}/transactions/{transaction_id}", ok, deleteTransaction)
+
+	s.handle(mux, "GET /plans/{plan_id}/scheduled_transactions", ok, listScheduledTransactions)
+	s.handle(mux, "POST /plans/{plan_id}/scheduled_transactions", created, createScheduledTransaction)
+	s.handle(mux, "GET /plans/{plan_id}/scheduled_transactions/{scheduled_transaction_id}", ok, getScheduledTransaction)
+	s.handle(mux, "PUT /plans/{plan_id}/scheduled_transactions/{scheduled_transaction_id}", ok, updateScheduledTransaction)
+	s.handle(mux, "DELETE /plans/{plan_id}/scheduled_transactions/{scheduled_transaction_id}", ok, deleteScheduledTransaction)
+	return mux
+}
+
+// lastKnowledge returns the last_knowledge_of_server query parameter.
+func lastKnowledge(r *http.Request) (int64, error) {
+	v := r.URL.Query().Get("last_knowledge_of_server")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, badRequest("invalid last_knowledge_of_server %q", v)
+	}
+	return n, nil
+}
+
+// decode decodes the JSON request body into v.
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// mutate runs fn as a single change to p: everything it stores shares one
+// server knowledge, and derived values are recomputed afterwards. If fn fails
+// part way through, what it already stored is kept.
+func (s *Server) mutate(p *plan, fn func(k int64, now time.Time) error) error {
+	now := s.now()
+	k := p.bump(now)
+	err := fn(k, now)
+	p.recompute(k, now)
+	return err
+}
+
+func getUser(s *Server, _ *plan, _ *http.Request) (any, error) {
+	return map[string]any{"user": &ynab.User{ID: s.userID}}, nil
+}
+
+func listPlans(s *Server, _ *plan, r *http.Request) (any, error) {
+	includeAccounts := r.URL.Query().Get("include_accounts") == "true"
+	data := ynab.PlanListWrapper{Plans: []*ynab.Plan{}}
+	for _, id := range s.planIDs {
+		p := s.plans[id]
+		info := p.info()
+		if includeAccounts {
+			info.Accounts = p.accounts.list(0)
+		}
+		data.Plans = append(data.Plans, info)
+	}
+	if len(data.Plans) > 0 {
+		data.DefaultPlan = data.Plans[0]
+	}
+	return data, nil
+}
+
+func getPlan(s *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	info := p.info()
+	detail := &ynab.PlanDetail{
+		ID:                       info.ID,
+		Name:                     info.Name,
+		LastModifiedOn:           info.LastModifiedOn,
+		FirstMonth:               info.FirstMonth,
+		LastMonth:                info.LastMonth,
+		DateFormat:               info.DateFormat,
+		CurrencyFormat:           info.CurrencyFormat,
+		Accounts:                 p.accounts.list(since),
+		Payees:                   p.payees.list(since),
+		PayeeLocations:           []*ynab.PayeeLocation{},
+		CategoryGroups:           []*ynab.CategoryGroup{},
+		Categories:               p.categories.list(since),
+		Months:                   []*ynab.MonthDetail{},
+		Transactions:             []*ynab.Transaction{},
+		Subtransactions:          []*ynab.Transaction{},
+		ScheduledTransactions:    []*ynab.ScheduledTransaction{},
+		ScheduledSubtransactions: []*ynab.ScheduledTransaction{},
+	}
+	for _, g := range p.groups.list(since) {
+		group := *g
+		group.Categories = nil
+		detail.CategoryGroups = append(detail.CategoryGroups, &group)
+	}
+	l := p.ledger(s.now())
+	for _, m := range p.months.list(since) {
+		detail.Months = append(detail.Months, monthDetail(p, l, m))
+	}
+	for _, t := range p.transactions.list(since) {
+		txn := *t
+		txn.Subtransactions = nil
+		detail.Transactions = append(detail.Transactions, &txn)
+		for i := range t.Subtransactions {
+			detail.Subtransactions = append(detail.Subtransactions, &t.Subtransactions[i])
+		}
+	}
+	detail.ScheduledTransactions = append(detail.ScheduledTransactions, p.scheduled.list(since)...)
+	return map[string]any{"plan": detail, "server_knowledge": p.knowledge}, nil
+}
+
+func getSettings(_ *Server, p *plan, _ *http.Request) (any, error) {
+	return map[string]any{"settings": p.settings}, nil
+}
+
+func listAccounts(_ *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	return ynab.AccountListWrapper{Accounts: p.accounts.list(since), ServerKnowledge: p.knowledge}, nil
+}
+
+func createAccount(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.CreateAccountRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var id string
+	err := s.mutate(p, func(k int64, now time.Time) error {
+		a, err := p.createAccount(req.Account, k, now)
+		if err == nil {
+			id = a.ID
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"account": p.accounts.get(id), "server_knowledge": p.knowledge}, nil
+}
+
+func getAccount(_ *Server, p *plan, r *http.Request) (any, error) {
+	a := p.accounts.get(r.PathValue("account_id"))
+	if a == nil {
+		return nil, notFound("Account")
+	}
+	return map[string]any{"account": a}, nil
+}
+
+func listCategories(_ *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	return ynab.CategoryListWrapper{CategoryGroups: p.categoryGroups(since), ServerKnowledge: p.knowledge}, nil
+}
+
+func createCategory(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.CreateCategoryRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var id string
+	err := s.mutate(p, func(k int64, _ time.Time) error {
+		c, err := p.createCategory(req.Category, k)
+		if err == nil {
+			id = c.ID
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ynab.SaveCategoryData{Category: p.categories.get(id), ServerKnowledge: p.knowledge}, nil
+}
+
+func getCategory(_ *Server, p *plan, r *http.Request) (any, error) {
+	c := p.categories.get(r.PathValue("category_id"))
+	if c == nil {
+		return nil, notFound("Category")
+	}
+	return ynab.CategoryData{Category: c}, nil
+}
+
+func updateCategory(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.UpdateCategoryRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	existing := p.categories.get(r.PathValue("category_id"))
+	if existing == nil || existing.Internal {
+		return nil, notFound("Category")
+	}
+	if req.Category == nil {
+		return nil, badRequest("category is required")
+	}
+	err := s.mutate(p, func(k int64, _ time.Time) error {
+		c := *existing
+		sc := req.Category
+		if sc.Name != "" {
+			c.Name = sc.Name
+		}
+		if sc.Note != "" {
+			c.Note = sc.Note
+		}
+		if sc.CategoryGroupID != "" && sc.CategoryGroupID != c.CategoryGroupID {
+			g := p.groups.get(sc.CategoryGroupID)
+			if g == nil || g.Internal {
+				return badRequest("invalid category_group_id %q", sc.CategoryGroupID)
+			}
+			c.CategoryGroupID, c.CategoryGroupName = g.ID, g.Name
+		}
+		if sc.GoalTarget != nil {
+			c.GoalTarget = sc.GoalTarget
+		}
+		if sc.GoalTargetDate != "" {
+			c.GoalTargetDate.String, c.GoalTargetDate.Valid = sc.GoalTargetDate, true
+		}
+		if sc.GoalNeedsWholeAmount != nil {
+			c.GoalNeedsWholeAmount = sc.GoalNeedsWholeAmount
+		}
+		p.categories.put(c.ID, &c, k)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ynab.SaveCategoryData{Category: p.categories.get(existing.ID), ServerKnowledge: p.knowledge}, nil
+}
+
+func createCategoryGroup(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.CreateCategoryGroupRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.CategoryGroup == nil {
+		return nil, badRequest("category_group is required")
+	}
+	var g *ynab.CategoryGroup
+	err := s.mutate(p, func(k int64, _ time.Time) (err error) {
+		g, err = p.createCategoryGroup(req.CategoryGroup.Name, k)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"category_group": g, "server_knowledge": p.knowledge}, nil
+}
+
+func updateCategoryGroup(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.UpdateCategoryGroupRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	existing := p.groups.get(r.PathValue("category_group_id"))
+	if existing == nil || existing.Internal {
+		return nil, notFound("Category group")
+	}
+	if req.CategoryGroup == nil || req.CategoryGroup.Name == "" || len(req.CategoryGroup.Name) > 50 {
+		return nil, badRequest("category group name must be between 1 and 50 characters")
+	}
+	g := *existing
+	g.Name = req.CategoryGroup.Name
+	s.mutate(p, func(k int64, _ time.Time) error {
+		p.groups.put(g.ID, &g, k)
+		for _, c := range p.categories.list(0) {
+			if c.CategoryGroupID == g.ID {
+				next := *c
+				next.CategoryGroupName = g.Name
+				p.categories.put(next.ID, &next, k)
+			}
+		}
+		return nil
+	})
+	return map[string]any{"category_group": &g, "server_knowledge": p.knowledge}, nil
+}
+
+func listPayees(_ *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"payees": p.payees.list(since), "server_knowledge": p.knowledge}, nil
+}
+
+func getPayee(_ *Server, p *plan, r *http.Request) (any, error) {
+	payee := p.payees.get(r.PathValue("payee_id"))
+	if payee == nil {
+		return nil, notFound("Payee")
+	}
+	return map[string]any{"payee": payee}, nil
+}
+
+func updatePayee(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.UpdatePayeeRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	existing := p.payees.get(r.PathValue("payee_id"))
+	if existing == nil {
+		return nil, notFound("Payee")
+	}
+	if req.Payee == nil || req.Payee.Name == "" || len(req.Payee.Name) > 500 {
+		return nil, badRequest("payee name must be between 1 and 500 characters")
+	}
+	payee := *existing
+	payee.Name = req.Payee.Name
+	s.mutate(p, func(k int64, _ time.Time) error {
+		p.payees.put(payee.ID, &payee, k)
+		for _, t := range p.transactions.list(0) {
+			if t.PayeeID.String == payee.ID {
+				next := *t
+				next.PayeeName = payee.Name
+				p.transactions.put(next.ID, &next, k)
+			}
+		}
+		return nil
+	})
+	return map[string]any{"payee": &payee, "server_knowledge": p.knowledge}, nil
+}
+
+func listPayeeLocations(*Server, *plan, *http.Request) (any, error) {
+	return map[string]any{"payee_locations": []*ynab.PayeeLocation{}}, nil
+}
+
+func listMonths(_ *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"months": p.months.list(since), "server_knowledge": p.knowledge}, nil
+}
+
+// monthDetail returns m with the amounts of every category in that month.
+func monthDetail(p *plan, l *ledger, m *ynab.MonthSummary) *ynab.MonthDetail {
+	d := &ynab.MonthDetail{
+		Month:                 m.Month,
+		Note:                  m.Note,
+		Income:                m.Income,
+		IncomeFormatted:       m.IncomeFormatted,
+		IncomeCurrency:        m.IncomeCurrency,
+		Budgeted:              m.Budgeted,
+		BudgetedFormatted:     m.BudgetedFormatted,
+		BudgetedCurrency:      m.BudgetedCurrency,
+		Activity:              m.Activity,
+		ActivityFormatted:     m.ActivityFormatted,
+		ActivityCurrency:      m.ActivityCurrency,
+		ToBeBudget:            m.ToBeBudgeted,
+		ToBeBudgetedFormatted: m.ToBeBudgetedFormatted,
+		ToBeBudgetedCurrency:  m.ToBeBudgetedCurrency,
+		Deleted:               m.Deleted,
+		Categories:            []*ynab.Category{},
+	}
+	for _, c := range p.categories.list(0) {
+		d.Categories = append(d.Categories, p.categoryInMonth(c, l, m.Month))
+	}
+	return d
+}
+
+func getMonth(s *Server, p *plan, r *http.Request) (any, error) {
+	month, err := resolveMonth(r.PathValue("month"), s.now())
+	if err != nil {
+		return nil, err
+	}
+	m := p.months.get(month)
+	if m == nil {
+		return nil, notFound("Month")
+	}
+	return map[string]any{"month": monthDetail(p, p.ledger(s.now()), m)}, nil
+}
+
+func getMonthCategory(s *Server, p *plan, r *http.Request) (any, error) {
+	month, err := resolveMonth(r.PathValue("month"), s.now())
+	if err != nil {
+		return nil, err
+	}
+	c := p.categories.get(r.PathValue("category_id"))
+	if c == nil || p.months.get(month) == nil {
+		return nil, notFound("Category")
+	}
+	return ynab.CategoryData{Category: p.categoryInMonth(c, p.ledger(s.now()), month)}, nil
+}
+
+func updateMonthCategory(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.UpdateMonthCategoryRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	month, err := resolveMonth(r.PathValue("month"), s.now())
+	if err != nil {
+		return nil, err
+	}
+	id := r.PathValue("category_id")
+	err = s.mutate(p, func(k int64, now time.Time) error {
+		return p.setBudgeted(month, id, req.Category.Budgeted, k, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := p.categoryInMonth(p.categories.get(id), p.ledger(s.now()), month)
+	return ynab.SaveCategoryData{Category: c, ServerKnowledge: p.knowledge}, nil
+}
+
+func listMoneyMovements(s *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	month := ""
+	if m := r.PathValue("month"); m != "" {
+		if month, err = resolveMonth(m, s.now()); err != nil {
+			return nil, err
+		}
+	}
+	out := []*ynab.MoneyMovement{}
+	for _, mm := range p.moneyMovements.list(since) {
+		if month == "" || mm.Month.Date.String() == month {
+			out = append(out, mm)
+		}
+	}
+	return map[string]any{"money_movements": out, "server_knowledge": p.knowledge}, nil
+}
+
+func listMoneyMovementGroups(s *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	month := ""
+	if m := r.PathValue("month"); m != "" {
+		if month, err = resolveMonth(m, s.now()); err != nil {
+			return nil, err
+		}
+	}
+	out := []*ynab.MoneyMovementGroup{}
+	for _, g := range p.moneyMovementGroups.list(since) {
+		if month == "" || g.Month.String() == month {
+			out = append(out, g)
+		}
+	}
+	return map[string]any{"money_movement_groups": out, "server_knowledge": p.knowledge}, nil
+}
+
+// transactionFilter returns the transactions matching the since_date, type
+// and last_knowledge_of_server query parameters.
+func transactionFilter(p *plan, r *http.Request) ([]*ynab.Transaction, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	sinceDate := q.Get("since_date")
+	if sinceDate != "" {
+		if _, err := time.Parse("2006-01-02", sinceDate); err != nil {
+			return nil, badRequest("invalid since_date %q", sinceDate)
+		}
+	}
+	typ := q.Get("type")
+	if typ != "" && typ != string(ynab.TransactionFilterUncategorized) && typ != string(ynab.TransactionFilterUnapproved) {
+		return nil, badRequest("invalid type %q", typ)
+	}
+	out := []*ynab.Transaction{}
+	for _, t := range p.transactions.list(since) {
+		if sinceDate != "" && t.Date.String() < sinceDate {
+			continue
+		}
+		switch ynab.TransactionFilterType(typ) {
+		case ynab.TransactionFilterUnapproved:
+			if t.Approved {
+				continue
+			}
+		case ynab.TransactionFilterUncategorized:
+			if t.CategoryID.Valid || len(t.Subtransactions) > 0 || t.TransferAccountID.Valid {
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func listTransactions(_ *Server, p *plan, r *http.Request) (any, error) {
+	txns, err := transactionFilter(p, r)
+	if err != nil {
+		return nil, err
+	}
+	return ynab.TransactionListWrapper{Transactions: txns, ServerKnowledge: p.knowledge}, nil
+}
+
+func listAccountTransactions(_ *Server, p *plan, r *http.Request) (any, error) {
+	id := r.PathValue("account_id")
+	if p.accounts.get(id) == nil {
+		return nil, notFound("Account")
+	}
+	txns, err := transactionFilter(p, r)
+	if err != nil {
+		return nil, err
+	}
+	out := []*ynab.Transaction{}
+	for _, t := range txns {
+		if t.AccountID == id {
+			out = append(out, t)
+		}
+	}
+	return ynab.TransactionListWrapper{Transactions: out, ServerKnowledge: p.knowledge}, nil
+}
+
+func listCategoryTransactions(_ *Server, p *plan, r *http.Request) (any, error) {
+	id := r.PathValue("category_id")
+	if p.categories.get(id) == nil {
+		return nil, notFound("Category")
+	}
+	txns, err := transactionFilter(p, r)
+	if err != nil {
+		return nil, err
+	}
+	out := hybrids(txns, func(t *ynab.Transaction) bool { return t.CategoryID.String == id })
+	return map[string]any{"transactions": out, "server_knowledge": p.knowledge}, nil
+}
+
+func listPayeeTransactions(_ *Server, p *plan, r *http.Request) (any, error) {
+	id := r.PathValue("payee_id")
+	if p.payees.get(id) == nil {
+		return nil, notFound("Payee")
+	}
+	txns, err := transactionFilter(p, r)
+	if err != nil {
+		return nil, err
+	}
+	out := hybrids(txns, func(t *ynab.Transaction) bool { return t.PayeeID.String == id })
+	return map[string]any{"transactions": out, "server_knowledge": p.knowledge}, nil
+}
+
+func listMonthTransactions(s *Server, p *plan, r *http.Request) (any, error) {
+	month, err := resolveMonth(r.PathValue("month"), s.now())
+	if err != nil {
+		return nil, err
+	}
+	txns, err := transactionFilter(p, r)
+	if err != nil {
+		return nil, err
+	}
+	out := hybrids(txns, func(t *ynab.Transaction) bool { return monthOf(time.Time(t.Date)) == month })
+	return map[string]any{"transactions": out, "server_knowledge": p.knowledge}, nil
+}
+
+func createTransactions(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.CreateTransactionRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if (req.Transaction == nil) == (len(req.Transactions) == 0) {
+		return nil, badRequest("specify either transaction or transactions")
+	}
+	data := ynab.CreateTransactionData{TransactionIDs: []string{}}
+	err := s.mutate(p, func(k int64, now time.Time) error {
+		if req.Transaction != nil {
+			t, err := p.createTransaction(req.Transaction, k, now)
+			if err != nil {
+				return err
+			}
+			data.TransactionIDs = append(data.TransactionIDs, t.ID)
+			return nil
+		}
+		for _, nt := range req.Transactions {
+			t, err := p.createTransaction(nt, k, now)
+			if e, ok := err.(*ynab.Error); ok && e.StatusCode == http.StatusConflict {
+				data.DuplicateImportIDs = append(data.DuplicateImportIDs, nt.ImportID.String)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			data.TransactionIDs = append(data.TransactionIDs, t.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Read the transactions back so responses include recomputed fields.
+	for _, id := range data.TransactionIDs {
+		t := p.transactions.get(id)
+		if req.Transaction != nil {
+			data.Transaction = t
+		} else {
+			data.Transactions = append(data.Transactions, t)
+		}
+	}
+	data.ServerKnowledge = p.knowledge
+	return data, nil
+}
+
+func getTransaction(_ *Server, p *plan, r *http.Request) (any, error) {
+	t := p.transactions.get(r.PathValue("transaction_id"))
+	if t == nil {
+		return nil, notFound("Transaction")
+	}
+	return ynab.TransactionWrapper{Transaction: t}, nil
+}
+
+func updateTransaction(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.UpdateTransactionRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var t *ynab.Transaction
+	err := s.mutate(p, func(k int64, now time.Time) (err error) {
+		t, err = p.updateTransaction(r.PathValue("transaction_id"), req.Transaction, k, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ynab.TransactionWrapper{Transaction: t}, nil
+}
+
+// bulkUpdate is an element of a PATCH /transactions request. YNAB finds the
+// transaction by id or, failing that, by import_id.
+type bulkUpdate struct {
+	ID       string `json:"id"`
+	ImportID string `json:"import_id"`
+	ynab.UpdateTransaction
+}
+
+func updateTransactions(s *Server, p *plan, r *http.Request) (any, error) {
+	var req struct {
+		Transactions []*bulkUpdate `json:"transactions"`
+	}
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	data := ynab.CreateTransactionData{TransactionIDs: []string{}, Transactions: []*ynab.Transaction{}}
+	err := s.mutate(p, func(k int64, now time.Time) error {
+		for _, u := range req.Transactions {
+			id := u.ID
+			if id == "" && u.ImportID != "" {
+				for _, t := range p.transactions.list(0) {
+					if t.ImportID.String == u.ImportID {
+						id = t.ID
+						break
+					}
+				}
+			}
+			t, err := p.updateTransaction(id, &u.UpdateTransaction, k, now)
+			if err != nil {
+				return err
+			}
+			data.TransactionIDs = append(data.TransactionIDs, t.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range data.TransactionIDs {
+		data.Transactions = append(data.Transactions, p.transactions.get(id))
+	}
+	data.ServerKnowledge = p.knowledge
+	return data, nil
+}
+
+func deleteTransaction(s *Server, p *plan, r *http.Request) (any, error) {
+	var t *ynab.Transaction
+	err := s.mutate(p, func(k int64, _ time.Time) (err error) {
+		t, err = p.deleteTransaction(r.PathValue("transaction_id"), k)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ynab.TransactionWrapper{Transaction: t}, nil
+}
+
+// importTransactions reports that there was nothing to import: the fake has
+// no linked accounts.
+func importTransactions(*Server, *plan, *http.Request) (any, error) {
+	return map[string]any{"transaction_ids": []string{}}, nil
+}
+
+func listScheduledTransactions(_ *Server, p *plan, r *http.Request) (any, error) {
+	since, err := lastKnowledge(r)
+	if err != nil {
+		return nil, err
+	}
+	return ynab.ScheduledTransactionListWrapper{ScheduledTransactions: p.scheduled.list(since), ServerKnowledge: p.knowledge}, nil
+}
+
+func createScheduledTransaction(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.CreateScheduledTransactionRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var st *ynab.ScheduledTransaction
+	err := s.mutate(p, func(k int64, now time.Time) (err error) {
+		st, err = p.saveScheduledTransaction(nil, req.ScheduledTransaction, k, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"scheduled_transaction": st}, nil
+}
+
+func getScheduledTransaction(_ *Server, p *plan, r *http.Request) (any, error) {
+	st := p.scheduled.get(r.PathValue("scheduled_transaction_id"))
+	if st == nil {
+		return nil, notFound("Scheduled transaction")
+	}
+	return map[string]any{"scheduled_transaction": st}, nil
+}
+
+func updateScheduledTransaction(s *Server, p *plan, r *http.Request) (any, error) {
+	var req ynab.UpdateScheduledTransactionRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	existing := p.scheduled.get(r.PathValue("scheduled_transaction_id"))
+	if existing == nil {
+		return nil, notFound("Scheduled transaction")
+	}
+	var st *ynab.ScheduledTransaction
+	err := s.mutate(p, func(k int64, now time.Time) (err error) {
+		st, err = p.saveScheduledTransaction(existing, req.ScheduledTransaction, k, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"scheduled_transaction": st}, nil
+}
+
+func deleteScheduledTransaction(s *Server, p *plan, r *http.Request) (any, error) {
+	existing := p.scheduled.get(r.PathValue("scheduled_transaction_id"))
+	if existing == nil {
+		return nil, notFound("Scheduled transaction")
+	}
+	st := *existing
+	st.Deleted = true
+	s.mutate(p, func(k int64, _ time.Time) error {
+		p.scheduled.put(st.ID, &st, k)
+		return nil
+	})
+	return map[string]any{"scheduled_transaction": &st}, nil
+}
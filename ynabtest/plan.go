@@ -0,0 +1,497 @@
+package ynabtest
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+// A table holds one kind of entity in a plan, along with the server knowledge
+// at which each entity last changed. Entities are never removed; deleting one
+// sets its Deleted field so that delta requests can report it.
+type table[T any] struct {
+	ids     []string
+	rows    map[string]*row[T]
+	deleted func(*T) bool
+}
+
+type row[T any] struct {
+	v         *T
+	knowledge int64
+}
+
+func newTable[T any](deleted func(*T) bool) *table[T] {
+	return &table[T]{rows: make(map[string]*row[T]), deleted: deleted}
+}
+
+// get returns the entity with the given ID, or nil if there is none or it has
+// been deleted.
+func (t *table[T]) get(id string) *T {
+	r, ok := t.rows[id]
+	if !ok || t.deleted(r.v) {
+		return nil
+	}
+	return r.v
+}
+
+// put stores v, recording that it changed at knowledge.
+func (t *table[T]) put(id string, v *T, knowledge int64) {
+	if _, ok := t.rows[id]; !ok {
+		t.ids = append(t.ids, id)
+	}
+	t.rows[id] = &row[T]{v: v, knowledge: knowledge}
+}
+
+// update stores v if it differs from the stored entity, so that recomputed
+// values only show up in delta requests when they actually change.
+func (t *table[T]) update(id string, v *T, knowledge int64) {
+	if r, ok := t.rows[id]; ok && reflect.DeepEqual(r.v, v) {
+		return
+	}
+	t.put(id, v, knowledge)
+}
+
+// changedSince reports whether the entity with the given ID changed after
+// knowledge.
+func (t *table[T]) changedSince(id string, knowledge int64) bool {
+	r, ok := t.rows[id]
+	return ok && r.knowledge > knowledge
+}
+
+// list returns entities in the order they were added. If since is zero,
+// deleted entities are left out. Otherwise only entities that changed after
+// since are returned, including deleted ones, like a YNAB delta request.
+func (t *table[T]) list(since int64) []*T {
+	out := make([]*T, 0, len(t.ids))
+	for _, id := range t.ids {
+		r := t.rows[id]
+		if since == 0 && t.deleted(r.v) {
+			continue
+		}
+		if since > 0 && r.knowledge <= since {
+			continue
+		}
+		out = append(out, r.v)
+	}
+	return out
+}
+
+// plan is the state of one plan on a Server.
+type plan struct {
+	id           string
+	name         string
+	settings     ynab.PlanSettings
+	knowledge    int64
+	lastModified time.Time
+
+	accounts            *table[ynab.Account]
+	groups              *table[ynab.CategoryGroup] // stored without Categories
+	categories          *table[ynab.Category]
+	payees              *table[ynab.Payee]
+	months              *table[ynab.MonthSummary]
+	transactions        *table[ynab.Transaction]
+	scheduled           *table[ynab.ScheduledTransaction]
+	moneyMovements      *table[ynab.MoneyMovement]
+	moneyMovementGroups *table[ynab.MoneyMovementGroup]
+
+	// budgeted is the amount assigned to each category, by month and then
+	// category ID.
+	budgeted map[string]map[string]int64
+	// readyToAssign is the ID of the "Inflow: Ready to Assign" category.
+	readyToAssign string
+}
+
+func newPlan(name string, now time.Time) *plan {
+	p := &plan{
+		id:                  newID(),
+		name:                name,
+		settings:            ynab.DefaultPlanSettings,
+		lastModified:        now,
+		accounts:            newTable(func(a *ynab.Account) bool { return a.Deleted }),
+		groups:              newTable(func(g *ynab.CategoryGroup) bool { return g.Deleted }),
+		categories:          newTable(func(c *ynab.Category) bool { return c.Deleted }),
+		payees:              newTable(func(p *ynab.Payee) bool { return p.Deleted }),
+		months:              newTable(func(m *ynab.MonthSummary) bool { return m.Deleted }),
+		transactions:        newTable(func(t *ynab.Transaction) bool { return t.Deleted }),
+		scheduled:           newTable(func(t *ynab.ScheduledTransaction) bool { return t.Deleted }),
+		moneyMovements:      newTable(func(*ynab.MoneyMovement) bool { return false }),
+		moneyMovementGroups: newTable(func(*ynab.MoneyMovementGroup) bool { return false }),
+		budgeted:            make(map[string]map[string]int64),
+	}
+	k := p.bump(now)
+	internal := &ynab.CategoryGroup{ID: newID(), Name: "Internal Master Category", Internal: true}
+	p.groups.put(internal.ID, internal, k)
+	rta := &ynab.Category{
+		ID:                newID(),
+		Name:              "Inflow: Ready to Assign",
+		CategoryGroupID:   internal.ID,
+		CategoryGroupName: internal.Name,
+		Internal:          true,
+	}
+	p.categories.put(rta.ID, rta, k)
+	p.readyToAssign = rta.ID
+	p.recompute(k, now)
+	return p
+}
+
+// bump increments the plan's server knowledge and returns the new value. Every
+// change made at the same time is recorded with the same knowledge.
+func (p *plan) bump(now time.Time) int64 {
+	p.knowledge++
+	p.lastModified = now
+	return p.knowledge
+}
+
+func (p *plan) info() *ynab.Plan {
+	l := p.ledger(p.lastModified)
+	cf, df := p.settings.CurrencyFormat, p.settings.DateFormat
+	return &ynab.Plan{
+		ID:             p.id,
+		Name:           p.name,
+		LastModifiedOn: p.lastModified.UTC().Format(time.RFC3339),
+		FirstMonth:     l.months[0],
+		LastMonth:      l.months[len(l.months)-1],
+		CurrencyFormat: &cf,
+		DateFormat:     &df,
+	}
+}
+
+// amount returns the formatted and decimal versions of a milliunit amount,
+// for the *_formatted and *_currency fields.
+func (p *plan) amount(m int64) (string, float64) {
+	return ynab.Milliunits(m).Format(p.settings.CurrencyFormat), float64(m) / 1000
+}
+
+// categoryGroups returns the category groups with their categories. If since
+// is not zero, only groups and categories that changed after since are
+// included.
+func (p *plan) categoryGroups(since int64) []*ynab.CategoryGroup {
+	var out []*ynab.CategoryGroup
+	for _, id := range p.groups.ids {
+		g := *p.groups.rows[id].v
+		g.Categories = []*ynab.Category{}
+		for _, c := range p.categories.list(since) {
+			if c.CategoryGroupID == g.ID {
+				g.Categories = append(g.Categories, c)
+			}
+		}
+		switch {
+		case since == 0 && g.Deleted:
+			continue
+		case since > 0 && !p.groups.changedSince(id, since) && len(g.Categories) == 0:
+			continue
+		}
+		out = append(out, &g)
+	}
+	return out
+}
+
+// findPayee returns the payee with the given name, or nil.
+func (p *plan) findPayee(name string) *ynab.Payee {
+	for _, payee := range p.payees.list(0) {
+		if payee.Name == name {
+			return payee
+		}
+	}
+	return nil
+}
+
+// payeeNamed returns the payee with the given name, creating it if it does not
+// exist.
+func (p *plan) payeeNamed(name string, k int64) *ynab.Payee {
+	if payee := p.findPayee(name); payee != nil {
+		return payee
+	}
+	payee := &ynab.Payee{ID: newID(), Name: name}
+	p.payees.put(payee.ID, payee, k)
+	return payee
+}
+
+var accountTypes = map[string]bool{
+	"checking": true, "savings": true, "cash": true, "creditCard": true, "lineOfCredit": true,
+	"otherAsset": false, "otherLiability": false, "mortgage": false, "autoLoan": false,
+	"studentLoan": false, "personalLoan": false, "medicalDebt": false, "otherDebt": false,
+}
+
+// createAccount adds an account, its transfer payee and, if the balance is
+// not zero, a starting balance transaction.
+func (p *plan) createAccount(sa *ynab.SaveAccount, k int64, now time.Time) (*ynab.Account, error) {
+	if sa == nil || sa.Name == "" {
+		return nil, badRequest("account name is required")
+	}
+	onBudget, ok := accountTypes[sa.Type]
+	if !ok {
+		return nil, badRequest("invalid account type %q", sa.Type)
+	}
+	a := &ynab.Account{
+		ID:              newID(),
+		Name:            sa.Name,
+		Type:            sa.Type,
+		OnBudget:        onBudget,
+		StartingBalance: sa.Balance,
+	}
+	transferPayee := &ynab.Payee{
+		ID:                newID(),
+		Name:              "Transfer : " + sa.Name,
+		TransferAccountID: types.NullString{String: a.ID, Valid: true},
+	}
+	a.TransferPayeeID = types.NullString{String: transferPayee.ID, Valid: true}
+	p.accounts.put(a.ID, a, k)
+	p.payees.put(transferPayee.ID, transferPayee, k)
+	if sa.Balance != 0 {
+		payee := p.payeeNamed("Starting Balance", k)
+		txn := &ynab.Transaction{
+			ID:          newID(),
+			AccountID:   a.ID,
+			AccountName: a.Name,
+			Amount:      sa.Balance,
+			Approved:    true,
+			Cleared:     ynab.ClearedStatusCleared,
+			Date:        ynab.Date(startOfDay(now)),
+			PayeeID:     types.NullString{String: payee.ID, Valid: true},
+			PayeeName:   payee.Name,
+		}
+		if onBudget {
+			txn.CategoryID = types.NullString{String: p.readyToAssign, Valid: true}
+			txn.CategoryName = types.NullString{String: "Inflow: Ready to Assign", Valid: true}
+		}
+		p.transactions.put(txn.ID, txn, k)
+	}
+	return a, nil
+}
+
+func (p *plan) createCategoryGroup(name string, k int64) (*ynab.CategoryGroup, error) {
+	if name == "" || len(name) > 50 {
+		return nil, badRequest("category group name must be between 1 and 50 characters")
+	}
+	g := &ynab.CategoryGroup{ID: newID(), Name: name}
+	p.groups.put(g.ID, g, k)
+	return g, nil
+}
+
+func (p *plan) createCategory(sc *ynab.SaveCategory, k int64) (*ynab.Category, error) {
+	if sc == nil || sc.Name == "" {
+		return nil, badRequest("category name is required")
+	}
+	g := p.groups.get(sc.CategoryGroupID)
+	if g == nil || g.Internal {
+		return nil, badRequest("invalid category_group_id %q", sc.CategoryGroupID)
+	}
+	c := &ynab.Category{
+		ID:                newID(),
+		Name:              sc.Name,
+		Note:              sc.Note,
+		CategoryGroupID:   g.ID,
+		CategoryGroupName: g.Name,
+		GoalTarget:        sc.GoalTarget,
+	}
+	if sc.GoalTargetDate != "" {
+		c.GoalTargetDate = types.NullString{String: sc.GoalTargetDate, Valid: true}
+	}
+	c.GoalNeedsWholeAmount = sc.GoalNeedsWholeAmount
+	p.categories.put(c.ID, c, k)
+	return c, nil
+}
+
+// setBudgeted assigns amount to a category in month and records the change
+// as a money movement.
+func (p *plan) setBudgeted(month, categoryID string, amount int64, k int64, now time.Time) error {
+	c := p.categories.get(categoryID)
+	if c == nil || c.Internal {
+		return notFound("Category")
+	}
+	if p.budgeted[month] == nil {
+		p.budgeted[month] = make(map[string]int64)
+	}
+	delta := amount - p.budgeted[month][categoryID]
+	p.budgeted[month][categoryID] = amount
+	if delta == 0 {
+		return nil
+	}
+	d, _ := time.ParseInLocation("2006-01-02", month, time.Local)
+	group := &ynab.MoneyMovementGroup{
+		ID:             newID(),
+		GroupCreatedAt: now.UTC().Format(time.RFC3339),
+		Month:          ynab.Date(d),
+	}
+	mm := &ynab.MoneyMovement{
+		ID:                   newID(),
+		Month:                ynab.NullDate{Valid: true, Date: ynab.Date(d)},
+		MovedAt:              types.NullString{String: group.GroupCreatedAt, Valid: true},
+		MoneyMovementGroupID: types.NullString{String: group.ID, Valid: true},
+		Amount:               delta,
+	}
+	if delta > 0 {
+		mm.ToCategoryID = types.NullString{String: categoryID, Valid: true}
+	} else {
+		mm.FromCategoryID = types.NullString{String: categoryID, Valid: true}
+		mm.Amount = -delta
+	}
+	mm.AmountFormatted, mm.AmountCurrency = p.amount(mm.Amount)
+	p.moneyMovementGroups.put(group.ID, group, k)
+	p.moneyMovements.put(mm.ID, mm, k)
+	return nil
+}
+
+// A ledger totals transactions and assigned amounts by month.
+type ledger struct {
+	// months is every month in the plan, in order, as ISO dates.
+	months []string
+	// income is the amount categorized to Ready to Assign in each month.
+	income map[string]int64
+	// activity is the amount categorized to each category in each month.
+	activity map[string]map[string]int64
+}
+
+func (p *plan) ledger(now time.Time) *ledger {
+	l := &ledger{income: make(map[string]int64), activity: make(map[string]map[string]int64)}
+	current := monthOf(now)
+	first, last := current, current
+	add := func(month, categoryID string, amount int64) {
+		if month < first {
+			first = month
+		}
+		if categoryID == p.readyToAssign {
+			l.income[month] += amount
+			return
+		}
+		if l.activity[month] == nil {
+			l.activity[month] = make(map[string]int64)
+		}
+		l.activity[month][categoryID] += amount
+	}
+	for _, t := range p.transactions.list(0) {
+		month := monthOf(time.Time(t.Date))
+		if len(t.Subtransactions) > 0 {
+			for _, sub := range t.Subtransactions {
+				if sub.CategoryID.Valid {
+					add(month, sub.CategoryID.String, sub.Amount)
+				}
+			}
+			continue
+		}
+		if t.CategoryID.Valid {
+			add(month, t.CategoryID.String, t.Amount)
+		}
+	}
+	for month := range p.budgeted {
+		first = min(first, month)
+		last = max(last, month)
+	}
+	for m := first; m <= last; m = nextMonth(m) {
+		l.months = append(l.months, m)
+	}
+	return l
+}
+
+// categoryInMonth returns a copy of c with the amounts for month. The balance
+// is everything assigned minus everything spent up to and including month.
+func (p *plan) categoryInMonth(c *ynab.Category, l *ledger, month string) *ynab.Category {
+	out := *c
+	out.Budgeted = p.budgeted[month][c.ID]
+	out.Activity = l.activity[month][c.ID]
+	out.Balance = 0
+	for _, m := range l.months {
+		if m > month {
+			break
+		}
+		out.Balance += p.budgeted[m][c.ID] + l.activity[m][c.ID]
+	}
+	out.BudgetedFormatted, out.BudgetedCurrency = p.amount(out.Budgeted)
+	out.ActivityFormatted, out.ActivityCurrency = p.amount(out.Activity)
+	out.BalanceFormatted, out.BalanceCurrency = p.amount(out.Balance)
+	return &out
+}
+
+// monthSummary returns the totals for month. Ready to Assign is all income
+// minus everything assigned, up to and including month.
+func (p *plan) monthSummary(l *ledger, month string) *ynab.MonthSummary {
+	m := &ynab.MonthSummary{Month: month, Income: l.income[month]}
+	if old := p.months.get(month); old != nil {
+		m.Note = old.Note
+	}
+	for _, budgeted := range p.budgeted[month] {
+		m.Budgeted += budgeted
+	}
+	for _, activity := range l.activity[month] {
+		m.Activity += activity
+	}
+	for _, prev := range l.months {
+		if prev > month {
+			break
+		}
+		m.ToBeBudgeted += l.income[prev]
+		for _, budgeted := range p.budgeted[prev] {
+			m.ToBeBudgeted -= budgeted
+		}
+	}
+	m.IncomeFormatted, m.IncomeCurrency = p.amount(m.Income)
+	m.BudgetedFormatted, m.BudgetedCurrency = p.amount(m.Budgeted)
+	m.ActivityFormatted, m.ActivityCurrency = p.amount(m.Activity)
+	m.ToBeBudgetedFormatted, m.ToBeBudgetedCurrency = p.amount(m.ToBeBudgeted)
+	return m
+}
+
+// recompute updates account balances, category amounts for the current month
+// and month summaries after a change. Entities whose values change are
+// recorded at knowledge k.
+func (p *plan) recompute(k int64, now time.Time) {
+	txns := p.transactions.list(0)
+	for _, a := range p.accounts.list(0) {
+		next := *a
+		next.Balance, next.ClearedBalance, next.UnclearedBalance = 0, 0, 0
+		for _, t := range txns {
+			if t.AccountID != a.ID {
+				continue
+			}
+			next.Balance += t.Amount
+			if t.Cleared == ynab.ClearedStatusUncleared || t.Cleared == "" {
+				next.UnclearedBalance += t.Amount
+			} else {
+				next.ClearedBalance += t.Amount
+			}
+		}
+		next.BalanceFormatted, next.BalanceCurrency = p.amount(next.Balance)
+		next.ClearedBalanceFormatted, next.ClearedBalanceCurrency = p.amount(next.ClearedBalance)
+		next.UnclearedBalanceFormatted, next.UnclearedBalanceCurrency = p.amount(next.UnclearedBalance)
+		p.accounts.update(a.ID, &next, k)
+	}
+
+	l := p.ledger(now)
+	current := monthOf(now)
+	for _, c := range p.categories.list(0) {
+		p.categories.update(c.ID, p.categoryInMonth(c, l, current), k)
+	}
+	for _, month := range l.months {
+		p.months.update(month, p.monthSummary(l, month), k)
+	}
+}
+
+// resolveMonth converts a month path parameter, an ISO date or "current", to
+// the first day of the month.
+func resolveMonth(month string, now time.Time) (string, error) {
+	if month == "current" {
+		return monthOf(now), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", month, time.Local)
+	if err != nil {
+		return "", badRequest("invalid month %q", month)
+	}
+	return monthOf(t), nil
+}
+
+// monthOf returns the first day of t's month as an ISO date.
+func monthOf(t time.Time) string {
+	return t.Format("2006-01") + "-01"
+}
+
+func nextMonth(month string) string {
+	t, _ := time.ParseInLocation("2006-01-02", month, time.Local)
+	return t.AddDate(0, 1, 0).Format("2006-01-02")
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,172 @@
+package ynabtest
+
+import (
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// The methods in this file add data to a Server directly, without going
+// through the HTTP API. They apply the same validation as the API and return
+// the same errors, as *ynab.Error values. The returned values are copies;
+// changing them does not change the server.
+
+// seed runs fn as a single change to the plan with the given ID.
+func (s *Server) seed(planID string, fn func(p *plan, k int64, now time.Time) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.plan(planID)
+	if p == nil {
+		return notFound("Plan")
+	}
+	return s.mutate(p, func(k int64, now time.Time) error {
+		return fn(p, k, now)
+	})
+}
+
+// clone returns a shallow copy of v.
+func clone[T any](v *T) *T {
+	c := *v
+	return &c
+}
+
+// AddPlan adds a plan with the default settings (US dollars, MM/DD/YYYY
+// dates). The first plan added is the default plan, which "last-used" and
+// "default" refer to.
+func (s *Server) AddPlan(name string) *ynab.Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := newPlan(name, s.now())
+	s.plans[p.id] = p
+	s.planIDs = append(s.planIDs, p.id)
+	return p.info()
+}
+
+// SetSettings changes the currency and date formats of a plan.
+func (s *Server) SetSettings(planID string, settings ynab.PlanSettings) error {
+	return s.seed(planID, func(p *plan, k int64, now time.Time) error {
+		p.settings = settings
+		// Formatted amounts depend on the currency format, so restamp every
+		// transaction; recompute takes care of the rest.
+		for _, t := range p.transactions.list(0) {
+			next := *t
+			next.AmountFormatted, next.AmountCurrency = p.amount(t.Amount)
+			next.Subtransactions = append([]ynab.Transaction(nil), t.Subtransactions...)
+			for i := range next.Subtransactions {
+				sub := &next.Subtransactions[i]
+				sub.AmountFormatted, sub.AmountCurrency = p.amount(sub.Amount)
+			}
+			p.transactions.update(next.ID, &next, k)
+		}
+		for _, st := range p.scheduled.list(0) {
+			next := *st
+			next.AmountFormatted, next.AmountCurrency = p.amount(st.Amount)
+			p.scheduled.update(next.ID, &next, k)
+		}
+		return nil
+	})
+}
+
+// AddAccount adds an account. A non-zero Balance is recorded as a "Starting
+// Balance" transaction, as YNAB does.
+func (s *Server) AddAccount(planID string, account *ynab.SaveAccount) (*ynab.Account, error) {
+	var a *ynab.Account
+	err := s.seed(planID, func(p *plan, k int64, now time.Time) (err error) {
+		a, err = p.createAccount(account, k, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyOf(s, planID, func(p *plan) *ynab.Account { return p.accounts.get(a.ID) }), nil
+}
+
+// AddCategoryGroup adds a category group.
+func (s *Server) AddCategoryGroup(planID, name string) (*ynab.CategoryGroup, error) {
+	var g *ynab.CategoryGroup
+	err := s.seed(planID, func(p *plan, k int64, _ time.Time) (err error) {
+		g, err = p.createCategoryGroup(name, k)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone(g), nil
+}
+
+// AddCategory adds a category to the category group with the given ID.
+func (s *Server) AddCategory(planID, groupID, name string) (*ynab.Category, error) {
+	var c *ynab.Category
+	err := s.seed(planID, func(p *plan, k int64, _ time.Time) (err error) {
+		c, err = p.createCategory(&ynab.SaveCategory{Name: name, CategoryGroupID: groupID}, k)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyOf(s, planID, func(p *plan) *ynab.Category { return p.categories.get(c.ID) }), nil
+}
+
+// AddPayee adds a payee, or returns the existing payee with the same name.
+func (s *Server) AddPayee(planID, name string) (*ynab.Payee, error) {
+	if name == "" {
+		return nil, badRequest("payee name is required")
+	}
+	var payee *ynab.Payee
+	err := s.seed(planID, func(p *plan, k int64, _ time.Time) error {
+		payee = p.payeeNamed(name, k)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone(payee), nil
+}
+
+// AddTransaction adds a transaction, as if it was created with
+// ynab.PlanService.CreateTransaction.
+func (s *Server) AddTransaction(planID string, txn *ynab.NewTransaction) (*ynab.Transaction, error) {
+	var t *ynab.Transaction
+	err := s.seed(planID, func(p *plan, k int64, now time.Time) (err error) {
+		t, err = p.createTransaction(txn, k, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyOf(s, planID, func(p *plan) *ynab.Transaction { return p.transactions.get(t.ID) }), nil
+}
+
+// AddScheduledTransaction adds a scheduled transaction. Its date must be in
+// the future according to the server's clock.
+func (s *Server) AddScheduledTransaction(planID string, txn *ynab.SaveScheduledTransaction) (*ynab.ScheduledTransaction, error) {
+	var st *ynab.ScheduledTransaction
+	err := s.seed(planID, func(p *plan, k int64, now time.Time) (err error) {
+		st, err = p.saveScheduledTransaction(nil, txn, k, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone(st), nil
+}
+
+// SetBudgeted sets the amount assigned to a category in a month, in
+// milliunits. month is an ISO date in the month, or "current".
+func (s *Server) SetBudgeted(planID, month, categoryID string, amount int64) error {
+	return s.seed(planID, func(p *plan, k int64, now time.Time) error {
+		m, err := resolveMonth(month, now)
+		if err != nil {
+			return err
+		}
+		return p.setBudgeted(m, categoryID, amount, k, now)
+	})
+}
+
+// copyOf returns a copy of the entity get returns, which reflects any
+// recomputed fields.
+func copyOf[T any](s *Server, planID string, get func(p *plan) *T) *T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return clone(get(s.plan(planID)))
+}
@@ -0,0 +1,310 @@
+// Package ynabtest provides an in-process fake of the YNAB API for tests.
+//
+// A Server keeps plans, accounts, categories, payees, months, transactions,
+// scheduled transactions and money movements in memory and serves the
+// /plans/{plan_id}/... routes described in open_api_spec.yaml. Every change
+// increments the plan's server knowledge, so delta requests with
+// last_knowledge_of_server return only what changed, including deletions.
+//
+//	srv := ynabtest.NewServer()
+//	defer srv.Close()
+//	plan := srv.AddPlan("Personal")
+//	checking, _ := srv.AddAccount(plan.ID, &ynab.SaveAccount{Name: "Checking", Type: "checking", Balance: 100000})
+//	client := srv.Client()
+//	resp, err := client.Plans(plan.ID).ListTransactions(ctx, nil)
+//
+// Account balances, category activity and balances, and month summaries are
+// computed from transactions and assigned amounts. The arithmetic is
+// simpler than YNAB's: overspending is not rolled over and credit card
+// payment categories are not maintained.
+//
+// Use AddFault to make requests fail with 429 or 5xx responses, or to delay
+// them.
+package ynabtest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kevinburke/ynab-go"
+)
+
+// DefaultToken is the access token a new Server accepts.
+const DefaultToken = "ynabtest-token"
+
+// Server is a fake YNAB API server. Create one with NewServer and stop it with
+// Close. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, e.g. "http://127.0.0.1:50000". Pass
+	// it to ynab.WithBaseURL, or use Client.
+	URL string
+
+	srv *httptest.Server
+
+	mu sync.Mutex
+	// token is the bearer token requests must present. Empty accepts any.
+	token    string
+	now      func() time.Time
+	userID   string
+	plans    map[string]*plan
+	planIDs  []string
+	faults   []*Fault
+	requests []Request
+}
+
+// A Request is a request received by a Server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+}
+
+// A Fault makes a Server fail or delay the requests that match it.
+type Fault struct {
+	// Method and Path restrict the fault to matching requests. Path is a
+	// path.Match pattern, e.g. "/plans/*/transactions". Empty values match
+	// every request.
+	Method string
+	Path   string
+
+	// StatusCode, if not zero, is returned instead of handling the request,
+	// with a YNAB error body, e.g. 429 or 503.
+	StatusCode int
+	// RetryAfter, if not zero, is sent in the Retry-After header along with
+	// StatusCode.
+	RetryAfter time.Duration
+	// Latency delays the response. The request is still handled unless
+	// StatusCode is set.
+	Latency time.Duration
+
+	// Times is the number of requests the fault applies to. Zero means every
+	// matching request, until ClearFaults is called.
+	Times int
+}
+
+// NewServer starts a Server with no plans. It accepts DefaultToken.
+func NewServer() *Server {
+	s := &Server{
+		token:  DefaultToken,
+		now:    time.Now,
+		userID: newID(),
+		plans:  make(map[string]*plan),
+	}
+	s.srv = httptest.NewServer(s.routes())
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a ynab.Client that sends requests to the server, with the
+// token the server accepts. opts are applied after the base URL is set.
+func (s *Server) Client(opts ...ynab.ClientOption) *ynab.Client {
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	return ynab.NewClient(token, append([]ynab.ClientOption{ynab.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// SetToken changes the bearer token the server accepts. Requests with any
+// other token fail with 401 Unauthorized. An empty token accepts every
+// request.
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// SetClock replaces the server's clock, which determines the current month
+// and which dates are in the future.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// AddFault adds a fault. Faults are checked in the order they were added and
+// the first match applies.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests the server has received, including those
+// that failed.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests clears the list returned by Requests.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// matchFault returns the fault that applies to r, if any, and uses it up.
+// s.mu must be held.
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" {
+			if ok, _ := path.Match(f.Path, r.URL.Path); !ok {
+				continue
+			}
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// handler handles a request for a plan, or for no plan if the route has no
+// plan_id. It runs with s.mu held and returns the value of the "data" key in
+// the response.
+type handler func(s *Server, p *plan, r *http.Request) (any, error)
+
+// handle registers h for pattern. Successful responses use status.
+func (s *Server) handle(mux *http.ServeMux, pattern string, status int, h handler) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()})
+		fault := s.matchFault(r)
+		token := s.token
+		s.mu.Unlock()
+
+		if fault != nil && fault.Latency > 0 {
+			t := time.NewTimer(fault.Latency)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+		if fault != nil && fault.StatusCode != 0 {
+			if fault.RetryAfter > 0 {
+				w.Header().Set("Retry-After", fmt.Sprint(int((fault.RetryAfter+time.Second-1)/time.Second)))
+			}
+			writeError(w, newError(fault.StatusCode, "fault injected by ynabtest"))
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			writeError(w, newError(http.StatusUnauthorized, "Unauthorized"))
+			return
+		}
+
+		s.mu.Lock()
+		var p *plan
+		if id := r.PathValue("plan_id"); id != "" {
+			p = s.plan(id)
+			if p == nil {
+				s.mu.Unlock()
+				writeError(w, newError(http.StatusNotFound, "Plan not found"))
+				return
+			}
+		}
+		data, err := h(s, p, r)
+		var body []byte
+		if err == nil {
+			body, err = json.Marshal(map[string]any{"data": data})
+		}
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+// plan returns the plan with the given ID, or nil. "last-used" and "default"
+// refer to the first plan added. s.mu must be held.
+func (s *Server) plan(id string) *plan {
+	if (id == "last-used" || id == "default") && len(s.planIDs) > 0 {
+		id = s.planIDs[0]
+	}
+	return s.plans[id]
+}
+
+// newError returns an *ynab.Error with the id and name YNAB uses for status.
+func newError(status int, detail string) *ynab.Error {
+	e := &ynab.Error{StatusCode: status, Detail: detail}
+	switch status {
+	case http.StatusBadRequest:
+		e.ID, e.Name = "400", "bad_request"
+	case http.StatusUnauthorized:
+		e.ID, e.Name = "401", "not_authorized"
+	case http.StatusForbidden:
+		e.ID, e.Name = "403.1", "subscription_lapsed"
+	case http.StatusNotFound:
+		e.ID, e.Name = "404.2", "resource_not_found"
+	case http.StatusConflict:
+		e.ID, e.Name = "409", "conflict"
+	case http.StatusTooManyRequests:
+		e.ID, e.Name = "429", "too_many_requests"
+	case http.StatusServiceUnavailable:
+		e.ID, e.Name = "503", "service_unavailable"
+	default:
+		e.ID, e.Name = fmt.Sprint(status), "internal_server_error"
+	}
+	return e
+}
+
+func badRequest(format string, args ...any) *ynab.Error {
+	return newError(http.StatusBadRequest, fmt.Sprintf(format, args...))
+}
+
+func notFound(kind string) *ynab.Error {
+	return newError(http.StatusNotFound, kind+" not found")
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*ynab.Error)
+	if !ok {
+		e = newError(http.StatusInternalServerError, err.Error())
+	}
+	body, _ := json.Marshal(ynab.ErrorResponse{Error: &ynab.ErrorDetail{ID: e.ID, Name: e.Name, Detail: e.Detail}})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(e.StatusCode)
+	w.Write(body)
+}
+
+// newID returns a random version 4 UUID, like the IDs YNAB uses.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,207 @@
+package ynabtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+var now = time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T) (*Server, *ynab.Plan) {
+	t.Helper()
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	srv.SetClock(func() time.Time { return now })
+	return srv, srv.AddPlan("Test Plan")
+}
+
+func date(s string) ynab.Date {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return ynab.Date(d)
+}
+
+func TestTransferAndSplit(t *testing.T) {
+	srv, plan := newTestServer(t)
+	checking, err := srv.AddAccount(plan.ID, &ynab.SaveAccount{Name: "Checking", Type: "checking", Balance: 500000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	savings, err := srv.AddAccount(plan.ID, &ynab.SaveAccount{Name: "Savings", Type: "savings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := srv.AddCategoryGroup(plan.ID, "Everyday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceries, err := srv.AddCategory(plan.ID, group.ID, "Groceries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	household, err := srv.AddCategory(plan.ID, group.ID, "Household")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	svc := srv.Client().Plans(plan.ID)
+	transfer, err := ynab.NewTransferTransaction(checking.ID, savings, -100000, date("2024-03-10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateTransaction(ctx, &ynab.CreateTransactionRequest{Transaction: transfer}); err != nil {
+		t.Fatal(err)
+	}
+	split := &ynab.NewTransaction{
+		AccountID: checking.ID,
+		Date:      date("2024-03-12"),
+		Amount:    -75000,
+		PayeeName: types.NullString{String: "Costco", Valid: true},
+		Subtransactions: []*ynab.NewSubTransaction{
+			{Amount: -50000, CategoryID: types.NullString{String: groceries.ID, Valid: true}},
+			{Amount: -25000, CategoryID: types.NullString{String: household.ID, Valid: true}},
+		},
+	}
+	resp, err := svc.CreateTransaction(ctx, &ynab.CreateTransactionRequest{Transaction: split})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(resp.Data.Transaction.Subtransactions); n != 2 {
+		t.Errorf("expected 2 subtransactions, got %d", n)
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want int64
+	}{
+		{checking.ID, 500000 - 100000 - 75000},
+		{savings.ID, 100000},
+	} {
+		acct, err := svc.GetAccount(ctx, tc.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acct.Data.Account.Balance != tc.want {
+			t.Errorf("%s: expected balance %d, got %d", acct.Data.Account.Name, tc.want, acct.Data.Account.Balance)
+		}
+	}
+
+	txns, err := svc.ListAccountTransactions(ctx, savings.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns.Data.Transactions) != 1 {
+		t.Fatalf("expected 1 savings transaction, got %d", len(txns.Data.Transactions))
+	}
+	if got := txns.Data.Transactions[0]; got.TransferAccountID.String != checking.ID || got.Amount != 100000 {
+		t.Errorf("unexpected transfer counterpart: %+v", got)
+	}
+
+	cat, err := svc.GetMonthCategory(ctx, "current", groceries.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cat.Data.Category.Activity != -50000 {
+		t.Errorf("expected groceries activity -50000, got %d", cat.Data.Category.Activity)
+	}
+}
+
+func TestSyncerDeltas(t *testing.T) {
+	srv, plan := newTestServer(t)
+	checking, err := srv.AddAccount(plan.ID, &ynab.SaveAccount{Name: "Checking", Type: "checking", Balance: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, err := srv.AddTransaction(plan.ID, &ynab.NewTransaction{
+		AccountID: checking.ID,
+		Date:      date("2024-03-01"),
+		Amount:    -20000,
+		PayeeName: types.NullString{String: "Coffee", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	svc := srv.Client().Plans(plan.ID)
+	syncer := svc.NewSyncer(nil)
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	snap := syncer.Snapshot()
+	if len(snap.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions after the first sync, got %d", len(snap.Transactions))
+	}
+	if snap.Accounts[checking.ID].Balance != 80000 {
+		t.Errorf("expected balance 80000, got %d", snap.Accounts[checking.ID].Balance)
+	}
+	if _, err := svc.DeleteTransaction(ctx, txn.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ResetRequests()
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	snap = syncer.Snapshot()
+	if _, ok := snap.Transactions[txn.ID]; ok {
+		t.Error("deleted transaction is still in the snapshot")
+	}
+	if snap.Accounts[checking.ID].Balance != 100000 {
+		t.Errorf("expected balance 100000, got %d", snap.Accounts[checking.ID].Balance)
+	}
+	for _, r := range srv.Requests() {
+		if r.Query.Get("last_knowledge_of_server") == "" {
+			t.Errorf("%s %s: second sync did not send last_knowledge_of_server", r.Method, r.Path)
+		}
+	}
+}
+
+func TestFaultRetried(t *testing.T) {
+	srv, plan := newTestServer(t)
+	srv.AddFault(Fault{Path: "/plans/*/accounts", StatusCode: http.StatusTooManyRequests, Times: 2})
+	client := srv.Client()
+	client.SetRetryPolicy(&ynab.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+
+	if _, err := client.Plans(plan.ID).ListAccounts(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv, plan := newTestServer(t)
+	ctx := context.Background()
+	_, err := srv.Client().Plans(plan.ID).GetTransaction(ctx, "missing")
+	if !errors.Is(err, ynab.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	_, err = srv.Client().Plans("missing").GetSettings(ctx)
+	if !errors.Is(err, ynab.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing plan, got %v", err)
+	}
+
+	srv.SetToken("other-token")
+	c := ynab.NewClient(DefaultToken)
+	c.Base = srv.URL
+	if _, err := c.GetUser(ctx); !errors.Is(err, ynab.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+
+	_, err = srv.AddTransaction(plan.ID, &ynab.NewTransaction{AccountID: "missing", Date: date("2024-03-01")})
+	var ynabErr *ynab.Error
+	if !errors.As(err, &ynabErr) || ynabErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a 400 error, got %v", err)
+	}
+}
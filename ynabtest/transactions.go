@@ -0,0 +1,456 @@
+package ynabtest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kevinburke/go-types"
+	"github.com/kevinburke/ynab-go"
+)
+
+// resolvePayee returns the payee for a payee_id or, failing that, a
+// payee_name, creating a payee with that name if needed. It returns nil if
+// neither is set.
+func (p *plan) resolvePayee(id, name types.NullString, k int64) (*ynab.Payee, error) {
+	if id.Valid {
+		payee := p.payees.get(id.String)
+		if payee == nil {
+			return nil, badRequest("invalid payee_id %q", id.String)
+		}
+		return payee, nil
+	}
+	if name.Valid && name.String != "" {
+		return p.payeeNamed(name.String, k), nil
+	}
+	return nil, nil
+}
+
+// setCategory sets the category of t, which may be a subtransaction.
+func (p *plan) setCategory(t *ynab.Transaction, id types.NullString) error {
+	if !id.Valid {
+		t.CategoryID, t.CategoryName = types.NullString{}, types.NullString{}
+		return nil
+	}
+	c := p.categories.get(id.String)
+	if c == nil {
+		return badRequest("invalid category_id %q", id.String)
+	}
+	t.CategoryID = types.NullString{String: c.ID, Valid: true}
+	t.CategoryName = types.NullString{String: c.Name, Valid: true}
+	return nil
+}
+
+// setPayee sets the payee of t. If the payee is an account's transfer payee,
+// t becomes a transfer and the matching transaction in the other account is
+// created or updated; if t was a transfer and no longer is, the other side
+// is deleted.
+func (p *plan) setPayee(t *ynab.Transaction, payee *ynab.Payee, k int64) error {
+	var target *ynab.Account
+	if payee != nil && payee.TransferAccountID.Valid {
+		target = p.accounts.get(payee.TransferAccountID.String)
+		if target == nil || target.ID == t.AccountID {
+			return badRequest("invalid transfer payee %q", payee.ID)
+		}
+	}
+	if t.TransferTransactionID.Valid && (target == nil || target.ID != t.TransferAccountID.String) {
+		if other := p.transactions.get(t.TransferTransactionID.String); other != nil {
+			deleted := *other
+			deleted.Deleted = true
+			p.transactions.put(deleted.ID, &deleted, k)
+		}
+		t.TransferAccountID, t.TransferTransactionID = types.NullString{}, types.NullString{}
+	}
+	if payee == nil {
+		t.PayeeID, t.PayeeName = types.NullString{}, ""
+		return nil
+	}
+	t.PayeeID = types.NullString{String: payee.ID, Valid: true}
+	t.PayeeName = payee.Name
+	if target == nil {
+		return nil
+	}
+	source := p.accounts.get(t.AccountID)
+	t.TransferAccountID = types.NullString{String: target.ID, Valid: true}
+	if source.OnBudget && target.OnBudget {
+		// Transfers between budget accounts don't change what's available
+		// to spend, so they have no category.
+		t.CategoryID, t.CategoryName = types.NullString{}, types.NullString{}
+	}
+	if !t.TransferTransactionID.Valid {
+		t.TransferTransactionID = types.NullString{String: newID(), Valid: true}
+	}
+	p.mirrorTransfer(t, k)
+	return nil
+}
+
+// mirrorTransfer creates or updates the other side of the transfer t.
+func (p *plan) mirrorTransfer(t *ynab.Transaction, k int64) {
+	if !t.TransferTransactionID.Valid {
+		return
+	}
+	source := p.accounts.get(t.AccountID)
+	target := p.accounts.get(t.TransferAccountID.String)
+	sourcePayee := p.payees.get(source.TransferPayeeID.String)
+	other := &ynab.Transaction{
+		ID:      t.TransferTransactionID.String,
+		Cleared: ynab.ClearedStatusUncleared,
+	}
+	if existing := p.transactions.get(other.ID); existing != nil {
+		*other = *existing
+	}
+	other.AccountID, other.AccountName = target.ID, target.Name
+	other.Date = t.Date
+	other.Amount = -t.Amount
+	other.AmountFormatted, other.AmountCurrency = p.amount(other.Amount)
+	other.Memo = t.Memo
+	other.Approved = t.Approved
+	other.PayeeID = types.NullString{String: sourcePayee.ID, Valid: true}
+	other.PayeeName = sourcePayee.Name
+	other.TransferAccountID = types.NullString{String: source.ID, Valid: true}
+	other.TransferTransactionID = types.NullString{String: t.ID, Valid: true}
+	p.transactions.put(other.ID, other, k)
+}
+
+// newSubtransactions converts the subtransactions of a split. Their amounts
+// must add up to the amount of the parent.
+func (p *plan) newSubtransactions(parent *ynab.Transaction, subs []*ynab.SubTransaction, k int64) ([]ynab.Transaction, error) {
+	var total int64
+	out := make([]ynab.Transaction, 0, len(subs))
+	for _, s := range subs {
+		sub := ynab.Transaction{
+			ID:        newID(),
+			AccountID: parent.AccountID,
+			Amount:    s.Amount,
+			Date:      parent.Date,
+			Memo:      s.Memo.String,
+		}
+		sub.AmountFormatted, sub.AmountCurrency = p.amount(sub.Amount)
+		payee, err := p.resolvePayee(s.PayeeID, s.PayeeName, k)
+		if err != nil {
+			return nil, err
+		}
+		if payee != nil {
+			sub.PayeeID = types.NullString{String: payee.ID, Valid: true}
+			sub.PayeeName = payee.Name
+		}
+		if err := p.setCategory(&sub, s.CategoryID); err != nil {
+			return nil, err
+		}
+		total += s.Amount
+		out = append(out, sub)
+	}
+	if total != parent.Amount {
+		return nil, badRequest("subtransaction amounts must add up to the transaction amount")
+	}
+	return out, nil
+}
+
+// createTransaction adds a transaction. If the account already has a
+// transaction with the same import_id, it returns a 409 Conflict error.
+func (p *plan) createTransaction(nt *ynab.NewTransaction, k int64, now time.Time) (*ynab.Transaction, error) {
+	if nt == nil {
+		return nil, badRequest("transaction is required")
+	}
+	account := p.accounts.get(nt.AccountID)
+	if account == nil {
+		return nil, badRequest("invalid account_id %q", nt.AccountID)
+	}
+	if time.Time(nt.Date).IsZero() {
+		return nil, badRequest("date is required")
+	}
+	if nt.Date.String() > now.Format("2006-01-02") {
+		return nil, badRequest("date must not be in the future")
+	}
+	if nt.ImportID.Valid {
+		for _, t := range p.transactions.list(0) {
+			if t.AccountID == account.ID && t.ImportID == nt.ImportID {
+				return nil, newError(http.StatusConflict, "A transaction with import_id '"+nt.ImportID.String+"' already exists on this account")
+			}
+		}
+	}
+	cleared := nt.Cleared
+	if cleared == "" {
+		cleared = ynab.ClearedStatusUncleared
+	}
+	t := &ynab.Transaction{
+		ID:          newID(),
+		AccountID:   account.ID,
+		AccountName: account.Name,
+		Amount:      nt.Amount,
+		Approved:    nt.Approved,
+		Cleared:     cleared,
+		Date:        nt.Date,
+		FlagColor:   nt.FlagColor,
+		ImportID:    nt.ImportID,
+		Memo:        nt.Memo.String,
+	}
+	t.AmountFormatted, t.AmountCurrency = p.amount(t.Amount)
+	if len(nt.Subtransactions) > 0 {
+		subs := make([]*ynab.SubTransaction, len(nt.Subtransactions))
+		for i, s := range nt.Subtransactions {
+			subs[i] = &ynab.SubTransaction{Amount: s.Amount, PayeeID: s.PayeeID, PayeeName: s.PayeeName, CategoryID: s.CategoryID, Memo: s.Memo}
+		}
+		var err error
+		if t.Subtransactions, err = p.newSubtransactions(t, subs, k); err != nil {
+			return nil, err
+		}
+		t.CategoryName = types.NullString{String: "Split", Valid: true}
+	} else if err := p.setCategory(t, nt.CategoryID); err != nil {
+		return nil, err
+	}
+	payeeID := nt.PayeeID
+	if nt.TransferAccountID.Valid && !payeeID.Valid {
+		target := p.accounts.get(nt.TransferAccountID.String)
+		if target == nil {
+			return nil, badRequest("invalid transfer_account_id %q", nt.TransferAccountID.String)
+		}
+		payeeID = target.TransferPayeeID
+	}
+	payee, err := p.resolvePayee(payeeID, nt.PayeeName, k)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.setPayee(t, payee, k); err != nil {
+		return nil, err
+	}
+	p.transactions.put(t.ID, t, k)
+	return t, nil
+}
+
+// updateTransaction applies u to the transaction with the given ID. Fields
+// that are null or absent in u are left alone.
+func (p *plan) updateTransaction(id string, u *ynab.UpdateTransaction, k int64, now time.Time) (*ynab.Transaction, error) {
+	if u == nil {
+		return nil, badRequest("transaction is required")
+	}
+	existing := p.transactions.get(id)
+	if existing == nil {
+		return nil, notFound("Transaction")
+	}
+	t := *existing
+	isSplit := len(t.Subtransactions) > 0
+	if u.AccountID != nil && *u.AccountID != t.AccountID {
+		account := p.accounts.get(*u.AccountID)
+		if account == nil {
+			return nil, badRequest("invalid account_id %q", *u.AccountID)
+		}
+		if t.TransferAccountID.String == account.ID {
+			return nil, badRequest("a transfer cannot be moved to the account it transfers to")
+		}
+		t.AccountID, t.AccountName = account.ID, account.Name
+	}
+	if !time.Time(u.Date).IsZero() {
+		if u.Date.String() > now.Format("2006-01-02") {
+			return nil, badRequest("date must not be in the future")
+		}
+		t.Date = u.Date
+	}
+	if u.Amount != nil && *u.Amount != t.Amount {
+		if isSplit {
+			return nil, badRequest("split transaction amounts cannot be changed")
+		}
+		t.Amount = *u.Amount
+		t.AmountFormatted, t.AmountCurrency = p.amount(t.Amount)
+	}
+	if u.Memo.Valid {
+		t.Memo = u.Memo.String
+	}
+	if u.Cleared.Valid {
+		switch c := ynab.ClearedStatus(u.Cleared.String); c {
+		case ynab.ClearedStatusCleared, ynab.ClearedStatusUncleared, ynab.ClearedStatusReconciled:
+			t.Cleared = c
+		default:
+			return nil, badRequest("invalid cleared status %q", u.Cleared.String)
+		}
+	}
+	if u.Approved != nil {
+		t.Approved = *u.Approved
+	}
+	if u.FlagColor.Valid {
+		t.FlagColor = ynab.FlagColor(u.FlagColor.String)
+	}
+	if len(u.Subtransactions) > 0 {
+		if isSplit {
+			return nil, badRequest("updating subtransactions on an existing split transaction is not supported")
+		}
+		var err error
+		if t.Subtransactions, err = p.newSubtransactions(&t, u.Subtransactions, k); err != nil {
+			return nil, err
+		}
+		t.CategoryID = types.NullString{}
+		t.CategoryName = types.NullString{String: "Split", Valid: true}
+	} else if u.CategoryID.Valid {
+		if isSplit {
+			return nil, badRequest("the category of a split transaction cannot be changed")
+		}
+		if err := p.setCategory(&t, u.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	if u.PayeeID.Valid || u.PayeeName.Valid || u.AccountID != nil {
+		payee := p.payees.get(t.PayeeID.String)
+		if u.PayeeID.Valid || u.PayeeName.Valid {
+			var err error
+			if payee, err = p.resolvePayee(u.PayeeID, u.PayeeName, k); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.setPayee(&t, payee, k); err != nil {
+			return nil, err
+		}
+	} else {
+		p.mirrorTransfer(&t, k)
+	}
+	for i := range t.Subtransactions {
+		t.Subtransactions[i].AccountID = t.AccountID
+		t.Subtransactions[i].Date = t.Date
+	}
+	p.transactions.put(t.ID, &t, k)
+	return &t, nil
+}
+
+// deleteTransaction deletes a transaction and, if it is a transfer, the other
+// side.
+func (p *plan) deleteTransaction(id string, k int64) (*ynab.Transaction, error) {
+	existing := p.transactions.get(id)
+	if existing == nil {
+		return nil, notFound("Transaction")
+	}
+	t := *existing
+	t.Deleted = true
+	p.transactions.put(t.ID, &t, k)
+	if t.TransferTransactionID.Valid {
+		if other := p.transactions.get(t.TransferTransactionID.String); other != nil {
+			deleted := *other
+			deleted.Deleted = true
+			p.transactions.put(deleted.ID, &deleted, k)
+		}
+	}
+	return &t, nil
+}
+
+// hybrid converts a transaction, or one of its subtransactions, to the
+// HybridTransaction returned by the category, payee and month endpoints.
+func hybrid(t *ynab.Transaction, sub *ynab.Transaction) *ynab.HybridTransaction {
+	h := &ynab.HybridTransaction{
+		ID:                      t.ID,
+		Date:                    t.Date,
+		Amount:                  t.Amount,
+		AmountFormatted:         t.AmountFormatted,
+		AmountCurrency:          t.AmountCurrency,
+		Memo:                    t.Memo,
+		Cleared:                 t.Cleared,
+		Approved:                t.Approved,
+		FlagColor:               t.FlagColor,
+		FlagName:                t.FlagName,
+		AccountID:               t.AccountID,
+		AccountName:             t.AccountName,
+		PayeeID:                 t.PayeeID,
+		PayeeName:               t.PayeeName,
+		CategoryID:              t.CategoryID,
+		CategoryName:            t.CategoryName,
+		TransferAccountID:       t.TransferAccountID,
+		TransferTransactionID:   t.TransferTransactionID,
+		MatchedTransactionID:    t.MatchedTransactionID,
+		ImportID:                t.ImportID,
+		ImportPayeeName:         t.ImportPayeeName,
+		ImportPayeeNameOriginal: t.ImportPayeeNameOriginal,
+		DebtTransactionType:     t.DebtTransactionType,
+		Deleted:                 t.Deleted,
+		Type:                    "transaction",
+		Subtransactions:         t.Subtransactions,
+	}
+	if sub != nil {
+		h.ID = sub.ID
+		h.Amount, h.AmountFormatted, h.AmountCurrency = sub.Amount, sub.AmountFormatted, sub.AmountCurrency
+		h.Memo = sub.Memo
+		h.CategoryID, h.CategoryName = sub.CategoryID, sub.CategoryName
+		if sub.PayeeID.Valid {
+			h.PayeeID, h.PayeeName = sub.PayeeID, sub.PayeeName
+		}
+		h.Type = "subtransaction"
+		h.ParentTransactionID = types.NullString{String: t.ID, Valid: true}
+		h.Subtransactions = nil
+	}
+	return h
+}
+
+// hybrids returns the transactions and subtransactions that match, for the
+// category, payee and month transaction endpoints. A split transaction whose
+// parent does not match is represented by its matching subtransactions.
+func hybrids(txns []*ynab.Transaction, match func(t *ynab.Transaction) bool) []*ynab.HybridTransaction {
+	out := []*ynab.HybridTransaction{}
+	for _, t := range txns {
+		if match(t) {
+			out = append(out, hybrid(t, nil))
+			continue
+		}
+		for i := range t.Subtransactions {
+			sub := &t.Subtransactions[i]
+			if match(sub) {
+				out = append(out, hybrid(t, sub))
+			}
+		}
+	}
+	return out
+}
+
+// saveScheduledTransaction creates a scheduled transaction from s, or updates
+// existing with it.
+func (p *plan) saveScheduledTransaction(existing *ynab.ScheduledTransaction, s *ynab.SaveScheduledTransaction, k int64, now time.Time) (*ynab.ScheduledTransaction, error) {
+	if s == nil {
+		return nil, badRequest("scheduled_transaction is required")
+	}
+	account := p.accounts.get(s.AccountID)
+	if account == nil {
+		return nil, badRequest("invalid account_id %q", s.AccountID)
+	}
+	today := startOfDay(now)
+	date := time.Time(s.Date)
+	if date.Before(today) || date.After(today.AddDate(5, 0, 0)) {
+		return nil, badRequest("date must be a future date no more than 5 years out")
+	}
+	frequency := s.Frequency
+	if frequency == "" {
+		frequency = "never"
+	}
+	if !frequency.Valid() {
+		return nil, badRequest("invalid frequency %q", s.Frequency)
+	}
+	st := &ynab.ScheduledTransaction{ID: newID(), DateFirst: s.Date}
+	if existing != nil {
+		*st = *existing
+	} else if s.Amount == nil {
+		return nil, badRequest("amount is required")
+	}
+	st.AccountID, st.AccountName = account.ID, account.Name
+	st.DateNext = s.Date
+	if s.Amount != nil {
+		st.Amount = *s.Amount
+	}
+	st.AmountFormatted, st.AmountCurrency = p.amount(st.Amount)
+	st.Memo = s.Memo.String
+	st.FlagColor = s.FlagColor
+	st.Frequency = frequency
+	st.CategoryID, st.CategoryName = types.NullString{}, types.NullString{}
+	if s.CategoryID.Valid {
+		c := p.categories.get(s.CategoryID.String)
+		if c == nil {
+			return nil, badRequest("invalid category_id %q", s.CategoryID.String)
+		}
+		st.CategoryID = types.NullString{String: c.ID, Valid: true}
+		st.CategoryName = types.NullString{String: c.Name, Valid: true}
+	}
+	payee, err := p.resolvePayee(s.PayeeID, s.PayeeName, k)
+	if err != nil {
+		return nil, err
+	}
+	st.PayeeID, st.PayeeName, st.TransferAccountID = types.NullString{}, "", types.NullString{}
+	if payee != nil {
+		st.PayeeID = types.NullString{String: payee.ID, Valid: true}
+		st.PayeeName = payee.Name
+		st.TransferAccountID = payee.TransferAccountID
+	}
+	p.scheduled.put(st.ID, st, k)
+	return st, nil
+}